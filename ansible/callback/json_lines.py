@@ -6,12 +6,17 @@ import json
 import pprint
 from os.path import basename
 import os
+import fcntl
+import signal
 
 # JSON Lines STDOUT callback module for Ansible.
 #
 # This callback module prints Ansible events out to STDOUT as JSON Lines.
 # The event consists of a type and data. The data has a different structure
 # depending on the event type.
+#
+# Kismatic sends SIGUSR1 to stop the playbook gracefully. The playbook is
+# interrupted before the next task is started, once the running task completed.
 class CallbackModule(CallbackBase):
     CALLBACK_VERSION = 2.0
     CALLBACK_TYPE = 'notification'
@@ -34,6 +39,7 @@ class CallbackModule(CallbackBase):
     RUNNER_ITEM_RETRY   = "RUNNER_ITEM_RETRY"
 
     named_pipe = None
+    stop_requested = False
 
     def _new_event(self, eventType, eventData):
         return {
@@ -55,8 +61,23 @@ class CallbackModule(CallbackBase):
     def __init__(self):
         named_pipe_file = os.environ["ANSIBLE_JSON_LINES_PIPE"]
         self.named_pipe = open(named_pipe_file, 'w', 0)
+        # The processes that ansible runs, such as the persistent ssh
+        # connections, must not hold the pipe open once ansible exits
+        fcntl.fcntl(self.named_pipe.fileno(), fcntl.F_SETFD, fcntl.FD_CLOEXEC)
+        signal.signal(signal.SIGUSR1, self._request_stop)
+        # Don't interrupt the system calls of ansible when the signal is received
+        signal.siginterrupt(signal.SIGUSR1, False)
         super(CallbackModule, self).__init__()
 
+    def _request_stop(self, signum, frame):
+        self.stop_requested = True
+
+    # Ansible handles the interrupt like a Ctrl-C, and exits with an error.
+    # KeyboardInterrupt is not caught by ansible when calling the callbacks.
+    def _stop_if_requested(self):
+        if self.stop_requested:
+            raise KeyboardInterrupt()
+
     # This gets called when the playbook ends. Close the pipe.
     def v2_playbook_on_stats(self, stats):
         self._on_runner_result(self.PLAYBOOK_END, None)
@@ -107,6 +128,7 @@ class CallbackModule(CallbackBase):
     #     self.playbook_on_no_hosts_remaining()
 
     def v2_playbook_on_task_start(self, task, is_conditional):
+        self._stop_if_requested()
         event_data = self._new_task(task)
         e = self._new_event(self.TASK_START, event_data)
         self._print_event(e)
@@ -136,11 +158,13 @@ class CallbackModule(CallbackBase):
         self._on_runner_result(self.RUNNER_UNREACHABLE, result)
 
     def v2_playbook_on_cleanup_task_start(self, task):
+        self._stop_if_requested()
         event_data = self._new_task(task)
         e = self._new_event(self.CLEANUP_TASK_START, event_data)
         self._print_event(e)
 
     def v2_playbook_on_handler_task_start(self, task):
+        self._stop_if_requested()
         event_data = self._new_task(task)
         e = self._new_event(self.HANDLER_TASK_START, event_data)
         self._print_event(e)
@@ -159,9 +183,19 @@ class CallbackModule(CallbackBase):
     #     host = result._host.get_name()
     #     self.playbook_on_not_import_for_host(host, missing_file)
 
+    # The name of the file that contains the play, used to checkpoint the
+    # plays that were completed
+    def _play_file(self, play):
+        try:
+            return basename(play._ds.ansible_pos[0])
+        except (AttributeError, IndexError, TypeError):
+            return ''
+
     def v2_playbook_on_play_start(self, play):
+        self._stop_if_requested()
         data = {
-            'name': play.name
+            'name': play.name,
+            'file': self._play_file(play)
         }
         e = self._new_event(self.PLAY_START, data)
         self._print_event(e)
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+type schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+}
+
+// jsonSchema renders the docs as a JSON Schema document
+type jsonSchema struct {
+	title string
+}
+
+func (js jsonSchema) render(docs []doc) {
+	fmt.Println(js.marshal(docs))
+}
+
+func (js jsonSchema) marshal(docs []doc) string {
+	root := &schema{
+		Schema:     jsonSchemaDraft,
+		Title:      js.title,
+		Type:       "object",
+		Properties: map[string]*schema{},
+	}
+	// the docs are in depth-first order, so the parent of a property
+	// is always found before the property itself
+	objects := map[string]*schema{"": root}
+	for _, d := range docs {
+		parentName := ""
+		name := d.property
+		if i := strings.LastIndex(d.property, "."); i != -1 {
+			parentName = d.property[:i]
+			name = d.property[i+1:]
+		}
+		parent, ok := objects[parentName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "parent of property %s was not found\n", d.property)
+			os.Exit(1)
+		}
+		s := schemaForDoc(d)
+		parent.Properties[name] = s
+		if d.required {
+			parent.Required = append(parent.Required, name)
+		}
+		// keep track of the schema that will hold the nested properties
+		switch {
+		case s.Items != nil && s.Items.Type == "object":
+			objects[d.property] = s.Items
+		case s.Type == "object":
+			objects[d.property] = s
+		}
+	}
+	b, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error marshaling JSON schema: %v\n", err)
+		os.Exit(1)
+	}
+	return string(b)
+}
+
+func schemaForDoc(d doc) *schema {
+	s := schemaForType(d.propertyType)
+	s.Description = strings.TrimSpace(d.description)
+	s.Deprecated = d.deprecated
+	s.Enum = d.options
+	s.Default = defaultForType(d.propertyType, d.defaultValue)
+	return s
+}
+
+func schemaForType(t string) *schema {
+	switch {
+	case t == "string":
+		return &schema{Type: "string"}
+	case t == "int":
+		return &schema{Type: "integer"}
+	case t == "bool":
+		return &schema{Type: "boolean"}
+	case strings.HasPrefix(t, "map[string]"):
+		return &schema{Type: "object", AdditionalProperties: schemaForType(strings.TrimPrefix(t, "map[string]"))}
+	case strings.HasPrefix(t, "[]"):
+		return &schema{Type: "array", Items: schemaForType(strings.TrimPrefix(t, "[]"))}
+	default:
+		return &schema{Type: "object", Properties: map[string]*schema{}}
+	}
+}
+
+// defaultForType returns the default value converted to the JSON type of the
+// property. Defaults that are not literal values, such as 'empty', are not
+// included in the schema.
+func defaultForType(t string, def string) interface{} {
+	def = strings.TrimSpace(def)
+	if def == "" || strings.HasPrefix(def, "'") {
+		return nil
+	}
+	switch t {
+	case "int":
+		if i, err := strconv.Atoi(def); err == nil {
+			return i
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	case "string":
+		return def
+	}
+	return nil
+}
+
+// goJSONSchema renders the JSON Schema as a Go source file, so that it can be
+// compiled into a binary.
+type goJSONSchema struct {
+	jsonSchema
+	pkg      string
+	constant string
+}
+
+func (g goJSONSchema) render(docs []doc) {
+	s := g.marshal(docs)
+	// backquotes cannot be escaped in raw string literals
+	s = strings.Replace(s, "`", "` + \"`\" + `", -1)
+	fmt.Println("// Code generated by gen-kismatic-ref-docs. DO NOT EDIT.")
+	fmt.Println()
+	fmt.Printf("package %s\n", g.pkg)
+	fmt.Println()
+	fmt.Printf("// %s is the JSON Schema of the %s type\n", g.constant, g.title)
+	fmt.Printf("const %s = `%s\n`\n", g.constant, s)
+}
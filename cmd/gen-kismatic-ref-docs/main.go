@@ -34,26 +34,30 @@ func main() {
 	file := flag.Arg(0)
 	typeName := flag.Arg(1)
 
+	fset := token.NewFileSet()
+	m := make(map[string]*ast.File)
+
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing file: %v\n", err)
+		os.Exit(1)
+	}
+
 	var r renderer
 	switch *output {
 	case "markdown":
 		r = markdown{}
 	case "markdown-table":
 		r = markdownTable{}
+	case "json-schema":
+		r = jsonSchema{title: typeName}
+	case "go-json-schema":
+		r = goJSONSchema{jsonSchema: jsonSchema{title: typeName}, pkg: f.Name.Name, constant: typeName + "JSONSchema"}
 	default:
 		fmt.Fprintf(os.Stderr, "unknown output type: %s\n", *output)
 		os.Exit(1)
 	}
 
-	fset := token.NewFileSet()
-	m := make(map[string]*ast.File)
-
-	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error parsing file: %v\n", err)
-		os.Exit(1)
-	}
-
 	m[file] = f
 	apkg, _ := ast.NewPackage(fset, m, nil, nil) // error deliberately ignored
 	pkgDoc := godoc.New(apkg, "", 0)
@@ -94,6 +98,10 @@ func docForType(typeName string, allTypes []*godoc.Type, parentFieldName string)
 				docs = append(docs, d...)
 			case *ast.StructType:
 				for _, f := range tt.Fields.List {
+					// unexported fields are not part of the plan file
+					if len(f.Names) > 0 && !ast.IsExported(f.Names[0].Name) {
+						continue
+					}
 					fieldName := fieldName(parentFieldName, f)
 					var typeName string
 
@@ -55,6 +55,10 @@ type ClusterCatalog struct {
 
 	NewNode string `yaml:"new_node"`
 
+	// CompletedPlays are the plays of kubernetes.yaml that are skipped,
+	// as they were completed by a previous run
+	CompletedPlays []string `yaml:"completed_plays"`
+
 	NFSVolumes []NFSVolume `yaml:"nfs_volumes"`
 
 	EnableGluster bool `yaml:"configure_storage"`
@@ -115,11 +119,14 @@ type ClusterCatalog struct {
 				Enabled bool
 			}
 			Calico struct {
-				Mode                  string
-				LogLevel              string `yaml:"log_level"`
-				WorkloadMTU           int    `yaml:"workload_mtu"`
-				FelixInputMTU         int    `yaml:"felix_input_mtu"`
-				IPAutodetectionMethod string `yaml:"ip_autodetection_method"`
+				Mode                   string
+				LogLevel               string `yaml:"log_level"`
+				WorkloadMTU            int    `yaml:"workload_mtu"`
+				FelixInputMTU          int    `yaml:"felix_input_mtu"`
+				IPAutodetectionMethod  string `yaml:"ip_autodetection_method"`
+				IP6AutodetectionMethod string `yaml:"ip6_autodetection_method"`
+				IPv4PoolCIDR           string `yaml:"ipv4_pool_cidr"`
+				IPv6PoolCIDR           string `yaml:"ipv6_pool_cidr"`
 			}
 			Weave struct {
 				Password string
@@ -202,6 +209,42 @@ func (c *ClusterCatalog) EnableRestart() {
 	c.ForceDockerRestart = true
 }
 
+// RedactedSecret replaces the secrets of a cluster catalog that is recorded
+// or rendered for review
+const RedactedSecret = "<redacted>"
+
+// Redacted returns a copy of the cluster catalog where the secrets that are
+// set are replaced by RedactedSecret
+func (c ClusterCatalog) Redacted() ClusterCatalog {
+	redacted := c
+	for _, secret := range []*string{&redacted.AdminPassword, &redacted.DockerRegistryPassword, &redacted.CNI.Options.Weave.Password} {
+		if *secret != "" {
+			*secret = RedactedSecret
+		}
+	}
+	return redacted
+}
+
+// secretVars returns the variables of the cluster catalog that contain
+// secrets, or nil if no secret is set. Extra vars replace the variables of the
+// catalog as a whole, so the cni variable is returned with all its options.
+func (c ClusterCatalog) secretVars() map[string]interface{} {
+	vars := map[string]interface{}{}
+	if c.AdminPassword != "" {
+		vars["kubernetes_admin_password"] = c.AdminPassword
+	}
+	if c.DockerRegistryPassword != "" {
+		vars["docker_registry_password"] = c.DockerRegistryPassword
+	}
+	if c.CNI.Options.Weave.Password != "" {
+		vars["cni"] = c.CNI
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	return vars
+}
+
 func (c *ClusterCatalog) ToYAML() ([]byte, error) {
 	bytez, marshalErr := yaml.Marshal(c)
 	if marshalErr != nil {
@@ -10,7 +10,8 @@ type namedEvent struct {
 	Name string
 }
 
-type runnerResult struct {
+// RunnerResult is the result of a runner on a host
+type RunnerResult struct {
 	// Command is the command that was run
 	Command []string `json:"cmd"`
 	// Stdout captured when the command was run
@@ -30,10 +31,22 @@ type runnerResult struct {
 
 type runnerResultEvent struct {
 	Host         string
-	Result       runnerResult
+	Result       RunnerResult
 	IgnoreErrors bool
 }
 
+// HostResult returns the host, the result of the runner on the host, and
+// whether errors of the runner are ignored
+func (e runnerResultEvent) HostResult() (string, RunnerResult, bool) {
+	return e.Host, e.Result, e.IgnoreErrors
+}
+
+// ResultEvent is an event that carries the result of a runner on a host
+type ResultEvent interface {
+	Event
+	HostResult() (host string, result RunnerResult, ignoreErrors bool)
+}
+
 // PlaybookStartEvent signals the beginning of a playbook
 type PlaybookStartEvent struct {
 	namedEvent
@@ -56,6 +69,8 @@ func (e *PlaybookEndEvent) Type() string {
 // PlayStartEvent signals the beginning of a play
 type PlayStartEvent struct {
 	namedEvent
+	// File is the name of the playbook file that contains the play
+	File string
 }
 
 func (e *PlayStartEvent) Type() string {
@@ -6,7 +6,7 @@ import (
 )
 
 func TestEventStreamSingleEvent(t *testing.T) {
-	in := bytes.NewBufferString(`{"eventType":"PLAY_START", "eventData": {"name":"somePlay"}}`)
+	in := bytes.NewBufferString(`{"eventType":"PLAY_START", "eventData": {"name":"somePlay", "file":"_somePlay.yaml"}}`)
 	es := EventStream(in)
 
 	gotEvent := false
@@ -20,6 +20,9 @@ func TestEventStreamSingleEvent(t *testing.T) {
 			if event.Name != "somePlay" {
 				t.Errorf("Expected play name %q, but got %q", "somePlay", event.Name)
 			}
+			if event.File != "_somePlay.yaml" {
+				t.Errorf("Expected play file %q, but got %q", "_somePlay.yaml", event.File)
+			}
 		}
 	}
 	if !gotEvent {
@@ -3,6 +3,8 @@ package ansible
 import (
 	"bytes"
 	"fmt"
+	"net"
+	"strings"
 )
 
 // Inventory is a collection of Nodes, keyed by role.
@@ -26,12 +28,31 @@ type Node struct {
 	PublicIP string
 	// InternalIP is the internal IP, if different from PublicIP.
 	InternalIP string
-	// SSHPrivateKey is the private key to be used for SSH authentication
+	// SSHPrivateKey is the private key to be used for SSH authentication.
+	// The keys of the ssh-agent are used when it's empty.
 	SSHPrivateKey string
 	// SSHPort is the SSH port number for connecting to the node
 	SSHPort int
 	// SSHUser is the SSH user for logging into the node
 	SSHUser string
+	// SSHKnownHostsFile is the known_hosts file the host key of the node is
+	// verified against. The host key is not verified if it's empty.
+	SSHKnownHostsFile string
+	// Bastion is the jump host that the SSH connections to the node go
+	// through, if any
+	Bastion *Bastion
+}
+
+// Bastion is a jump host that SSH connections go through
+type Bastion struct {
+	// Host is the hostname or IP of the bastion host
+	Host string
+	// SSHPort is the SSH port number for connecting to the bastion host
+	SSHPort int
+	// SSHUser is the SSH user for logging into the bastion host
+	SSHUser string
+	// SSHPrivateKey is the private key to be used for SSH authentication
+	SSHPrivateKey string
 }
 
 // ToINI converts the inventory into INI format
@@ -44,9 +65,38 @@ func (i Inventory) ToINI() []byte {
 			if n.InternalIP != "" {
 				internalIP = n.InternalIP
 			}
-			fmt.Fprintf(w, "%q ansible_host=%q internal_ipv4=%q ansible_ssh_private_key_file=%q ansible_port=%d ansible_user=%q\n", n.Host, n.PublicIP, internalIP, n.SSHPrivateKey, n.SSHPort, n.SSHUser)
+			fmt.Fprintf(w, "%q ansible_host=%q internal_ipv4=%q internal_ip_url_host=%q ansible_ssh_private_key_file=%q ansible_port=%d ansible_user=%q ansible_ssh_common_args=%q\n", n.Host, n.PublicIP, internalIP, urlHost(internalIP), n.SSHPrivateKey, n.SSHPort, n.SSHUser, sshCommonArgs(n))
 		}
 	}
 
 	return w.Bytes()
 }
+
+// sshCommonArgs returns the options that verify the host key of the node, and
+// that connect to the node through the bastion host
+func sshCommonArgs(n Node) string {
+	hostKeyArgs := "-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+	if n.SSHKnownHostsFile != "" {
+		hostKeyArgs = fmt.Sprintf("-o StrictHostKeyChecking=yes -o UserKnownHostsFile='%s'", n.SSHKnownHostsFile)
+	}
+	if n.Bastion == nil {
+		return hostKeyArgs
+	}
+	// The proxy command is quoted with single quotes, and is run by a shell
+	// that reads the paths quoted with double quotes.
+	b := n.Bastion
+	proxyArgs := strings.Replace(hostKeyArgs, "'", `"`, -1)
+	if b.SSHPrivateKey != "" {
+		proxyArgs = fmt.Sprintf(`-i "%s" %s`, b.SSHPrivateKey, proxyArgs)
+	}
+	return fmt.Sprintf(`%s -o 'ProxyCommand=ssh -W %%h:%%p -q -p %d %s %s@%s'`, hostKeyArgs, b.SSHPort, proxyArgs, b.SSHUser, b.Host)
+}
+
+// urlHost returns the IP address formatted for the host part of a URL.
+// IPv6 addresses are enclosed in square brackets.
+func urlHost(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return "[" + ip + "]"
+	}
+	return ip
+}
@@ -42,12 +42,13 @@ func TestInventoryINIGeneration(t *testing.T) {
 						SSHUser:       "alice",
 					},
 					{
-						Host:          "worker02",
-						PublicIP:      "10.0.0.4",
-						InternalIP:    "192.168.0.14",
-						SSHPrivateKey: "id_rsa",
-						SSHPort:       2222,
-						SSHUser:       "alice and bob",
+						Host:              "worker02",
+						PublicIP:          "10.0.0.4",
+						InternalIP:        "fd00::14",
+						SSHPrivateKey:     "id_rsa",
+						SSHPort:           2222,
+						SSHUser:           "alice and bob",
+						SSHKnownHostsFile: "/tmp/generated/known_hosts",
 					},
 				},
 			},
@@ -57,12 +58,12 @@ func TestInventoryINIGeneration(t *testing.T) {
 	ini := string(inv.ToINI())
 
 	expected := `[etcd]
-"etcd01" ansible_host="10.0.0.1" internal_ipv4="192.168.0.11" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
+"etcd01" ansible_host="10.0.0.1" internal_ipv4="192.168.0.11" internal_ip_url_host="192.168.0.11" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice" ansible_ssh_common_args="-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
 [master]
-"master01" ansible_host="10.0.0.2" internal_ipv4="192.168.0.12" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
+"master01" ansible_host="10.0.0.2" internal_ipv4="192.168.0.12" internal_ip_url_host="192.168.0.12" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice" ansible_ssh_common_args="-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
 [worker]
-"worker01" ansible_host="10.0.0.3" internal_ipv4="192.168.0.13" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice"
-"worker02" ansible_host="10.0.0.4" internal_ipv4="192.168.0.14" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice and bob"
+"worker01" ansible_host="10.0.0.3" internal_ipv4="192.168.0.13" internal_ip_url_host="192.168.0.13" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice" ansible_ssh_common_args="-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+"worker02" ansible_host="10.0.0.4" internal_ipv4="fd00::14" internal_ip_url_host="[fd00::14]" ansible_ssh_private_key_file="id_rsa" ansible_port=2222 ansible_user="alice and bob" ansible_ssh_common_args="-o StrictHostKeyChecking=yes -o UserKnownHostsFile='/tmp/generated/known_hosts'"
 `
 
 	if ini != expected {
@@ -70,3 +71,39 @@ func TestInventoryINIGeneration(t *testing.T) {
 	}
 
 }
+
+func TestInventoryINIGenerationWithBastion(t *testing.T) {
+	inv := Inventory{
+		Roles: []Role{
+			{
+				Name: "worker",
+				Nodes: []Node{
+					{
+						Host:              "worker01",
+						PublicIP:          "10.0.0.3",
+						SSHPrivateKey:     "/keys/id_rsa",
+						SSHPort:           22,
+						SSHUser:           "alice",
+						SSHKnownHostsFile: "/tmp/generated/known_hosts",
+						Bastion: &Bastion{
+							Host:          "bastion.example.com",
+							SSHPort:       2222,
+							SSHUser:       "bob",
+							SSHPrivateKey: "/keys/bastion.pem",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ini := string(inv.ToINI())
+
+	expected := `[worker]
+"worker01" ansible_host="10.0.0.3" internal_ipv4="10.0.0.3" internal_ip_url_host="10.0.0.3" ansible_ssh_private_key_file="/keys/id_rsa" ansible_port=22 ansible_user="alice" ansible_ssh_common_args="-o StrictHostKeyChecking=yes -o UserKnownHostsFile='/tmp/generated/known_hosts' -o 'ProxyCommand=ssh -W %h:%p -q -p 2222 -i \"/keys/bastion.pem\" -o StrictHostKeyChecking=yes -o UserKnownHostsFile=\"/tmp/generated/known_hosts\" bob@bastion.example.com'"
+`
+
+	if ini != expected {
+		t.Errorf("expected format differs from obtained format. Expected: \n%s\nGot: \n%s\n", expected, ini)
+	}
+}
@@ -1,6 +1,8 @@
 package ansible
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,8 +12,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 const (
@@ -19,8 +24,36 @@ const (
 	RawFormat = OutputFormat("raw")
 	// JSONLinesFormat is a JSON Lines representation of Ansible events
 	JSONLinesFormat = OutputFormat("json_lines")
+	// EventsFile is the file in the run directory where the JSON Lines
+	// representation of the Ansible events is recorded
+	EventsFile = "events.jsonl"
+
+	// drainTimeout is how long the events that are left in the pipe are read
+	// for, once ansible exits
+	drainTimeout = 10 * time.Second
+)
+
+// ErrInterrupted is returned when ansible stopped before the playbook
+// completed, because the context of the playbook was done
+var ErrInterrupted = errors.New("ansible was interrupted before the playbook completed")
+
+var (
+	// playbooksMu guards the process groups of the playbooks that are running
+	playbooksMu sync.Mutex
+	playbooks   = map[int]bool{}
 )
 
+// KillPlaybooks kills the process groups of the playbooks that are running,
+// instead of waiting for the tasks that are running to complete. The
+// playbooks return ErrInterrupted if their context is done.
+func KillPlaybooks() {
+	playbooksMu.Lock()
+	defer playbooksMu.Unlock()
+	for pgid := range playbooks {
+		syscall.Kill(-pgid, syscall.SIGKILL) // error deliberately ignored, ansible might have exited already
+	}
+}
+
 // OutputFormat is used for controlling the STDOUT format of the Ansible runner
 type OutputFormat string
 
@@ -28,14 +61,17 @@ type OutputFormat string
 type Runner interface {
 	// StartPlaybook runs the playbook asynchronously with the given inventory and extra vars.
 	// It returns a read-only channel that must be consumed for the playbook execution to proceed.
-	StartPlaybook(playbookFile string, inventory Inventory, cc ClusterCatalog) (<-chan Event, error)
-	// WaitPlaybook blocks until the execution of the playbook is complete. If an error occurred,
-	// it is returned. Otherwise, returns nil to signal the completion of the playbook.
+	// When the context is done, ansible stops once the task that is running completes.
+	StartPlaybook(ctx context.Context, playbookFile string, inventory Inventory, cc ClusterCatalog) (<-chan Event, error)
+	// WaitPlaybook blocks until the execution of the playbook is complete, and the channel of
+	// events is closed. If an error occurred, it is returned. Returns ErrInterrupted if ansible
+	// stopped because the context was done. Otherwise, returns nil to signal the completion of the playbook.
 	WaitPlaybook() error
 	// StartPlaybookOnNode runs the playbook asynchronously with the given inventory and extra vars
 	// against the specific node.
 	// It returns a read-only channel that must be consumed for the playbook execution to proceed.
-	StartPlaybookOnNode(playbookFile string, inventory Inventory, cc ClusterCatalog, node ...string) (<-chan Event, error)
+	// When the context is done, ansible stops once the task that is running completes.
+	StartPlaybookOnNode(ctx context.Context, playbookFile string, inventory Inventory, cc ClusterCatalog, node ...string) (<-chan Event, error)
 }
 
 type runner struct {
@@ -48,11 +84,27 @@ type runner struct {
 	ansibleDir   string
 	runDir       string
 	waitPlaybook func() error
+	ctx          context.Context
 	namedPipe    string
+	// eventStream is the end of the named pipe the events are read from,
+	// and pipeWriter holds the pipe open until ansible exits
+	eventStream *os.File
+	pipeWriter  *os.File
+	streamEnded chan struct{}
+	eventsFile  *os.File
+	// becomePassword is the password for sudo on the nodes. It is passed to
+	// ansible in a private file that is removed once the playbook exits,
+	// like the secrets of the cluster catalog.
+	becomePassword string
+	privateVarsDir string
+	// sshAuthSock is the socket of the agent that serves the keys protected
+	// by a passphrase. It is only set in the environment of ansible.
+	sshAuthSock string
 }
 
-// NewRunner returns a new runner for running Ansible playbooks.
-func NewRunner(out, errOut io.Writer, ansibleDir string, runDir string) (Runner, error) {
+// NewRunner returns a new runner for running Ansible playbooks. The become
+// password is used when the user can't sudo without a password on the nodes.
+func NewRunner(out, errOut io.Writer, ansibleDir string, runDir string, becomePassword string, sshAuthSock string) (Runner, error) {
 	// Ansible depends on python 2.7 being installed and on the path as "python".
 	// Validate that it is available
 	if _, err := exec.LookPath("python"); err != nil {
@@ -65,11 +117,13 @@ func NewRunner(out, errOut io.Writer, ansibleDir string, runDir string) (Runner,
 	}
 
 	return &runner{
-		out:        out,
-		errOut:     errOut,
-		pythonPath: ppath,
-		ansibleDir: ansibleDir,
-		runDir:     runDir,
+		out:            out,
+		errOut:         errOut,
+		pythonPath:     ppath,
+		ansibleDir:     ansibleDir,
+		runDir:         runDir,
+		becomePassword: becomePassword,
+		sshAuthSock:    sshAuthSock,
 	}, nil
 }
 
@@ -80,6 +134,11 @@ func (r *runner) WaitPlaybook() error {
 		return fmt.Errorf("wait called, but playbook not started")
 	}
 	execErr := r.waitPlaybook()
+	if execErr != nil && r.ctx.Err() != nil {
+		execErr = ErrInterrupted
+	}
+	r.closeEventStream()
+	r.removePrivateVars()
 	// Process exited, we can clean up named pipe
 	removeErr := os.Remove(r.namedPipe)
 	if removeErr != nil && execErr != nil {
@@ -88,6 +147,9 @@ func (r *runner) WaitPlaybook() error {
 	if removeErr != nil {
 		return fmt.Errorf("failed to clean up named pipe at %q: %v", r.namedPipe, removeErr)
 	}
+	if execErr == ErrInterrupted {
+		return execErr
+	}
 	if execErr != nil {
 		return fmt.Errorf("error running ansible: %v", execErr)
 	}
@@ -95,48 +157,52 @@ func (r *runner) WaitPlaybook() error {
 }
 
 // RunPlaybook with the given inventory and extra vars
-func (r *runner) StartPlaybook(playbookFile string, inv Inventory, cc ClusterCatalog) (<-chan Event, error) {
-	return r.startPlaybook(playbookFile, inv, cc) // Don't set the --limit arg
+func (r *runner) StartPlaybook(ctx context.Context, playbookFile string, inv Inventory, cc ClusterCatalog) (<-chan Event, error) {
+	return r.startPlaybook(ctx, playbookFile, inv, cc) // Don't set the --limit arg
 }
 
 // StartPlaybookOnNode runs the playbook asynchronously with the given inventory and extra vars
 // against the specific node.
 // It returns a read-only channel that must be consumed for the playbook execution to proceed.
-func (r *runner) StartPlaybookOnNode(playbookFile string, inv Inventory, cc ClusterCatalog, nodes ...string) (<-chan Event, error) {
+func (r *runner) StartPlaybookOnNode(ctx context.Context, playbookFile string, inv Inventory, cc ClusterCatalog, nodes ...string) (<-chan Event, error) {
 	// set the --limit arg to the node we want to target
-	return r.startPlaybook(playbookFile, inv, cc, nodes...)
+	return r.startPlaybook(ctx, playbookFile, inv, cc, nodes...)
 }
 
-func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCatalog, nodes ...string) (<-chan Event, error) {
+func (r *runner) startPlaybook(ctx context.Context, playbookFile string, inv Inventory, cc ClusterCatalog, nodes ...string) (<-chan Event, error) {
+	if ctx.Err() != nil {
+		return nil, ErrInterrupted
+	}
 	playbook := filepath.Join(r.ansibleDir, "playbooks", playbookFile)
 	if _, err := os.Stat(playbook); os.IsNotExist(err) {
 		return nil, fmt.Errorf("playbook %q does not exist", playbook)
 	}
 
-	yamlBytes, err := cc.ToYAML()
+	// The secrets are not recorded in the run directory
+	redacted := cc.Redacted()
+	yamlBytes, err := redacted.ToYAML()
 	if err != nil {
 		return nil, fmt.Errorf("error writing cluster catalog data to yaml: %v", err)
 	}
-	clusterCatalogFile := filepath.Join(r.ansibleDir, "clustercatalog.yaml")
+	// The files are generated in the run directory, so that runs don't share
+	// them with other runs
+	clusterCatalogFile := filepath.Join(r.runDir, "clustercatalog.yaml")
 	if err = ioutil.WriteFile(clusterCatalogFile, yamlBytes, 0644); err != nil {
 		return nil, fmt.Errorf("error writing cluster catalog file to %q: %v", clusterCatalogFile, err)
 	}
 
-	inventoryFile := filepath.Join(r.ansibleDir, "inventory.ini")
+	inventoryFile := filepath.Join(r.runDir, "inventory.ini")
 	if err := ioutil.WriteFile(inventoryFile, inv.ToINI(), 0644); err != nil {
 		return nil, fmt.Errorf("error writing inventory file to %q: %v", inventoryFile, err)
 	}
 
-	if err := copyFileContents(clusterCatalogFile, filepath.Join(r.runDir, "clustercatalog.yaml")); err != nil {
-		return nil, fmt.Errorf("error copying clustercatalog.yaml to %q: %v", r.runDir, err)
-	}
-	if err := copyFileContents(inventoryFile, filepath.Join(r.runDir, "inventory.ini")); err != nil {
-		return nil, fmt.Errorf("error copying inventory.ini to %q: %v", r.runDir, err)
-	}
-
 	cmd := exec.Command(filepath.Join(r.ansibleDir, "bin", "ansible-playbook"), "-i", inventoryFile, "-s", playbook, "--extra-vars", "@"+clusterCatalogFile)
 	cmd.Stdout = r.out
 	cmd.Stderr = r.errOut
+	// Ansible runs in its own process group, so that an interrupt from the
+	// terminal doesn't kill it in the middle of a task. It is asked to stop
+	// when the context is done instead.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	log.SetOutput(r.out)
 
@@ -145,6 +211,23 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 		cmd.Args = append(cmd.Args, "--limit", limitArg)
 	}
 
+	// The secrets are passed in private files, after the cluster catalog
+	// that is recorded in the run directory
+	if secrets := cc.secretVars(); secrets != nil {
+		varsFile, err := r.writePrivateVars("secrets.yaml", secrets)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Args = append(cmd.Args, "--extra-vars", "@"+varsFile)
+	}
+	if r.becomePassword != "" {
+		varsFile, err := r.writePrivateVars("become.yaml", map[string]string{"ansible_become_pass": r.becomePassword})
+		if err != nil {
+			return nil, err
+		}
+		cmd.Args = append(cmd.Args, "--extra-vars", "@"+varsFile)
+	}
+
 	// We always want the most verbose output from Ansible. If it's not going to
 	// stdout, it's going to a log file.
 	cmd.Args = append(cmd.Args, "-vvvv")
@@ -152,9 +235,15 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	// Create named pipe
 	np, err := createTempNamedPipe()
 	if err != nil {
+		r.removePrivateVars()
 		return nil, err
 	}
 	r.namedPipe = np
+	if err = r.openEventStream(); err != nil {
+		r.removePrivateVars()
+		os.Remove(np)
+		return nil, err
+	}
 
 	os.Setenv("PYTHONPATH", r.pythonPath)
 	os.Setenv("ANSIBLE_CALLBACK_PLUGINS", filepath.Join(r.ansibleDir, "playbooks", "callback"))
@@ -168,23 +257,155 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	fmt.Fprintf(r.out, "export ANSIBLE_CALLBACK_WHITELIST=%v\n", os.Getenv("ANSIBLE_CALLBACK_WHITELIST"))
 	fmt.Fprintf(r.out, "export ANSIBLE_CONFIG=%v\n", os.Getenv("ANSIBLE_CONFIG"))
 	fmt.Fprintf(r.out, "export ANSIBLE_JSON_LINES_PIPE=%v\n", os.Getenv("ANSIBLE_JSON_LINES_PIPE"))
+	if r.sshAuthSock != "" {
+		cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+r.sshAuthSock)
+		fmt.Fprintf(r.out, "export SSH_AUTH_SOCK=%v\n", r.sshAuthSock)
+	}
 	fmt.Fprintln(r.out, strings.Join(cmd.Args, " "))
 
-	// Starts async execution of ansible, which will block until
-	// we start reading from the named pipe
+	// Record the events in the run directory, after the events of the
+	// playbooks that were run before in the same directory
+	eventsFile, err := os.OpenFile(filepath.Join(r.runDir, EventsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		r.removePrivateVars()
+		r.eventStream.Close()
+		r.pipeWriter.Close()
+		os.Remove(np)
+		return nil, fmt.Errorf("error creating events file: %v", err)
+	}
+	r.eventsFile = eventsFile
+
+	// Starts async execution of ansible, which will block on writing
+	// events until we start reading from the event stream
 	err = cmd.Start()
 	if err != nil {
+		r.removePrivateVars()
+		r.eventStream.Close()
+		r.pipeWriter.Close()
+		r.eventsFile.Close()
+		os.Remove(np)
 		return nil, fmt.Errorf("error running playbook: %v", err)
 	}
-	r.waitPlaybook = cmd.Wait
+	r.ctx = ctx
+	// ansible is the leader of its process group
+	pgid := cmd.Process.Pid
+	playbooksMu.Lock()
+	playbooks[pgid] = true
+	playbooksMu.Unlock()
+	exited := make(chan struct{})
+	r.waitPlaybook = func() error {
+		defer close(exited)
+		err := cmd.Wait()
+		playbooksMu.Lock()
+		delete(playbooks, pgid)
+		playbooksMu.Unlock()
+		return err
+	}
+	go r.stopWhenDone(ctx, cmd.Process, exited)
+
+	r.streamEnded = make(chan struct{})
+	eventStream := EventStream(&eventRecorder{in: r.eventStream, out: eventsFile, ended: r.streamEnded})
+	return eventStream, nil
+}
+
+// stopWhenDone asks ansible to stop once the task that is running completes,
+// when the context is done before ansible exits. The json_lines callback
+// stops the playbook on SIGUSR1, before the next task is started.
+func (r *runner) stopWhenDone(ctx context.Context, p *os.Process, exited <-chan struct{}) {
+	select {
+	case <-exited:
+	case <-ctx.Done():
+		fmt.Fprintln(r.out, "Interrupted, stopping ansible once the running task completes")
+		p.Signal(syscall.SIGUSR1) // error deliberately ignored, ansible might have exited already
+	}
+}
 
-	// Create the event stream out of the named pipe
-	eventStreamFile, err := os.OpenFile(r.namedPipe, os.O_RDWR, os.ModeNamedPipe)
+// openEventStream opens the named pipe for reading the events. The pipe is
+// also opened for writing, so that the stream does not end before ansible
+// opens the pipe. The writer is closed once ansible exits, and the stream ends
+// once the events that ansible wrote are read.
+func (r *runner) openEventStream() error {
+	in, err := os.OpenFile(r.namedPipe, os.O_RDONLY|syscall.O_NONBLOCK, os.ModeNamedPipe)
 	if err != nil {
-		return nil, fmt.Errorf("error openning event stream pipe: %v", err)
+		return fmt.Errorf("error opening event stream pipe: %v", err)
 	}
-	eventStream := EventStream(eventStreamFile)
-	return eventStream, nil
+	w, err := os.OpenFile(r.namedPipe, os.O_WRONLY, os.ModeNamedPipe)
+	if err != nil {
+		in.Close()
+		return fmt.Errorf("error opening event stream pipe: %v", err)
+	}
+	r.eventStream = in
+	r.pipeWriter = w
+	return nil
+}
+
+// closeEventStream drains the events that are left in the pipe, and closes
+// it. The events are not drained if a process that ansible started still
+// holds the pipe open after the drain timeout.
+func (r *runner) closeEventStream() {
+	if r.eventStream == nil {
+		return
+	}
+	r.pipeWriter.Close()
+	select {
+	case <-r.streamEnded:
+	case <-time.After(drainTimeout):
+	}
+	r.eventStream.Close()
+	r.eventsFile.Close()
+	r.eventStream = nil
+}
+
+// writePrivateVars writes the variables to an extra vars file in a temporary
+// directory that only the current user can read
+func (r *runner) writePrivateVars(name string, vars interface{}) (string, error) {
+	if r.privateVarsDir == "" {
+		dir, err := ioutil.TempDir("", "ansible-vars")
+		if err != nil {
+			return "", fmt.Errorf("error creating directory for the private variables: %v", err)
+		}
+		r.privateVarsDir = dir
+	}
+	b, err := yaml.Marshal(vars)
+	if err != nil {
+		r.removePrivateVars()
+		return "", fmt.Errorf("error marshaling the private variables: %v", err)
+	}
+	file := filepath.Join(r.privateVarsDir, name)
+	if err := ioutil.WriteFile(file, b, 0600); err != nil {
+		r.removePrivateVars()
+		return "", fmt.Errorf("error writing the private variables: %v", err)
+	}
+	return file, nil
+}
+
+func (r *runner) removePrivateVars() {
+	if r.privateVarsDir == "" {
+		return
+	}
+	os.RemoveAll(r.privateVarsDir) // error deliberately ignored, the directory is private
+	r.privateVarsDir = ""
+}
+
+// eventRecorder writes the events that are read from the stream to a file.
+// Errors writing the file, such as after it is closed, don't interrupt the
+// stream. The ended channel is closed when the stream ends.
+type eventRecorder struct {
+	in    io.Reader
+	out   io.Writer
+	ended chan struct{}
+}
+
+func (r *eventRecorder) Read(p []byte) (int, error) {
+	n, err := r.in.Read(p)
+	if n > 0 {
+		r.out.Write(p[:n])
+	}
+	if err != nil && r.ended != nil {
+		close(r.ended)
+		r.ended = nil
+	}
+	return n, err
 }
 
 // create a named pipe for getting json events out of ansible.
@@ -207,20 +428,3 @@ func getPythonPath() (string, error) {
 	lib64 := filepath.Join(wd, "ansible", "lib64", "python2.7", "site-packages")
 	return fmt.Sprintf("%s:%s", lib, lib64), nil
 }
-
-func copyFileContents(src, dst string) (err error) {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-	if _, err = io.Copy(out, in); err != nil {
-		return err
-	}
-	return out.Sync()
-}
@@ -1,12 +1,20 @@
 package ansible
 
 import (
+	"bytes"
+	"context"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 func TestWaitPlaybook(t *testing.T) {
-	r, err := NewRunner(ioutil.Discard, ioutil.Discard, "", "/tmp")
+	r, err := NewRunner(ioutil.Discard, ioutil.Discard, "", "/tmp", "", "")
 	if err != nil {
 		t.Fatalf("Error creating runner: %v", err)
 	}
@@ -15,3 +23,254 @@ func TestWaitPlaybook(t *testing.T) {
 		t.Error("Did not get the expected error when calling WaitPlaybook")
 	}
 }
+
+func TestPrivateVarsFile(t *testing.T) {
+	r := &runner{}
+	file, err := r.writePrivateVars("become.yaml", map[string]string{"ansible_become_pass": `pass"word`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected the file to be private, but its permissions are %#o", fi.Mode().Perm())
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars := map[string]string{}
+	if err := yaml.Unmarshal(b, &vars); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vars["ansible_become_pass"] != `pass"word` {
+		t.Errorf("unexpected become password %q", vars["ansible_become_pass"])
+	}
+	r.removePrivateVars()
+	if _, err := os.Stat(filepath.Dir(file)); !os.IsNotExist(err) {
+		t.Errorf("expected the directory of the private variables to be removed")
+	}
+}
+
+// fakeAnsibleDir returns an ansible directory with the ansible-playbook
+// script, and an empty test.yaml playbook
+func fakeAnsibleDir(t *testing.T, script string) string {
+	dir, err := ioutil.TempDir("", "ket-test-runner")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	for _, d := range []string{"bin", "playbooks", "run"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0700); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bin", "ansible-playbook"), []byte(script), 0700); err != nil {
+		t.Fatalf("error writing fake ansible-playbook: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "playbooks", "test.yaml"), []byte{}, 0600); err != nil {
+		t.Fatalf("error writing playbook: %v", err)
+	}
+	return dir
+}
+
+// fakeAnsiblePlaybook writes an event when it starts, and another one when
+// it is asked to stop, before exiting like an interrupted ansible
+const fakeAnsiblePlaybook = `#!/bin/sh
+stop() {
+	echo '{"eventType":"PLAYBOOK_END","eventData":{}}' > "$ANSIBLE_JSON_LINES_PIPE"
+	exit 99
+}
+trap stop USR1
+echo '{"eventType":"PLAYBOOK_START","eventData":{"name":"test.yaml","count":1}}' > "$ANSIBLE_JSON_LINES_PIPE"
+while true; do sleep 0.1; done
+`
+
+func TestInterruptPlaybook(t *testing.T) {
+	dir := fakeAnsibleDir(t, fakeAnsiblePlaybook)
+	defer os.RemoveAll(dir)
+	r := &runner{out: ioutil.Discard, errOut: ioutil.Discard, ansibleDir: dir, runDir: filepath.Join(dir, "run")}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := r.StartPlaybook(ctx, "test.yaml", Inventory{}, ClusterCatalog{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := <-events; e.Type() != "Playbook Start" {
+		t.Fatalf("expected the playbook to start, got %q", e.Type())
+	}
+	cancel()
+	received := make(chan []Event)
+	go func() {
+		rest := []Event{}
+		for e := range events {
+			rest = append(rest, e)
+		}
+		received <- rest
+	}()
+	if err = r.WaitPlaybook(); err != ErrInterrupted {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+	// the events that were written before ansible exited are drained
+	select {
+	case rest := <-received:
+		if len(rest) != 1 || rest[0].Type() != "Playbook End" {
+			t.Errorf("expected the last event to be drained, got %v", rest)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the event stream did not end once ansible exited")
+	}
+	if _, err := os.Stat(r.namedPipe); !os.IsNotExist(err) {
+		t.Errorf("expected the named pipe to be removed")
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "run", EventsFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 2 {
+		t.Errorf("expected both events to be recorded, got:\n%s", b)
+	}
+}
+
+// stuckAnsiblePlaybook does not stop when it is asked to, and starts a
+// process that holds the pipe open, like the workers of ansible do
+const stuckAnsiblePlaybook = `#!/bin/sh
+trap '' USR1
+echo '{"eventType":"PLAYBOOK_START","eventData":{"name":"test.yaml","count":1}}' > "$ANSIBLE_JSON_LINES_PIPE"
+sleep 100 > "$ANSIBLE_JSON_LINES_PIPE" &
+while true; do sleep 0.1; done
+`
+
+func TestKillPlaybooks(t *testing.T) {
+	dir := fakeAnsibleDir(t, stuckAnsiblePlaybook)
+	defer os.RemoveAll(dir)
+	r := &runner{out: ioutil.Discard, errOut: ioutil.Discard, ansibleDir: dir, runDir: filepath.Join(dir, "run")}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := r.StartPlaybook(ctx, "test.yaml", Inventory{}, ClusterCatalog{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := <-events; e.Type() != "Playbook Start" {
+		t.Fatalf("expected the playbook to start, got %q", e.Type())
+	}
+	go func() {
+		for range events {
+		}
+	}()
+	cancel()
+	KillPlaybooks()
+	waited := make(chan error)
+	go func() { waited <- r.WaitPlaybook() }()
+	// the stream ends before the drain timeout, as the process that held
+	// the pipe was killed with ansible
+	select {
+	case err = <-waited:
+	case <-time.After(drainTimeout / 2):
+		t.Fatalf("the playbook was not killed")
+	}
+	if err != ErrInterrupted {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+	if _, err := os.Stat(r.namedPipe); !os.IsNotExist(err) {
+		t.Errorf("expected the named pipe to be removed")
+	}
+	playbooksMu.Lock()
+	defer playbooksMu.Unlock()
+	if len(playbooks) != 0 {
+		t.Errorf("expected the playbook to be forgotten once it exited")
+	}
+}
+
+// catExtraVars prints the extra vars files it is passed
+const catExtraVars = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	if [ "$1" = "--extra-vars" ]; then
+		cat "${2#@}"
+	fi
+	shift
+done
+`
+
+func TestSecretsAreNotRecorded(t *testing.T) {
+	dir := fakeAnsibleDir(t, catExtraVars)
+	defer os.RemoveAll(dir)
+	out := &bytes.Buffer{}
+	r := &runner{out: out, errOut: ioutil.Discard, ansibleDir: dir, runDir: filepath.Join(dir, "run"), becomePassword: "become-secret"}
+	cc := ClusterCatalog{AdminPassword: "admin-secret", DockerRegistryPassword: "registry-secret"}
+	cc.CNI.Provider = "weave"
+	cc.CNI.Options.Weave.Password = "weave-secret"
+	events, err := r.StartPlaybook(context.Background(), "test.yaml", Inventory{}, cc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() {
+		for range events {
+		}
+	}()
+	if err = r.WaitPlaybook(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secrets := []string{"admin-secret", "registry-secret", "weave-secret", "become-secret"}
+	// ansible got the secrets
+	for _, s := range secrets {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q to be passed to ansible", s)
+		}
+	}
+	if !strings.Contains(out.String(), "provider: weave") {
+		t.Errorf("expected the cni options to be passed with the weave password")
+	}
+	// but none of them is in the run directory
+	err = filepath.Walk(filepath.Join(dir, "run"), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, s := range secrets {
+			if strings.Contains(string(b), s) {
+				t.Errorf("secret %q was recorded in %s", s, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "run", "clustercatalog.yaml"))
+	if err != nil || !strings.Contains(string(b), RedactedSecret) {
+		t.Errorf("expected the recorded cluster catalog to be redacted: %v", err)
+	}
+	if r.privateVarsDir != "" {
+		t.Errorf("expected the private variables to be removed")
+	}
+}
+
+func TestSSHAuthSockIsOnlySetForAnsible(t *testing.T) {
+	dir := fakeAnsibleDir(t, "#!/bin/sh\necho \"agent at $SSH_AUTH_SOCK\"\n")
+	defer os.RemoveAll(dir)
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	out := &bytes.Buffer{}
+	r := &runner{out: out, errOut: ioutil.Discard, ansibleDir: dir, runDir: filepath.Join(dir, "run"), sshAuthSock: "/tmp/ket-agent/agent.sock"}
+	events, err := r.StartPlaybook(context.Background(), "test.yaml", Inventory{}, ClusterCatalog{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() {
+		for range events {
+		}
+	}()
+	if err = r.WaitPlaybook(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "agent at /tmp/ket-agent/agent.sock") {
+		t.Errorf("expected ansible to use the agent, got:\n%s", out.String())
+	}
+	if os.Getenv("SSH_AUTH_SOCK") != sock {
+		t.Errorf("expected the environment of kismatic not to change, got SSH_AUTH_SOCK=%q", os.Getenv("SSH_AUTH_SOCK"))
+	}
+}
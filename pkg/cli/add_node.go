@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
 	"github.com/apprenda/kismatic/pkg/install"
@@ -16,16 +15,18 @@ type addNodeOpts struct {
 	Roles                    []string
 	NodeLabels               []string
 	GeneratedAssetsDirectory string
+	RunsDirectory            string
 	RestartServices          bool
 	OutputFormat             string
 	Verbose                  bool
 	SkipPreFlight            bool
+	ForceUnlock              bool
 }
 
 var validRoles = []string{"worker", "ingress", "storage"}
 
 // NewCmdAddNode returns the command for adding node to the cluster
-func NewCmdAddNode(out io.Writer, installOpts *installOpts) *cobra.Command {
+func NewCmdAddNode(out io.Writer, errOut io.Writer, installOpts *installOpts) *cobra.Command {
 	opts := &addNodeOpts{}
 	cmd := &cobra.Command{
 		Use:     "add-node NODE_NAME NODE_IP [NODE_INTERNAL_IP]",
@@ -61,30 +62,44 @@ func NewCmdAddNode(out io.Writer, installOpts *installOpts) *cobra.Command {
 					newNode.Labels[pair[0]] = pair[1]
 				}
 			}
-			return doAddNode(out, installOpts.planFilename, opts, newNode)
+			return withClusterLock(out, opts.GeneratedAssetsDirectory, cmd.CommandPath(), opts.ForceUnlock, func() error {
+				return doAddNode(out, errOut, installOpts.planner(), opts, newNode)
+			})
 		},
 	}
 	cmd.Flags().StringSliceVar(&opts.Roles, "roles", []string{}, "roles separated by ',' (options \"worker\"|\"ingress\"|\"storage\")")
 	cmd.Flags().StringSliceVarP(&opts.NodeLabels, "labels", "l", []string{}, "key=value pairs separated by ','")
 	cmd.Flags().StringVar(&opts.GeneratedAssetsDirectory, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &opts.RunsDirectory)
 	cmd.Flags().BoolVar(&opts.RestartServices, "restart-services", false, "force restart clusters services (Use with care)")
 	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&opts.SkipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+	addForceUnlockFlag(cmd.Flags(), &opts.ForceUnlock)
 	return cmd
 }
 
-func doAddNode(out io.Writer, planFile string, opts *addNodeOpts, newNode install.Node) error {
-	planner := &install.FilePlanner{File: planFile}
+func doAddNode(out io.Writer, errOut io.Writer, planner *install.FilePlanner, opts *addNodeOpts, newNode install.Node) error {
+	stdout := out
+	out = humanOutput(out, errOut, opts.OutputFormat)
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
+	// the new node is written to the plan file once it is added to the
+	// cluster, which is refused when the plan file has overlays
+	if len(planner.Overlays) > 0 {
+		return fmt.Errorf("cannot add a node to a plan file that has overlays, add the node to the plan file %q or one of its overlays and run 'kismatic install apply' instead", planner.File)
+	}
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	execOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.GeneratedAssetsDirectory,
+		RunsDirectory:            opts.RunsDirectory,
 		OutputFormat:             opts.OutputFormat,
 		Verbose:                  opts.Verbose,
+		Context:                  ctx,
 	}
-	executor, err := install.NewExecutor(out, os.Stderr, execOpts)
+	executor, err := install.NewExecutor(stdout, errOut, execOpts)
 	if err != nil {
 		return err
 	}
@@ -92,6 +107,11 @@ func doAddNode(out io.Writer, planFile string, opts *addNodeOpts, newNode instal
 	if err != nil {
 		return fmt.Errorf("failed to read plan file: %v", err)
 	}
+	for _, role := range opts.Roles {
+		if plan.HasInventorySource(role) {
+			return fmt.Errorf("the %s nodes are read from an inventory source, add the new node to the inventory source and run 'kismatic install apply' instead", role)
+		}
+	}
 	if _, errs := install.ValidateNode(&newNode); errs != nil {
 		util.PrintValidationErrors(out, errs)
 		return errors.New("information provided about the new node is invalid")
@@ -102,8 +122,9 @@ func doAddNode(out io.Writer, planFile string, opts *addNodeOpts, newNode instal
 		util.PrintValidationErrors(out, errs)
 		return errors.New("the plan file failed validation")
 	}
+	nodeSSH := plan.Cluster.SSH.ForNode(newNode)
 	nodeSSHCon := &install.SSHConnection{
-		SSHConfig: &plan.Cluster.SSH,
+		SSHConfig: &nodeSSH,
 		Node:      &newNode,
 	}
 	if _, errs := install.ValidateSSHConnection(nodeSSHCon, "New node"); errs != nil {
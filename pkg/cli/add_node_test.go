@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestAddNodeRefusesPlanOverlays(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-add-node-overlays")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	base := filepath.Join(tmp, "base.yaml")
+	overlay := filepath.Join(tmp, "overlay.yaml")
+	if err = ioutil.WriteFile(base, []byte("cluster:\n  name: base\n"), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	if err = ioutil.WriteFile(overlay, []byte("cluster:\n  name: prod\n"), 0644); err != nil {
+		t.Fatalf("error writing overlay file: %v", err)
+	}
+	planner := &install.FilePlanner{File: base, Overlays: []string{overlay}}
+	opts := &addNodeOpts{
+		Roles:                    []string{"worker"},
+		GeneratedAssetsDirectory: filepath.Join(tmp, "generated"),
+		RunsDirectory:            filepath.Join(tmp, "runs"),
+		OutputFormat:             "simple",
+	}
+	newNode := install.Node{Host: "worker2", IP: "10.0.0.2"}
+	err = doAddNode(&bytes.Buffer{}, &bytes.Buffer{}, planner, opts, newNode)
+	if err == nil || !strings.Contains(err.Error(), "overlays") {
+		t.Errorf("expected adding a node to a plan file with overlays to be refused, got: %v", err)
+	}
+	d, err := ioutil.ReadFile(base)
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	if string(d) != "cluster:\n  name: base\n" {
+		t.Errorf("expected the plan file to be unchanged, got:\n%s", d)
+	}
+}
@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -12,10 +11,12 @@ import (
 
 type applyCmd struct {
 	out                io.Writer
+	errOut             io.Writer
 	planner            install.Planner
 	executor           install.Executor
 	planFile           string
 	generatedAssetsDir string
+	runsDir            string
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
@@ -25,15 +26,22 @@ type applyCmd struct {
 
 type applyOpts struct {
 	generatedAssetsDir string
+	runsDir            string
 	restartServices    bool
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	resume             bool
+	eventWebhook       string
+	webhookAnsible     bool
+	profile            bool
+	retry              install.RetryPolicy
+	forceUnlock        bool
 	limit              []string
 }
 
 // NewCmdApply creates a cluter using the plan file
-func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
+func NewCmdApply(out io.Writer, errOut io.Writer, installOpts *installOpts) *cobra.Command {
 	applyOpts := applyOpts{}
 	cmd := &cobra.Command{
 		Use:   "apply",
@@ -42,45 +50,68 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: installOpts.planFilename}
+			planner := installOpts.planner()
+			sink, err := newEventSink(applyOpts.eventWebhook, applyOpts.webhookAnsible)
+			if err != nil {
+				return err
+			}
+			defer closeEventSink(humanOutput(out, errOut, applyOpts.outputFormat), sink)
+			ctx, stop := cancelOnInterrupt(errOut)
+			defer stop()
 			executorOpts := install.ExecutorOptions{
 				GeneratedAssetsDirectory: applyOpts.generatedAssetsDir,
+				RunsDirectory:            applyOpts.runsDir,
 				OutputFormat:             applyOpts.outputFormat,
 				Verbose:                  applyOpts.verbose,
+				Resume:                   applyOpts.resume,
+				EventSink:                sink,
+				Profile:                  applyOpts.profile,
+				Retry:                    applyOpts.retry,
+				Context:                  ctx,
 			}
-			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			executor, err := install.NewExecutor(out, errOut, executorOpts)
 			if err != nil {
 				return err
 			}
 
 			applyCmd := &applyCmd{
 				out:                out,
+				errOut:             errOut,
 				planner:            planner,
 				executor:           executor,
 				planFile:           installOpts.planFilename,
 				generatedAssetsDir: applyOpts.generatedAssetsDir,
+				runsDir:            applyOpts.runsDir,
 				verbose:            applyOpts.verbose,
 				outputFormat:       applyOpts.outputFormat,
-				skipPreFlight:      applyOpts.skipPreFlight,
+				skipPreFlight:      applyOpts.skipPreFlight || applyOpts.resume,
 				restartServices:    applyOpts.restartServices,
 				limit:              applyOpts.limit,
 			}
-			return applyCmd.run()
+			return withClusterLock(out, applyOpts.generatedAssetsDir, cmd.CommandPath(), applyOpts.forceUnlock, applyCmd.run)
 		},
 	}
 
 	// Flags
 	cmd.Flags().StringSliceVar(&applyOpts.limit, "limit", []string{}, "comma-separated list of hostnames to limit the execution to a subset of nodes")
 	cmd.Flags().StringVar(&applyOpts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &applyOpts.runsDir)
 	cmd.Flags().BoolVar(&applyOpts.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.Flags().BoolVar(&applyOpts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&applyOpts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+	addEventWebhookFlags(cmd.Flags(), &applyOpts.eventWebhook, &applyOpts.webhookAnsible)
+	addProfileFlag(cmd.Flags(), &applyOpts.profile)
+	addRetryFlags(cmd.Flags(), &applyOpts.retry)
+	addForceUnlockFlag(cmd.Flags(), &applyOpts.forceUnlock)
+	cmd.Flags().BoolVar(&applyOpts.resume, "resume", false, "resume the last failed installation on the same plan, skipping the plays that were completed. Implies --skip-preflight")
 
 	return cmd
 }
 
 func (c *applyCmd) run() error {
+	out := humanOutput(c.out, c.errOut, c.outputFormat)
+	phases := newPhaseMarker(c.out, c.outputFormat)
 	// Validate and run pre-flight
 	opts := &validateOpts{
 		planFile:           c.planFile,
@@ -88,9 +119,10 @@ func (c *applyCmd) run() error {
 		outputFormat:       c.outputFormat,
 		skipPreFlight:      c.skipPreFlight,
 		generatedAssetsDir: c.generatedAssetsDir,
+		runsDir:            c.runsDir,
 		limit:              c.limit,
 	}
-	err := doValidate(c.out, c.planner, opts)
+	err := doValidate(c.out, c.errOut, c.planner, opts)
 	if err != nil {
 		return fmt.Errorf("error validating plan: %v", err)
 	}
@@ -105,12 +137,14 @@ func (c *applyCmd) run() error {
 	}
 
 	// Generate kubeconfig
-	util.PrintHeader(c.out, "Generating Kubeconfig File", '=')
-	err = install.GenerateKubeconfig(plan, c.generatedAssetsDir)
+	util.PrintHeader(out, "Generating Kubeconfig File", '=')
+	err = phases.run(install.PhaseKubeconfig, func() error {
+		return install.GenerateKubeconfig(plan, c.generatedAssetsDir)
+	})
 	if err != nil {
 		return fmt.Errorf("error generating kubeconfig file: %v", err)
 	}
-	util.PrettyPrintOk(c.out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
+	util.PrettyPrintOk(out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
 
 	// Perform the installation
 	if err := c.executor.Install(plan, c.restartServices, c.limit...); err != nil {
@@ -125,16 +159,16 @@ func (c *applyCmd) run() error {
 		}
 	}
 
-	util.PrintColor(c.out, util.Green, "\nThe cluster was installed successfully!\n")
-	fmt.Fprintln(c.out)
+	util.PrintColor(out, util.Green, "\nThe cluster was installed successfully!\n")
+	fmt.Fprintln(out)
 
 	msg := "- To use the generated kubeconfig file with kubectl:" +
 		"\n    * use \"./kubectl --kubeconfig %s/kubeconfig\"" +
 		"\n    * or copy the config file \"cp %[1]s/kubeconfig ~/.kube/config\"\n"
-	util.PrintColor(c.out, util.Blue, msg, c.generatedAssetsDir)
-	util.PrintColor(c.out, util.Blue, "- To view the Kubernetes dashboard: \"./kismatic dashboard\"\n")
-	util.PrintColor(c.out, util.Blue, "- To SSH into a cluster node: \"./kismatic ssh etcd|master|worker|storage|$node.host\"\n")
-	fmt.Fprintln(c.out)
+	util.PrintColor(out, util.Blue, msg, c.generatedAssetsDir)
+	util.PrintColor(out, util.Blue, "- To view the Kubernetes dashboard: \"./kismatic dashboard\"\n")
+	util.PrintColor(out, util.Blue, "- To SSH into a cluster node: \"./kismatic ssh etcd|master|worker|storage|$node.host\"\n")
+	fmt.Fprintln(out)
 
 	return nil
 }
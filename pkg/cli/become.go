@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// askBecomePassword prompts once for the password for sudo on the nodes. The
+// password is used by all the plans that are read, unless the plan refers to
+// its own password.
+func askBecomePassword(out io.Writer) error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return fmt.Errorf("--ask-become-pass requires a terminal. Set cluster.ssh.become_password to a secret reference, such as ${env:NAME}, instead")
+	}
+	fmt.Fprint(out, "Enter the sudo password of the nodes: ")
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(out)
+	if err != nil {
+		return fmt.Errorf("error reading the sudo password: %v", err)
+	}
+	install.SetBecomePassword(string(password))
+	return nil
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// clusterPathFlags are the flags that are set to the paths of the targeted
+// workspace cluster, unless they are set on the command line.
+var clusterPathFlags = map[string]func(install.WorkspaceCluster) string{
+	"plan-file":            install.WorkspaceCluster.PlanFile,
+	"generated-assets-dir": install.WorkspaceCluster.GeneratedAssetsDirectory,
+	"runs-dir":             install.WorkspaceCluster.RunsDirectory,
+}
+
+// NewCmdCluster creates a new command for managing the clusters of the workspace
+func NewCmdCluster(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "manage the clusters of your workspace",
+		Long: `Manage the clusters of your workspace.
+
+Each cluster of the workspace has its own plan file, generated assets and runs
+directory. Use the --cluster flag to target a cluster with the commands that
+use these files, or 'kismatic cluster use' to set the cluster that is targeted
+by default.
+
+The workspace is kept in the "` + install.DefaultWorkspaceDirectory + `" directory, unless the ` + install.WorkspaceEnvVar + `
+environment variable is set.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+		// the cluster commands manage the workspace, they don't target a cluster
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("cluster") {
+				return errClusterNotSupported(cmd)
+			}
+			return nil
+		},
+	}
+	cmd.AddCommand(NewCmdClusterList(out))
+	cmd.AddCommand(NewCmdClusterCreate(out))
+	cmd.AddCommand(NewCmdClusterUse(out))
+	cmd.AddCommand(NewCmdClusterCurrent(out))
+	return cmd
+}
+
+// targetCluster sets the path flags of the command to the paths of the named
+// cluster of the workspace, or of the cluster in use when no name is given.
+// Flags that were set on the command line are not modified. Naming a cluster
+// is an error when the command has none of the path flags.
+func targetCluster(cmd *cobra.Command, ws install.Workspace, name string) error {
+	flags := []string{}
+	for f := range clusterPathFlags {
+		if cmd.Flags().Lookup(f) != nil {
+			flags = append(flags, f)
+		}
+	}
+	if len(flags) == 0 {
+		if name != "" {
+			return errClusterNotSupported(cmd)
+		}
+		return nil
+	}
+	var c *install.WorkspaceCluster
+	var err error
+	if name != "" {
+		c, err = ws.Cluster(name)
+	} else {
+		c, err = ws.Current()
+	}
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return nil
+	}
+	for _, f := range flags {
+		if cmd.Flags().Changed(f) {
+			continue
+		}
+		if err := cmd.Flags().Set(f, clusterPathFlags[f](*c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func errClusterNotSupported(cmd *cobra.Command) error {
+	return fmt.Errorf("%s does not use the files of a cluster, the --cluster flag is not supported", cmd.CommandPath())
+}
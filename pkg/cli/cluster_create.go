@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type clusterCreateOpts struct {
+	fromDirectory string
+	use           bool
+}
+
+// NewCmdClusterCreate creates a new command for adding a cluster to the workspace
+func NewCmdClusterCreate(out io.Writer) *cobra.Command {
+	opts := clusterCreateOpts{}
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "create a new cluster in the workspace",
+		Long: `Create a new cluster in the workspace.
+
+Use --from to import a cluster that was installed from another directory. The
+plan file, generated assets and runs of the directory are copied into the new
+cluster.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doClusterCreate(out, install.DefaultWorkspace(), args[0], opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.fromDirectory, "from", "", "path to a directory that contains the kismatic-cluster.yaml plan file and the generated assets of an existing cluster")
+	cmd.Flags().BoolVar(&opts.use, "use", false, "use the new cluster when no cluster is targeted")
+	return cmd
+}
+
+func doClusterCreate(out io.Writer, ws install.Workspace, name string, opts clusterCreateOpts) error {
+	if opts.fromDirectory != "" {
+		if _, err := os.Stat(filepath.Join(opts.fromDirectory, "kismatic-cluster.yaml")); err != nil {
+			return fmt.Errorf("cannot import cluster from %q: %v", opts.fromDirectory, err)
+		}
+	}
+	c, err := ws.CreateCluster(name)
+	if err != nil {
+		return err
+	}
+	if opts.fromDirectory != "" {
+		if err := util.CopyFile(filepath.Join(opts.fromDirectory, "kismatic-cluster.yaml"), c.PlanFile()); err != nil {
+			return fmt.Errorf("error copying plan file: %v", err)
+		}
+		dirs := map[string]string{
+			filepath.Join(opts.fromDirectory, "generated"): c.GeneratedAssetsDirectory(),
+			filepath.Join(opts.fromDirectory, "runs"):      c.RunsDirectory(),
+		}
+		for from, to := range dirs {
+			if _, err := os.Stat(from); os.IsNotExist(err) {
+				continue
+			}
+			if err := util.CopyDirectory(from, to); err != nil {
+				return fmt.Errorf("error copying %q: %v", from, err)
+			}
+		}
+		util.PrettyPrintOk(out, "Imported cluster %q from %q", name, opts.fromDirectory)
+	} else {
+		util.PrettyPrintOk(out, "Created cluster %q", name)
+		fmt.Fprintf(out, "Generate its plan file with 'kismatic install plan --cluster %s'\n", name)
+	}
+	if opts.use {
+		if err := ws.Use(name); err != nil {
+			return err
+		}
+		util.PrettyPrintOk(out, "Using cluster %q", name)
+	}
+	return nil
+}
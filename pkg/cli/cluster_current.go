@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdClusterCurrent creates a new command for printing the cluster that is targeted by default
+func NewCmdClusterCurrent(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "current",
+		Short: "print the cluster that is targeted when no cluster is specified",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doClusterCurrent(out, install.DefaultWorkspace())
+		},
+	}
+	return cmd
+}
+
+func doClusterCurrent(out io.Writer, ws install.Workspace) error {
+	c, err := ws.Current()
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("no cluster is in use. Use 'kismatic cluster use' to set the cluster")
+	}
+	fmt.Fprintln(out, c.Name)
+	return nil
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdClusterList creates a new command for listing the clusters of the workspace
+func NewCmdClusterList(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the clusters of the workspace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doClusterList(out, install.DefaultWorkspace())
+		},
+	}
+	return cmd
+}
+
+func doClusterList(out io.Writer, ws install.Workspace) error {
+	clusters, err := ws.Clusters()
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		fmt.Fprintf(out, "No clusters were found in %q. You may use 'kismatic cluster create' to create a cluster.\n", ws.Directory)
+		return nil
+	}
+	current, err := ws.Current()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tNAME\tPLAN FILE")
+	for _, c := range clusters {
+		mark := ""
+		if current != nil && current.Name == c.Name {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", mark, c.Name, c.PlanFile())
+	}
+	return w.Flush()
+}
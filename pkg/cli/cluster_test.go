@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+func TestTargetCluster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kismatic-cluster-cmd")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ws := install.Workspace{Directory: dir}
+	out := &bytes.Buffer{}
+	for _, name := range []string{"dev", "prod"} {
+		if err = doClusterCreate(out, ws, name, clusterCreateOpts{}); err != nil {
+			t.Fatalf("unexpected error creating cluster: %v", err)
+		}
+	}
+
+	newCmd := func() *cobra.Command {
+		var planFile, assetsDir, runsDir string
+		cmd := &cobra.Command{Use: "test"}
+		addPlanFileFlag(cmd.Flags(), &planFile)
+		cmd.Flags().StringVar(&assetsDir, "generated-assets-dir", "generated", "")
+		addRunsDirFlag(cmd.Flags(), &runsDir)
+		return cmd
+	}
+
+	// no cluster in use: the defaults are kept
+	cmd := newCmd()
+	if err = targetCluster(cmd, ws, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := cmd.Flags().Lookup("plan-file").Value.String(); v != "kismatic-cluster.yaml" {
+		t.Errorf("expected the default plan file, but got %q", v)
+	}
+
+	// the cluster in use is targeted
+	if err = doClusterUse(out, ws, "prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd = newCmd()
+	if err = targetCluster(cmd, ws, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"plan-file":            filepath.Join(dir, "prod", "kismatic-cluster.yaml"),
+		"generated-assets-dir": filepath.Join(dir, "prod", "generated"),
+		"runs-dir":             filepath.Join(dir, "prod", "runs"),
+	}
+	for f, v := range expected {
+		if got := cmd.Flags().Lookup(f).Value.String(); got != v {
+			t.Errorf("expected flag %q to be %q, but got %q", f, v, got)
+		}
+	}
+
+	// the named cluster is targeted, and flags set on the command line are kept
+	cmd = newCmd()
+	if err = cmd.Flags().Parse([]string{"--plan-file", "other.yaml"}); err != nil {
+		t.Fatalf("unexpected error parsing flags: %v", err)
+	}
+	if err = targetCluster(cmd, ws, "dev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := cmd.Flags().Lookup("plan-file").Value.String(); v != "other.yaml" {
+		t.Errorf("expected the plan file set on the command line, but got %q", v)
+	}
+	if v := cmd.Flags().Lookup("runs-dir").Value.String(); v != filepath.Join(dir, "dev", "runs") {
+		t.Errorf("expected the runs directory of the dev cluster, but got %q", v)
+	}
+
+	if err = targetCluster(newCmd(), ws, "staging"); err == nil {
+		t.Errorf("expected an error targeting a cluster that does not exist")
+	}
+
+	// a command without path flags can't target a cluster
+	if err = targetCluster(&cobra.Command{Use: "test"}, ws, "dev"); err == nil {
+		t.Errorf("expected an error targeting a cluster with a command without path flags")
+	}
+	if err = targetCluster(&cobra.Command{Use: "test"}, ws, ""); err != nil {
+		t.Errorf("unexpected error for a command without path flags: %v", err)
+	}
+
+	out.Reset()
+	if err = doClusterList(out, ws); err != nil {
+		t.Fatalf("unexpected error listing clusters: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "*") || !strings.Contains(lines[2], "prod") {
+		t.Errorf("unexpected cluster list:\n%s", out.String())
+	}
+}
+
+func TestClusterFlagNotSupported(t *testing.T) {
+	for _, args := range [][]string{
+		{"version", "--cluster", "dev"},
+		{"cluster", "list", "--cluster", "dev"},
+	} {
+		out := &bytes.Buffer{}
+		cmd, err := NewKismaticCommand("", "", &bytes.Buffer{}, out, out)
+		if err != nil {
+			t.Fatalf("unexpected error creating the command: %v", err)
+		}
+		cmd.SetArgs(args)
+		if err = cmd.Execute(); err == nil {
+			t.Errorf("expected an error running %v", args)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdClusterUse creates a new command for setting the cluster that is targeted by default
+func NewCmdClusterUse(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use NAME",
+		Short: "set the cluster that is targeted when no cluster is specified",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doClusterUse(out, install.DefaultWorkspace(), args[0])
+		},
+	}
+	return cmd
+}
+
+func doClusterUse(out io.Writer, ws install.Workspace, name string) error {
+	if err := ws.Use(name); err != nil {
+		return err
+	}
+	util.PrettyPrintOk(out, "Using cluster %q", name)
+	return nil
+}
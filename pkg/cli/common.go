@@ -2,7 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"time"
 
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/pflag"
 )
 
@@ -10,6 +15,77 @@ func addPlanFileFlag(flagSet *pflag.FlagSet, p *string) {
 	flagSet.StringVarP(p, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
 }
 
+func addPlanOverlayFlag(flagSet *pflag.FlagSet, p *[]string) {
+	flagSet.StringSliceVar(p, "plan-overlay", []string{}, "path to a plan file that is merged on top of the installation plan file. Can be repeated to merge multiple overlays in order")
+}
+
+func addRunsDirFlag(flagSet *pflag.FlagSet, p *string) {
+	flagSet.StringVar(p, "runs-dir", install.DefaultRunsDirectory, "path to the directory where information about installation runs is kept")
+}
+
+func addEventWebhookFlags(flagSet *pflag.FlagSet, url *string, ansibleEvents *bool) {
+	flagSet.StringVar(url, "event-webhook", "", "URL that the installation events are posted to, one JSON object per request")
+	flagSet.BoolVar(ansibleEvents, "event-webhook-ansible-events", false, "also post the ansible event of each task to the event webhook. Only the run and node upgrade events are posted otherwise")
+}
+
+func addProfileFlag(flagSet *pflag.FlagSet, p *bool) {
+	flagSet.BoolVar(p, "profile", false, "print the slowest plays, tasks and hosts after each ansible run")
+}
+
+func addForceUnlockFlag(flagSet *pflag.FlagSet, p *bool) {
+	flagSet.BoolVar(p, "force-unlock", false, "remove the lock of the cluster before starting. Use only when the command that locked the cluster is not running anymore. Refused when the command is still running on this host")
+}
+
+// withClusterLock runs the command while holding the lock of the cluster
+// whose assets are generated in the directory. The lock that was left by a
+// command that did not exit is removed first when forced.
+func withClusterLock(out io.Writer, generatedAssetsDir string, command string, forceUnlock bool, run func() error) error {
+	if forceUnlock {
+		stale, err := install.ForceUnlockCluster(generatedAssetsDir)
+		if err != nil {
+			return err
+		}
+		if stale != nil {
+			util.PrettyPrintWarn(out, "Removed the lock of %q, run by %s with PID %d", stale.Command, stale.Owner, stale.PID)
+		}
+	}
+	lock, err := install.LockCluster(generatedAssetsDir, command)
+	if err != nil {
+		return err
+	}
+	defer lock.Release() // error deliberately ignored, the lock is removed with the generated assets on reset
+	return run()
+}
+
+func addRetryFlags(flagSet *pflag.FlagSet, p *install.RetryPolicy) {
+	flagSet.IntVar(&p.Attempts, "retries", 0, "number of times a playbook that failed because hosts failed or were unreachable is re-run on these hosts, when no other host ran it")
+	flagSet.DurationVar(&p.Backoff, "retry-backoff", 30*time.Second, "time to wait before re-running a playbook that failed, doubled before each following retry")
+}
+
+// newEventSink returns the event sink that posts events to the webhook URL,
+// or nil if the URL is empty
+func newEventSink(url string, ansibleEvents bool) (install.EventSink, error) {
+	if url == "" {
+		return nil, nil
+	}
+	sink, err := install.NewWebhookSink(install.WebhookOptions{URL: url, Retries: 3, AnsibleEvents: ansibleEvents})
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the event webhook: %v", err)
+	}
+	return sink, nil
+}
+
+// closeEventSink delivers the events that are still buffered. Events that
+// could not be delivered do not fail the command.
+func closeEventSink(out io.Writer, sink install.EventSink) {
+	if sink == nil {
+		return
+	}
+	if err := sink.Close(); err != nil {
+		util.PrettyPrintWarn(out, "Delivering events to the event webhook: %v", err)
+	}
+}
+
 type planFileNotFoundErr struct {
 	filename string
 }
@@ -17,3 +93,37 @@ type planFileNotFoundErr struct {
 func (e planFileNotFoundErr) Error() string {
 	return fmt.Sprintf("Plan file not found at %q. If you don't have a plan file, you may generate one with 'kismatic install plan'", e.filename)
 }
+
+// humanOutput returns the writer for the human readable output of a command.
+// The json output format writes a JSON object per line to out, so the human
+// readable output goes to errOut instead.
+func humanOutput(out io.Writer, errOut io.Writer, outputFormat string) io.Writer {
+	if outputFormat == "json" {
+		return errOut
+	}
+	return out
+}
+
+// phaseMarker marks the start and end of the kismatic phases in the json
+// output format, and does nothing for the other formats
+type phaseMarker struct {
+	json *explain.JSONExplainer
+}
+
+func newPhaseMarker(out io.Writer, outputFormat string) phaseMarker {
+	if outputFormat == "json" {
+		return phaseMarker{json: explain.NewJSONExplainer(out)}
+	}
+	return phaseMarker{}
+}
+
+// run marks the start of the phase, runs it, and marks its end
+func (m phaseMarker) run(phase string, f func() error) error {
+	if m.json == nil {
+		return f()
+	}
+	m.json.PhaseStarted(phase)
+	err := f()
+	m.json.PhaseFinished(phase, err)
+	return err
+}
@@ -19,6 +19,12 @@ const dashboardAdminKubeconfigFilename = "dashboard-admin-kubeconfig"
 type dashboardOpts struct {
 	generatedAssetsDir string
 	planFilename       string
+	planOverlays       []string
+}
+
+// planner returns a planner for the plan file, merged with its overlays
+func (opts *dashboardOpts) planner() *install.FilePlanner {
+	return &install.FilePlanner{File: opts.planFilename, Overlays: opts.planOverlays}
 }
 
 const url = "http://localhost:8001/api/v1/namespaces/kube-system/services/https:kubernetes-dashboard:/proxy/#!/login"
@@ -61,16 +67,17 @@ func NewCmdDashboard(in io.Reader, out io.Writer) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			return doDashboard(in, out, opts)
+			return doDashboard(in, out, &opts)
 		},
 	}
 
 	cmd.PersistentFlags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &opts.planOverlays)
 
 	cmd.AddCommand(NewCmdDashboardURL(out))
-	cmd.AddCommand(NewCmdDashboardToken(out, opts))
-	cmd.AddCommand(NewCmdDashboardKubeconfig(out, opts))
+	cmd.AddCommand(NewCmdDashboardToken(out, &opts))
+	cmd.AddCommand(NewCmdDashboardKubeconfig(out, &opts))
 
 	return cmd
 }
@@ -89,7 +96,7 @@ func NewCmdDashboardURL(out io.Writer) *cobra.Command {
 	}
 }
 
-func NewCmdDashboardToken(out io.Writer, opts dashboardOpts) *cobra.Command {
+func NewCmdDashboardToken(out io.Writer, opts *dashboardOpts) *cobra.Command {
 	return &cobra.Command{
 		Use:   "token",
 		Short: "Print the ServiceAccount 'kubernetes-dashboard-admin' token",
@@ -111,7 +118,7 @@ func NewCmdDashboardToken(out io.Writer, opts dashboardOpts) *cobra.Command {
 	}
 }
 
-func NewCmdDashboardKubeconfig(out io.Writer, opts dashboardOpts) *cobra.Command {
+func NewCmdDashboardKubeconfig(out io.Writer, opts *dashboardOpts) *cobra.Command {
 	return &cobra.Command{
 		Use:   "kubeconfig",
 		Short: "Generate a kubeconfig file with the ServiceAccount 'kubernetes-dashboard-admin' token",
@@ -126,7 +133,7 @@ func NewCmdDashboardKubeconfig(out io.Writer, opts dashboardOpts) *cobra.Command
 			adminKubeconfig := filepath.Join(opts.generatedAssetsDir, dashboardAdminKubeconfigFilename)
 			// Generate dashboard admin certificate if it does not exist
 			if _, err := os.Stat(adminKubeconfig); os.IsNotExist(err) {
-				if err := generateKubeconfig(opts.planFilename, opts.generatedAssetsDir, adminKubeconfig); err != nil {
+				if err := generateKubeconfig(opts.planner(), opts.generatedAssetsDir, adminKubeconfig); err != nil {
 					return err
 				}
 				fmt.Fprintf(out, "Generated kubeconfig in %q\n", adminKubeconfig)
@@ -138,7 +145,7 @@ func NewCmdDashboardKubeconfig(out io.Writer, opts dashboardOpts) *cobra.Command
 	}
 }
 
-func doDashboard(in io.Reader, out io.Writer, opts dashboardOpts) error {
+func doDashboard(in io.Reader, out io.Writer, opts *dashboardOpts) error {
 	kubeconfig := filepath.Join(opts.generatedAssetsDir, "kubeconfig")
 	if stat, err := os.Stat(kubeconfig); os.IsNotExist(err) || stat.IsDir() {
 		return fmt.Errorf("Did not find required kubeconfig file %q", kubeconfig)
@@ -147,7 +154,7 @@ func doDashboard(in io.Reader, out io.Writer, opts dashboardOpts) error {
 	adminKubeconfig := filepath.Join(opts.generatedAssetsDir, dashboardAdminKubeconfigFilename)
 	// Generate dashboard admin certificate if it does not exist
 	if _, err := os.Stat(adminKubeconfig); os.IsNotExist(err) {
-		generateErr = generateKubeconfig(opts.planFilename, opts.generatedAssetsDir, adminKubeconfig)
+		generateErr = generateKubeconfig(opts.planner(), opts.generatedAssetsDir, adminKubeconfig)
 	}
 
 	if generateErr != nil {
@@ -174,8 +181,7 @@ func doDashboard(in io.Reader, out io.Writer, opts dashboardOpts) error {
 	return nil
 }
 
-func generateKubeconfig(planeFile, generatedAssetsDir, outFile string) error {
-	planner := &install.FilePlanner{File: planeFile}
+func generateKubeconfig(planner *install.FilePlanner, generatedAssetsDir, outFile string) error {
 	plan, err := planner.Read()
 	if err != nil {
 		return fmt.Errorf("Error reading plan file: %v", err)
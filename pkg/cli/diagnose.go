@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -12,12 +11,14 @@ import (
 
 type diagsOpts struct {
 	planFilename string
+	planOverlays []string
+	runsDir      string
 	verbose      bool
 	outputFormat string
 }
 
 // NewCmdDiagnostic collects diagnostic data on remote nodes
-func NewCmdDiagnostic(out io.Writer) *cobra.Command {
+func NewCmdDiagnostic(out io.Writer, errOut io.Writer) *cobra.Command {
 	opts := &diagsOpts{}
 
 	cmd := &cobra.Command{
@@ -28,23 +29,27 @@ func NewCmdDiagnostic(out io.Writer) *cobra.Command {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
 
-			return doDiagnostics(out, opts)
+			return doDiagnostics(out, errOut, opts)
 		},
 	}
 
 	// PersistentFlags
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &opts.planOverlays)
+	addRunsDirFlag(cmd.Flags(), &opts.runsDir)
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 
 	return cmd
 }
 
-func doDiagnostics(out io.Writer, opts *diagsOpts) error {
+func doDiagnostics(out io.Writer, errOut io.Writer, opts *diagsOpts) error {
+	stdout := out
+	out = humanOutput(out, errOut, opts.outputFormat)
 	util.PrintHeader(out, "Gathering Diagnostic Data", '=')
 
 	planFile := opts.planFilename
-	planner := install.FilePlanner{File: planFile}
+	planner := install.FilePlanner{File: planFile, Overlays: opts.planOverlays}
 
 	// Read plan file
 	if !planner.PlanExists() {
@@ -67,11 +72,15 @@ func doDiagnostics(out io.Writer, opts *diagsOpts) error {
 	util.PrettyPrintOk(out, "Validate SSH connectivity to nodes")
 
 	// Get diagnostics from nodes
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	options := install.ExecutorOptions{
-		OutputFormat: opts.outputFormat,
-		Verbose:      opts.verbose,
+		OutputFormat:  opts.outputFormat,
+		Verbose:       opts.verbose,
+		RunsDirectory: opts.runsDir,
+		Context:       ctx,
 	}
-	executor, err := install.NewDiagnosticsExecutor(out, os.Stderr, options)
+	executor, err := install.NewDiagnosticsExecutor(stdout, errOut, options)
 	if err != nil {
 		return err
 	}
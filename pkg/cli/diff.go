@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type diffOpts struct {
+	planFile      string
+	runsDirectory string
+	runDirectory  string
+}
+
+// NewCmdDiff creates a new command for comparing the plan file with the last applied plan
+func NewCmdDiff(out io.Writer, installOpts *installOpts) *cobra.Command {
+	opts := diffOpts{}
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "compare your plan file with the plan file of the last successful installation",
+		Long: `Compare your plan file with the plan file that was used by the most recent
+successful run of 'kismatic install apply'.
+
+The nodes that were added, removed or changed, the add-ons that were enabled or
+disabled and the fields that were changed are printed, together with the plays
+and nodes that would be affected when applying the plan file.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			opts.planFile = installOpts.planFilename
+			// compare secret references instead of the secrets
+			planner := installOpts.planner()
+			planner.KeepSecretReferences = true
+			return doDiff(out, planner, opts)
+		},
+	}
+	addRunsDirFlag(cmd.Flags(), &opts.runsDirectory)
+	cmd.Flags().StringVar(&opts.runDirectory, "run", "", "path to the directory of a specific run to compare against, instead of the last successful installation")
+	return cmd
+}
+
+func doDiff(out io.Writer, planner install.Planner, opts diffOpts) error {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFile}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+
+	runDir := opts.runDirectory
+	if runDir == "" {
+		run, err := install.LastSuccessfulRun(opts.runsDirectory, "apply")
+		if err != nil {
+			return fmt.Errorf("error finding the last successful installation: %v", err)
+		}
+		if run == nil {
+			return fmt.Errorf("no successful installation was found in %q", opts.runsDirectory)
+		}
+		runDir = run.Directory
+	}
+	appliedPlanner := &install.FilePlanner{File: (install.Run{Directory: runDir}).PlanFile(), KeepSecretReferences: true}
+	if !appliedPlanner.PlanExists() {
+		return fmt.Errorf("plan file %q was not found", appliedPlanner.File)
+	}
+	applied, err := appliedPlanner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file of run %q: %v", runDir, err)
+	}
+
+	fmt.Fprintf(out, "Comparing %q with the plan file applied in %q\n", opts.planFile, runDir)
+	d := install.DiffPlans(applied, plan)
+	if d.Empty() {
+		util.PrettyPrintOk(out, "The plan file has not changed since it was applied")
+		return nil
+	}
+	printPlanDiff(out, d)
+
+	util.PrintHeader(out, "Affected Plays", '=')
+	for _, pi := range d.Impact(plan) {
+		hosts := "(none)"
+		if len(pi.Hosts) > 0 {
+			hosts = strings.Join(pi.Hosts, ", ")
+		}
+		fmt.Fprintf(out, "%s: %s\n", pi.Play, hosts)
+	}
+	if len(d.NodesRemoved) > 0 {
+		fmt.Fprintln(out)
+		util.PrettyPrintWarn(out, "Nodes removed from the plan file are not removed from the cluster by 'kismatic install apply'")
+	}
+	return nil
+}
+
+func printPlanDiff(out io.Writer, d install.PlanDiff) {
+	if len(d.NodesAdded)+len(d.NodesRemoved)+len(d.NodesChanged) > 0 {
+		util.PrintHeader(out, "Nodes", '=')
+		for _, n := range d.NodesAdded {
+			fmt.Fprintf(out, "+ %s (%s)\n", n.Host, n.Group)
+		}
+		for _, n := range d.NodesRemoved {
+			fmt.Fprintf(out, "- %s (%s)\n", n.Host, n.Group)
+		}
+		for _, n := range d.NodesChanged {
+			fmt.Fprintf(out, "~ %s (%s)\n", n.Host, n.Group)
+			for _, f := range n.Fields {
+				fmt.Fprintf(out, "    %s: %s -> %s\n", f.Path, f.From, f.To)
+			}
+		}
+	}
+	if len(d.AddOnsToggled) > 0 {
+		util.PrintHeader(out, "Add-Ons", '=')
+		for _, a := range d.AddOnsToggled {
+			state := "disabled"
+			if a.Enabled {
+				state = "enabled"
+			}
+			fmt.Fprintf(out, "%s: %s\n", a.Name, state)
+		}
+	}
+	if len(d.Fields) > 0 {
+		util.PrintHeader(out, "Fields", '=')
+		for _, f := range d.Fields {
+			fmt.Fprintf(out, "%s: %s -> %s\n", f.Path, f.From, f.To)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestDiffCmd(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-diff-cmd")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+
+	applied := &install.Plan{
+		Cluster: install.Cluster{Name: "test", Version: "v1.10.5"},
+		Master: install.MasterNodeGroup{
+			ExpectedCount: 1,
+			Nodes:         []install.Node{{Host: "master01", IP: "10.0.0.1"}},
+		},
+		Worker: install.NodeGroup{
+			ExpectedCount: 1,
+			Nodes:         []install.Node{{Host: "worker01", IP: "10.0.0.2"}},
+		},
+	}
+	runDir := filepath.Join(runsDir, "apply", "2018-01-01-10-00-00")
+	if err = os.MkdirAll(runDir, 0777); err != nil {
+		t.Fatalf("error creating run dir: %v", err)
+	}
+	fp := &install.FilePlanner{File: filepath.Join(runDir, "kismatic-cluster.yaml")}
+	if err = fp.Write(applied); err != nil {
+		t.Fatalf("error writing applied plan: %v", err)
+	}
+
+	// no successful run has been recorded
+	opts := diffOpts{planFile: "kismatic-cluster.yaml", runsDirectory: runsDir}
+	planner := &fakePlanner{exists: true, plan: applied}
+	if err = doDiff(&bytes.Buffer{}, planner, opts); err == nil {
+		t.Errorf("expected an error when there are no successful runs")
+	}
+
+	current, _ := fp.Read()
+	current.Worker.Nodes = append(current.Worker.Nodes, install.Node{Host: "worker02", IP: "10.0.0.3"})
+	current.Cluster.KubeletOptions.Overrides = map[string]string{"max-pods": "50"}
+	planner = &fakePlanner{exists: true, plan: current}
+	opts.runDirectory = runDir
+	out := &bytes.Buffer{}
+	if err = doDiff(out, planner, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"+ worker02 (worker)",
+		`cluster.kubelet.option_overrides.max-pods: (not set) -> "50"`,
+		"kubernetes.yaml: worker02",
+		"_kubelet.yaml: master01, worker01, worker02",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+}
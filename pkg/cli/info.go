@@ -14,6 +14,7 @@ import (
 
 type infoOpts struct {
 	planFilename string
+	planOverlays []string
 	outputFormat string
 }
 
@@ -31,13 +32,14 @@ This will be retrieved by connecting to each node via ssh`,
 		},
 	}
 	cmd.Flags().StringVarP(&opts.planFilename, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
+	addPlanOverlayFlag(cmd.Flags(), &opts.planOverlays)
 	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
 	return cmd
 }
 
 func list(out io.Writer, opts *infoOpts) error {
 	// Check if plan file exists
-	planner := &install.FilePlanner{File: opts.planFilename}
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.planOverlays}
 	if !planner.PlanExists() {
 		return fmt.Errorf("plan does not exist")
 	}
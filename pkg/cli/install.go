@@ -3,15 +3,22 @@ package cli
 import (
 	"io"
 
+	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/spf13/cobra"
 )
 
 type installOpts struct {
 	planFilename string
+	planOverlays []string
+}
+
+// planner returns a planner for the plan file, merged with its overlays
+func (opts *installOpts) planner() *install.FilePlanner {
+	return &install.FilePlanner{File: opts.planFilename, Overlays: opts.planOverlays}
 }
 
 // NewCmdInstall creates a new install command
-func NewCmdInstall(in io.Reader, out io.Writer) *cobra.Command {
+func NewCmdInstall(in io.Reader, out io.Writer, errOut io.Writer) *cobra.Command {
 	opts := &installOpts{}
 
 	cmd := &cobra.Command{
@@ -23,14 +30,16 @@ func NewCmdInstall(in io.Reader, out io.Writer) *cobra.Command {
 	}
 
 	// Subcommands
-	cmd.AddCommand(NewCmdPlan(in, out, opts))
-	cmd.AddCommand(NewCmdValidate(out, opts))
-	cmd.AddCommand(NewCmdApply(out, opts))
-	cmd.AddCommand(NewCmdAddNode(out, opts))
-	cmd.AddCommand(NewCmdStep(out, opts))
+	cmd.AddCommand(NewCmdPlan(in, out, errOut, opts))
+	cmd.AddCommand(NewCmdValidate(out, errOut, opts))
+	cmd.AddCommand(NewCmdApply(out, errOut, opts))
+	cmd.AddCommand(NewCmdAddNode(out, errOut, opts))
+	cmd.AddCommand(NewCmdStep(out, errOut, opts))
+	cmd.AddCommand(NewCmdDiff(out, opts))
 
 	// PersistentFlags
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &opts.planOverlays)
 
 	return cmd
 }
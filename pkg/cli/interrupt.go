@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// cancelOnInterrupt returns a context that is cancelled when kismatic gets
+// SIGINT or SIGTERM, so that the running playbook is stopped once its running
+// task completes. Interrupting kismatic again kills the running playbook, so
+// that the command stops right away, after recording the run as interrupted
+// and releasing the lock of the cluster. A third interrupt exits immediately.
+// The returned function stops handling the signals.
+func cancelOnInterrupt(errOut io.Writer) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case <-signals:
+			util.PrettyPrintWarn(errOut, "Interrupted, waiting for the running task to complete. Interrupt again to stop ansible right away")
+			cancel()
+		case <-stopped:
+			return
+		}
+		select {
+		case <-signals:
+			// the next signal gets the default behavior, and exits
+			signal.Stop(signals)
+			util.PrettyPrintWarn(errOut, "Interrupted again, stopping ansible. Interrupt again to exit immediately")
+			ansible.KillPlaybooks()
+		case <-stopped:
+		}
+	}()
+	return ctx, func() {
+		close(stopped)
+		cancel()
+	}
+}
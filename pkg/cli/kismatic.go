@@ -3,11 +3,14 @@ package cli
 import (
 	"io"
 
+	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/spf13/cobra"
 )
 
 // NewKismaticCommand creates the kismatic command
 func NewKismaticCommand(version string, buildDate string, in io.Reader, out, stderr io.Writer) (*cobra.Command, error) {
+	var clusterName string
+	var askBecomePass bool
 	cmd := &cobra.Command{
 		Use:   "kismatic",
 		Short: "kismatic is the main tool for managing your Kubernetes cluster",
@@ -16,22 +19,38 @@ more documentation is available at https://github.com/apprenda/kismatic`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := targetCluster(cmd, install.DefaultWorkspace(), clusterName); err != nil {
+				return err
+			}
+			if askBecomePass {
+				if err := askBecomePassword(stderr); err != nil {
+					return err
+				}
+			}
+			return useKnownHostsFile(cmd)
+		},
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
+	cmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "name of the workspace cluster to target, instead of the cluster in use. The plan file, generated assets and runs of the cluster are used, unless set explicitly. Not supported by the commands that use none of these")
+	cmd.PersistentFlags().BoolVar(&askBecomePass, "ask-become-pass", false, "prompt for the sudo password of the nodes, when the SSH user can't sudo without a password")
 
 	cmd.AddCommand(NewCmdVersion(buildDate, out))
-	cmd.AddCommand(NewCmdInstall(in, out))
-	cmd.AddCommand(NewCmdReset(in, out))
-	cmd.AddCommand(NewCmdVolume(in, out))
+	cmd.AddCommand(NewCmdInstall(in, out, stderr))
+	cmd.AddCommand(NewCmdReset(in, out, stderr))
+	cmd.AddCommand(NewCmdVolume(in, out, stderr))
 	cmd.AddCommand(NewCmdIP(out))
 	cmd.AddCommand(NewCmdDashboard(in, out))
 	cmd.AddCommand(NewCmdSSH(out))
+	cmd.AddCommand(NewCmdSSHKeys(out))
 	cmd.AddCommand(NewCmdInfo(out))
-	cmd.AddCommand(NewCmdUpgrade(in, out))
-	cmd.AddCommand(NewCmdDiagnostic(out))
+	cmd.AddCommand(NewCmdUpgrade(in, out, stderr))
+	cmd.AddCommand(NewCmdDiagnostic(out, stderr))
 	cmd.AddCommand(NewCmdCertificates(out))
 	cmd.AddCommand(NewCmdSeedRegistry(out, stderr))
+	cmd.AddCommand(NewCmdCluster(out))
+	cmd.AddCommand(NewCmdRuns(out))
 
 	return cmd, nil
 }
@@ -3,26 +3,89 @@ package cli
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"strings"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
 )
 
+type planOpts struct {
+	nonInteractive bool
+	answersFile    string
+	set            []string
+	nodes          []string
+	nodeCounts     map[string]*int
+}
+
 // NewCmdPlan creates a new install plan command
-func NewCmdPlan(in io.Reader, out io.Writer, options *installOpts) *cobra.Command {
+func NewCmdPlan(in io.Reader, out io.Writer, errOut io.Writer, options *installOpts) *cobra.Command {
+	opts := planOpts{nodeCounts: map[string]*int{}}
 	cmd := &cobra.Command{
 		Use:   "plan",
 		Short: "plan your Kubernetes cluster and generate a plan file",
+		Long: `Plan your Kubernetes cluster and generate a plan file.
+
+The number of nodes of the cluster is prompted for, unless the plan file is
+generated non-interactively. Any of the flags below, other than --plan-file,
+generate the plan file non-interactively:
+
+  --answers-file is a partial plan file. Its settings take precedence over the
+  defaults of the plan file template, so it may describe any section of the
+  plan file, such as the CNI and DNS providers, add-ons, docker storage, the
+  docker registry, the cloud provider and the nodes.
+
+  --set sets a single setting of the plan file, given its dot-separated path.
+  For example, --set add_ons.cni.provider=weave. Settings are applied after
+  the answers file.
+
+  --node adds a node to a node group, formatted as GROUP=HOST,IP[,INTERNAL_IP].
+  For example, --node etcd=etcd01,10.0.0.1.
+
+  --etcd-nodes, --master-nodes, --worker-nodes, --ingress-nodes and
+  --storage-nodes set the expected number of nodes of a group. The expected
+  number of nodes of a group defaults to the number of nodes that were given.
+  Empty entries are added for the nodes that were not given.
+
+The generated plan file is validated, so that pipelines fail when the plan
+file is not complete.
+`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: options.planFilename}
+			planner := options.planner()
+			for _, f := range []string{"answers-file", "set", "node"} {
+				opts.nonInteractive = opts.nonInteractive || cmd.Flags().Changed(f)
+			}
+			counts := map[string]int{}
+			for group, n := range opts.nodeCounts {
+				if cmd.Flags().Changed(group + "-nodes") {
+					counts[group] = *n
+					opts.nonInteractive = true
+				}
+			}
+			if opts.nonInteractive {
+				return doPlanNonInteractive(out, planner, options.planFilename, opts, counts)
+			}
 			return doPlan(in, out, planner, options.planFilename)
 		},
 	}
 
+	cmd.Flags().BoolVar(&opts.nonInteractive, "non-interactive", false, "generate the plan file without prompting")
+	cmd.Flags().StringVar(&opts.answersFile, "answers-file", "", "path to a partial plan file with the answers used to generate the plan file")
+	cmd.Flags().StringArrayVar(&opts.set, "set", []string{}, "set a setting of the plan file, formatted as path=value. May be repeated")
+	cmd.Flags().StringArrayVar(&opts.nodes, "node", []string{}, "add a node to a node group, formatted as GROUP=HOST,IP[,INTERNAL_IP]. May be repeated")
+	for _, group := range []string{"etcd", "master", "worker", "ingress", "storage"} {
+		opts.nodeCounts[group] = cmd.Flags().Int(group+"-nodes", 0, fmt.Sprintf("expected number of %s nodes", group))
+	}
+
+	// Subcommands
+	cmd.AddCommand(NewCmdPlanMigrate(out, options))
+	cmd.AddCommand(NewCmdPlanSchema(out))
+	cmd.AddCommand(NewCmdPlanRender(out, errOut, options))
+
 	return cmd
 }
 
@@ -102,3 +165,60 @@ func doPlan(in io.Reader, out io.Writer, planner install.Planner, planFile strin
 	fmt.Fprintf(out, "Edit the plan file to further describe your cluster. Once ready, execute the \"install validate\" command to proceed.\n")
 	return nil
 }
+
+func doPlanNonInteractive(out io.Writer, planner install.Planner, planFile string, opts planOpts, nodeCounts map[string]int) error {
+	answers := install.PlanAnswers{
+		Set:        opts.set,
+		NodeCounts: nodeCounts,
+		Nodes:      map[string][]install.Node{},
+	}
+	if opts.answersFile != "" {
+		b, err := ioutil.ReadFile(opts.answersFile)
+		if err != nil {
+			return fmt.Errorf("error reading answers file: %v", err)
+		}
+		answers.Plan = b
+	}
+	for _, n := range opts.nodes {
+		group, node, err := parseNodeFlag(n)
+		if err != nil {
+			return err
+		}
+		answers.Nodes[group] = append(answers.Nodes[group], node)
+	}
+	plan, err := install.BuildPlanFromAnswers(answers)
+	if err != nil {
+		return fmt.Errorf("error planning installation: %v", err)
+	}
+	if err := planner.Write(plan); err != nil {
+		return fmt.Errorf("error writing plan file: %v", err)
+	}
+	fmt.Fprintf(out, "Wrote plan file to %q\n", planFile)
+	if ok, errs := install.ValidatePlan(plan); !ok {
+		util.PrettyPrintErr(out, "Validating installation plan file")
+		util.PrintValidationErrors(out, errs)
+		return fmt.Errorf("the generated plan file is not valid")
+	}
+	util.PrettyPrintOk(out, "Validating installation plan file")
+	return nil
+}
+
+// parseNodeFlag parses a node formatted as GROUP=HOST,IP[,INTERNAL_IP]
+func parseNodeFlag(s string) (string, install.Node, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return "", install.Node{}, fmt.Errorf("invalid node %q: must be formatted as GROUP=HOST,IP[,INTERNAL_IP]", s)
+	}
+	fields := strings.Split(parts[1], ",")
+	if len(fields) < 2 || len(fields) > 3 {
+		return "", install.Node{}, fmt.Errorf("invalid node %q: must be formatted as GROUP=HOST,IP[,INTERNAL_IP]", s)
+	}
+	node := install.Node{
+		Host: strings.TrimSpace(fields[0]),
+		IP:   strings.TrimSpace(fields[1]),
+	}
+	if len(fields) == 3 {
+		node.InternalIP = strings.TrimSpace(fields[2])
+	}
+	return strings.TrimSpace(parts[0]), node, nil
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type planMigrateOpts struct {
+	planFile string
+	dryRun   bool
+}
+
+// NewCmdPlanMigrate creates a new command for migrating the plan file to the current version
+func NewCmdPlanMigrate(out io.Writer, installOpts *installOpts) *cobra.Command {
+	opts := planMigrateOpts{}
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "migrate your plan file to the current version of the plan file schema",
+		Long: `Migrate your plan file to the current version of the plan file schema.
+
+Deprecated fields are replaced with their current equivalent, and the plan file
+is rewritten with the current version. A backup of the original plan file is kept
+next to it, and the changes that were made are printed.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			opts.planFile = installOpts.planFilename
+			return doPlanMigrate(out, installOpts.planner(), opts)
+		},
+	}
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "print the changes that would be made to the plan file, but don't modify it")
+	return cmd
+}
+
+func doPlanMigrate(out io.Writer, planner *install.FilePlanner, opts planMigrateOpts) error {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: opts.planFile}
+	}
+	m, err := planner.Migrate()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	if !m.Required() {
+		util.PrettyPrintOk(out, "Plan file %q is at the current version %q", opts.planFile, m.ToVersion)
+		return nil
+	}
+
+	util.PrintHeader(out, fmt.Sprintf("Migrating plan file from version %s to %s", planVersionString(m.FromVersion), planVersionString(m.ToVersion)), '=')
+	fmt.Fprint(out, util.UnifiedDiff(opts.planFile, opts.planFile+" (migrated)", m.Original, m.Migrated))
+	fmt.Fprintln(out)
+	if opts.dryRun {
+		fmt.Fprintln(out, "Dry run, the plan file was not modified.")
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.%s.bak", opts.planFile, time.Now().Format("2006-01-02-15-04-05"))
+	if err := ioutil.WriteFile(backup, m.Original, 0644); err != nil {
+		return fmt.Errorf("error backing up plan file to %q: %v", backup, err)
+	}
+	util.PrettyPrintOk(out, "Backed up plan file to %q", backup)
+	if err := ioutil.WriteFile(opts.planFile, m.Migrated, 0644); err != nil {
+		return fmt.Errorf("error writing migrated plan file: %v", err)
+	}
+	util.PrettyPrintOk(out, "Migrated plan file %q to version %q", opts.planFile, m.ToVersion)
+	return nil
+}
+
+func planVersionString(version string) string {
+	if version == "" {
+		return "(unversioned)"
+	}
+	return version
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdPlanRender creates a new command for printing the plan file merged with its overlays
+func NewCmdPlanRender(out io.Writer, errOut io.Writer, installOpts *installOpts) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "print your plan file merged with its overlays, and validate it",
+		Long: `Print your plan file merged with its overlays, and validate it.
+
+Overlays are merged in the order they are provided with '--plan-overlay'.
+Maps, such as option overrides and labels, are merged key by key. A key that is
+set to null in an overlay is removed. Nodes are merged by host, and a node of an
+overlay that has '$patch: delete' is removed from the node group. All other
+values, including lists, are replaced by the value of the overlay.
+`,
+		Example: `  kismatic install plan render -f base.yaml --plan-overlay prod.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doPlanRender(out, errOut, installOpts.planner())
+		},
+	}
+	return cmd
+}
+
+// doPlanRender writes the merged plan to out, and the validation results to errOut
+func doPlanRender(out io.Writer, errOut io.Writer, planner *install.FilePlanner) error {
+	if !planner.PlanExists() {
+		return planFileNotFoundErr{filename: planner.File}
+	}
+	plan, err := planner.Render(out)
+	if err != nil {
+		return fmt.Errorf("error rendering plan file: %v", err)
+	}
+	return validatePlan(errOut, plan)
+}
@@ -3,8 +3,13 @@ package cli
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
 )
 
 func TestPlanCmdPlanNotFound(t *testing.T) {
@@ -101,3 +106,184 @@ func TestPlanCmdPlanNotFound(t *testing.T) {
 		}
 	}
 }
+
+func TestPlanCmdNonInteractive(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-non-interactive")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	key := filepath.Join(tmp, "kismaticuser.key")
+	if err = ioutil.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatalf("error writing ssh key: %v", err)
+	}
+	answers := filepath.Join(tmp, "answers.yaml")
+	if err = ioutil.WriteFile(answers, []byte("add_ons:\n  cni:\n    provider: weave\nmaster:\n  load_balancer: 10.0.0.2:6443\n"), 0644); err != nil {
+		t.Fatalf("error writing answers file: %v", err)
+	}
+	opts := planOpts{
+		answersFile: answers,
+		set:         []string{"cluster.ssh.ssh_key=" + key},
+		nodes: []string{
+			"etcd=etcd01,10.0.0.1",
+			"master=master01,10.0.0.2",
+			"worker=worker01,10.0.0.3,192.168.0.3",
+		},
+	}
+	out := &bytes.Buffer{}
+	fp := &fakePlanner{}
+	if err = doPlanNonInteractive(out, fp, "", opts, map[string]int{}); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out.String())
+	}
+	p := fp.plan
+	if p.AddOns.CNI.Provider != "weave" {
+		t.Errorf("expected CNI provider weave, got %q", p.AddOns.CNI.Provider)
+	}
+	if p.Worker.ExpectedCount != 1 || p.Worker.Nodes[0].InternalIP != "192.168.0.3" {
+		t.Errorf("unexpected worker nodes: %+v", p.Worker)
+	}
+
+	// the plan is not complete, so validation fails
+	out = &bytes.Buffer{}
+	fp = &fakePlanner{}
+	err = doPlanNonInteractive(out, fp, "", planOpts{nodes: []string{"etcd=etcd01,10.0.0.1"}}, map[string]int{"etcd": 3})
+	if err == nil {
+		t.Errorf("expected a validation error")
+	}
+	if fp.plan == nil || len(fp.plan.Etcd.Nodes) != 3 {
+		t.Errorf("expected the plan file to be written with empty entries for the missing nodes")
+	}
+}
+
+func TestParseNodeFlag(t *testing.T) {
+	tests := []struct {
+		in            string
+		expectedGroup string
+		expectedNode  install.Node
+		shouldError   bool
+	}{
+		{
+			in:            "etcd=etcd01,10.0.0.1",
+			expectedGroup: "etcd",
+			expectedNode:  install.Node{Host: "etcd01", IP: "10.0.0.1"},
+		},
+		{
+			in:            "worker=worker01, 10.0.0.1, 192.168.0.1",
+			expectedGroup: "worker",
+			expectedNode:  install.Node{Host: "worker01", IP: "10.0.0.1", InternalIP: "192.168.0.1"},
+		},
+		{
+			in:          "etcd01,10.0.0.1",
+			shouldError: true,
+		},
+		{
+			in:          "etcd=etcd01",
+			shouldError: true,
+		},
+		{
+			in:          "etcd=etcd01,10.0.0.1,192.168.0.1,foo",
+			shouldError: true,
+		},
+	}
+	for _, test := range tests {
+		group, node, err := parseNodeFlag(test.in)
+		if err != nil {
+			if !test.shouldError {
+				t.Errorf("%q: unexpected error: %v", test.in, err)
+			}
+			continue
+		}
+		if test.shouldError {
+			t.Errorf("%q: expected an error, but got none", test.in)
+			continue
+		}
+		if group != test.expectedGroup || node.Host != test.expectedNode.Host || node.IP != test.expectedNode.IP || node.InternalIP != test.expectedNode.InternalIP {
+			t.Errorf("%q: expected %s %+v, got %s %+v", test.in, test.expectedGroup, test.expectedNode, group, node)
+		}
+	}
+}
+
+func TestPlanMigrateCmd(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-migrate-cmd")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	file := filepath.Join(tmp, "kismatic-cluster.yaml")
+	original := []byte("cluster:\n  name: test\n  allow_package_installation: true\n")
+	if err = ioutil.WriteFile(file, original, 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	planner := &install.FilePlanner{File: file}
+
+	// dry run does not modify the plan file
+	out := &bytes.Buffer{}
+	if err = doPlanMigrate(out, planner, planMigrateOpts{planFile: file, dryRun: true}); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if !strings.Contains(out.String(), "-  allow_package_installation: true") {
+		t.Errorf("expected the diff to be printed, got:\n%s", out.String())
+	}
+	got, _ := ioutil.ReadFile(file)
+	if !bytes.Equal(got, original) {
+		t.Errorf("expected plan file to not be modified on dry run")
+	}
+
+	out = &bytes.Buffer{}
+	if err = doPlanMigrate(out, planner, planMigrateOpts{planFile: file}); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	backups, _ := filepath.Glob(file + ".*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("expected a backup of the plan file, found %v", backups)
+	}
+	backup, _ := ioutil.ReadFile(backups[0])
+	if !bytes.Equal(backup, original) {
+		t.Errorf("expected backup to contain the original plan file")
+	}
+	p, err := planner.Read()
+	if err != nil {
+		t.Fatalf("error reading migrated plan: %v", err)
+	}
+	if p.APIVersion != install.CurrentPlanAPIVersion || p.Cluster.AllowPackageInstallation != nil {
+		t.Errorf("expected plan file to be migrated to the current version")
+	}
+
+	// migrating a plan at the current version is a no-op
+	out = &bytes.Buffer{}
+	if err = doPlanMigrate(out, planner, planMigrateOpts{planFile: file}); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if !strings.Contains(out.String(), "is at the current version") {
+		t.Errorf("expected plan file to already be at the current version, got:\n%s", out.String())
+	}
+}
+
+func TestPlanRenderCmd(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-render-cmd")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	base := filepath.Join(tmp, "base.yaml")
+	overlay := filepath.Join(tmp, "overlay.yaml")
+	if err = ioutil.WriteFile(base, []byte("cluster:\n  name: base\n"), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	if err = ioutil.WriteFile(overlay, []byte("cluster:\n  name: prod\n"), 0644); err != nil {
+		t.Fatalf("error writing overlay file: %v", err)
+	}
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	planner := &install.FilePlanner{File: base, Overlays: []string{overlay}}
+	// the plan is not complete, so validation fails
+	if err = doPlanRender(out, errOut, planner); err == nil {
+		t.Errorf("expected a validation error")
+	}
+	if !strings.Contains(out.String(), "name: prod") {
+		t.Errorf("expected the merged plan to be printed, got:\n%s", out.String())
+	}
+	if !strings.Contains(errOut.String(), "Validating installation plan file") {
+		t.Errorf("expected the validation errors to be printed, got:\n%s", errOut.String())
+	}
+}
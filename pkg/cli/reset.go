@@ -13,16 +13,19 @@ import (
 
 type resetOpts struct {
 	planFilename       string
+	planOverlays       []string
 	generatedAssetsDir string
+	runsDir            string
 	verbose            bool
 	outputFormat       string
 	limit              []string
 	force              bool
 	removeAssets       bool
+	forceUnlock        bool
 }
 
 // NewCmdReset resets nodes
-func NewCmdReset(in io.Reader, out io.Writer) *cobra.Command {
+func NewCmdReset(in io.Reader, out io.Writer, errOut io.Writer) *cobra.Command {
 	opts := &resetOpts{}
 	cmd := &cobra.Command{
 		Use:   "reset",
@@ -40,24 +43,31 @@ func NewCmdReset(in io.Reader, out io.Writer) *cobra.Command {
 					os.Exit(0)
 				}
 			}
-			return doReset(out, opts)
+			return withClusterLock(out, opts.generatedAssetsDir, cmd.CommandPath(), opts.forceUnlock, func() error {
+				return doReset(out, errOut, opts)
+			})
 		},
 	}
 
 	cmd.Flags().StringSliceVar(&opts.limit, "limit", []string{}, "comma-separated list of hostnames to limit the execution to a subset of nodes")
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &opts.runsDir)
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&opts.force, "force", false, `do not prompt`)
 	cmd.Flags().BoolVar(&opts.removeAssets, "remove-assets", false, "remove generated-assets-dir")
+	addForceUnlockFlag(cmd.Flags(), &opts.forceUnlock)
 
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &opts.planOverlays)
 
 	return cmd
 }
 
-func doReset(out io.Writer, opts *resetOpts) error {
-	planner := &install.FilePlanner{File: opts.planFilename}
+func doReset(out io.Writer, errOut io.Writer, opts *resetOpts) error {
+	stdout := out
+	out = humanOutput(out, errOut, opts.outputFormat)
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.planOverlays}
 	if !planner.PlanExists() {
 		return planFileNotFoundErr{filename: opts.planFilename}
 	}
@@ -65,12 +75,16 @@ func doReset(out io.Writer, opts *resetOpts) error {
 	if err != nil {
 		return fmt.Errorf("failed to read plan file: %v", err)
 	}
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	executorOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
 		OutputFormat:             opts.outputFormat,
 		Verbose:                  opts.verbose,
+		Context:                  ctx,
 	}
-	executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+	executor, err := install.NewExecutor(stdout, errOut, executorOpts)
 	if err != nil {
 		return err
 	}
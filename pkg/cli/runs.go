@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdRuns creates a new command for inspecting the runs of the cluster
+func NewCmdRuns(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "inspect the history of the commands that were run against your cluster",
+		Long: `Inspect the history of the commands that were run against your cluster.
+
+Every command that runs ansible, such as 'kismatic install apply', records the
+plan file, the inventory, the cluster catalog, the ansible logs and events, and
+the status of the run in the runs directory.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	cmd.AddCommand(NewCmdRunsList(out))
+	cmd.AddCommand(NewCmdRunsShow(out))
+	cmd.AddCommand(NewCmdRunsPrune(out))
+	return cmd
+}
+
+// formatRunDuration returns the duration rounded to the second, or a dash
+// when the duration is not known
+func formatRunDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
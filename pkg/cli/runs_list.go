@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdRunsList creates a new command for listing the runs
+func NewCmdRunsList(out io.Writer) *cobra.Command {
+	var runsDir string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the runs, most recent first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doRunsList(out, runsDir)
+		},
+	}
+	addRunsDirFlag(cmd.Flags(), &runsDir)
+	return cmd
+}
+
+func doRunsList(out io.Writer, runsDir string) error {
+	runs, err := install.ListRuns(runsDir)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Fprintf(out, "No runs were found in %q\n", runsDir)
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTASK\tSTARTED\tDURATION\tSTATUS\tFAILED HOSTS\tFAILED TASK")
+	for _, r := range runs {
+		failedHosts, failedTask := "", ""
+		c, err := r.Checkpoints()
+		if err != nil {
+			return err
+		}
+		if c != nil {
+			if f := c.Failure(); f != nil {
+				failedHosts = strings.Join(f.FailedHosts(), ",")
+				failedTask = f.FailedTask
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID(), r.Task, r.Start.Format("2006-01-02 15:04:05"), formatRunDuration(r.Duration()), r.Status, failedHosts, failedTask)
+	}
+	return w.Flush()
+}
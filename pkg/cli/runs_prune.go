@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type runsPruneOpts struct {
+	runsDir string
+	keep    int
+}
+
+// NewCmdRunsPrune creates a new command for removing old runs
+func NewCmdRunsPrune(out io.Writer) *cobra.Command {
+	opts := runsPruneOpts{}
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "remove all but the most recent runs of each task",
+		Long: `Remove all but the most recent runs of each task.
+
+The last successful run of each task is always kept, as 'kismatic install diff'
+compares the plan file against it. Runs that are still running are kept as well,
+unless they were abandoned: they started more than 24 hours ago, or the command
+that started them is not running anymore on this machine.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doRunsPrune(out, opts)
+		},
+	}
+	addRunsDirFlag(cmd.Flags(), &opts.runsDir)
+	cmd.Flags().IntVar(&opts.keep, "keep", 10, "number of runs of each task to keep")
+	return cmd
+}
+
+func doRunsPrune(out io.Writer, opts runsPruneOpts) error {
+	pruned, err := install.PruneRuns(opts.runsDir, opts.keep)
+	for _, r := range pruned {
+		fmt.Fprintf(out, "Removed run %s\n", r.ID())
+	}
+	if err != nil {
+		return err
+	}
+	util.PrettyPrintOk(out, "Removed %d runs from %q", len(pruned), opts.runsDir)
+	return nil
+}
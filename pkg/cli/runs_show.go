@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdRunsShow creates a new command for showing the summary of a run
+func NewCmdRunsShow(out io.Writer) *cobra.Command {
+	var runsDir string
+	cmd := &cobra.Command{
+		Use:   "show ID",
+		Short: "show the summary of a run, rebuilt from the events it recorded",
+		Long: `Show the summary of a run, rebuilt from the ansible events it recorded.
+
+The ID of a run is formatted as task/start-time, as printed by 'kismatic runs list'.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doRunsShow(out, runsDir, args[0])
+		},
+	}
+	addRunsDirFlag(cmd.Flags(), &runsDir)
+	return cmd
+}
+
+func doRunsShow(out io.Writer, runsDir string, id string) error {
+	r, err := install.GetRun(runsDir, id)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Run:\t%s\n", r.ID())
+	fmt.Fprintf(w, "Directory:\t%s\n", r.Directory)
+	fmt.Fprintf(w, "Playbook:\t%s\n", r.Playbook)
+	if len(r.Limit) > 0 {
+		fmt.Fprintf(w, "Limit:\t%s\n", strings.Join(r.Limit, ","))
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", r.Start.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Duration:\t%s\n", formatRunDuration(r.Duration()))
+	fmt.Fprintf(w, "Status:\t%s\n", r.Status)
+	if r.Retries > 0 {
+		fmt.Fprintf(w, "Retries:\t%d\n", r.Retries)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", r.Error)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	s, err := r.Summary()
+	if err != nil {
+		return fmt.Errorf("error reading the events of run %q: %v", id, err)
+	}
+	if s == nil {
+		fmt.Fprintf(out, "\nNo events were recorded by this run. The ansible logs are in %q\n", r.Directory)
+		return nil
+	}
+	tasks := 0
+	for _, p := range s.Plays {
+		tasks += p.Tasks
+	}
+	fmt.Fprintf(out, "\n%d plays, %d tasks\n", len(s.Plays), tasks)
+
+	if len(s.Hosts) > 0 {
+		fmt.Fprintln(out)
+		hosts := []string{}
+		for h := range s.Hosts {
+			hosts = append(hosts, h)
+		}
+		sort.Strings(hosts)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "HOST\tOK\tSKIPPED\tFAILED\tUNREACHABLE")
+		for _, h := range hosts {
+			hs := s.Hosts[h]
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", h, hs.OK, hs.Skipped, hs.Failed, hs.Unreachable)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Failures) > 0 {
+		fmt.Fprintln(out, "\nFailures:")
+		for _, f := range s.Failures {
+			kind := "failed"
+			if f.Unreachable {
+				kind = "unreachable"
+			}
+			fmt.Fprintf(out, "- %s: %s %s (play %q)\n", f.Host, f.Task, kind, f.Play)
+			if f.Message != "" {
+				fmt.Fprintf(out, "  %s\n", f.Message)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunsCmds(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-runs-cmd")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+
+	runs := map[string]string{
+		"apply/2018-01-01-10-00-00": "task: apply\nplaybook: kubernetes.yaml\nstatus: succeeded\nstart: 2018-01-01T10:00:00Z\nend: 2018-01-01T10:12:30Z\n",
+		"apply/2018-01-02-10-00-00": "task: apply\nplaybook: kubernetes.yaml\nstatus: failed\nstart: 2018-01-02T10:00:00Z\nend: 2018-01-02T10:01:00Z\nerror: exit status 2\n",
+		"apply/2018-01-03-10-00-00": "task: apply\nplaybook: kubernetes.yaml\nstatus: failed\n",
+	}
+	for id, record := range runs {
+		dir := filepath.Join(runsDir, id)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "run.yaml"), []byte(record), 0644); err != nil {
+			t.Fatalf("error writing run record: %v", err)
+		}
+	}
+	failed := filepath.Join(runsDir, "apply", "2018-01-02-10-00-00")
+	checkpoints := "plan_hash: abc\nplays:\n- file: _etcd-k8s.yaml\n  name: Start etcd\n  status: failed\n  hosts:\n    etcd01: failed\n  failed_task: start etcd\n"
+	if err := ioutil.WriteFile(filepath.Join(failed, "checkpoints.yaml"), []byte(checkpoints), 0644); err != nil {
+		t.Fatalf("error writing checkpoints: %v", err)
+	}
+	events := `{"eventType":"PLAY_START","eventData":{"name":"Start etcd","file":"_etcd-k8s.yaml"}}
+{"eventType":"TASK_START","eventData":{"name":"start etcd"}}
+{"eventType":"RUNNER_FAILED","eventData":{"host":"etcd01","result":{"msg":"etcd did not start"},"ignoreErrors":false}}
+`
+	if err := ioutil.WriteFile(filepath.Join(failed, "events.jsonl"), []byte(events), 0644); err != nil {
+		t.Fatalf("error writing events: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := doRunsList(out, runsDir); err != nil {
+		t.Fatalf("unexpected error listing runs: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 runs, got:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[1], "apply/2018-01-03-10-00-00") {
+		t.Errorf("expected the most recent run first, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[2], "1m0s") || !strings.Contains(lines[2], "etcd01") || !strings.Contains(lines[2], "start etcd") {
+		t.Errorf("expected the duration and failure of the failed run, got:\n%s", lines[2])
+	}
+	if !strings.Contains(lines[3], "12m30s") || !strings.Contains(lines[3], "succeeded") {
+		t.Errorf("expected the duration and status of the successful run, got:\n%s", lines[3])
+	}
+
+	out = &bytes.Buffer{}
+	if err := doRunsShow(out, runsDir, "apply/2018-01-02-10-00-00"); err != nil {
+		t.Fatalf("unexpected error showing run: %v", err)
+	}
+	for _, s := range []string{"exit status 2", "1 plays, 1 tasks", "etcd01: start etcd failed", "etcd did not start"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q in the summary, got:\n%s", s, out.String())
+		}
+	}
+
+	out = &bytes.Buffer{}
+	if err := doRunsPrune(out, runsPruneOpts{runsDir: runsDir, keep: 1}); err != nil {
+		t.Fatalf("unexpected error pruning runs: %v", err)
+	}
+	if !strings.Contains(out.String(), "Removed run apply/2018-01-02-10-00-00") {
+		t.Errorf("expected the failed run to be removed, got:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(runsDir, "apply", "2018-01-01-10-00-00")); err != nil {
+		t.Errorf("expected the last successful run to be kept: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/ssh"
 
@@ -14,8 +15,10 @@ import (
 
 type sshOpts struct {
 	planFilename string
+	planOverlays []string
 	host         string
 	pty          bool
+	timeout      time.Duration
 	arguments    []string
 }
 
@@ -42,7 +45,7 @@ HOST must be one of the following:
 
 			opts.host = args[0]
 
-			planner := &install.FilePlanner{File: opts.planFilename}
+			planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.planOverlays}
 			// Check if plan file exists
 			if !planner.PlanExists() {
 				return planFileNotFoundErr{filename: opts.planFilename}
@@ -58,7 +61,9 @@ HOST must be one of the following:
 	}
 
 	cmd.Flags().StringVarP(&opts.planFilename, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
+	addPlanOverlayFlag(cmd.Flags(), &opts.planOverlays)
 	cmd.Flags().BoolVarP(&opts.pty, "pty", "t", false, "force PTY \"-t\" flag on the SSH connection")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 0, "how long the command can run before it is aborted. Zero means no timeout. Interactive shells never time out")
 
 	return cmd
 }
@@ -82,10 +87,11 @@ func doSSH(out io.Writer, planner install.Planner, opts *sshOpts) error {
 		return fmt.Errorf("cannot validate SSH connection to node %q", opts.host)
 	}
 
-	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key)
+	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key, con.SSHConfig.BastionHost())
 	if err != nil {
 		return fmt.Errorf("error creating SSH client: %v", err)
 	}
+	client.CommandTimeout = opts.timeout
 
 	if err = client.Shell(opts.pty, opts.arguments...); err != nil {
 		return fmt.Errorf("error running command: %v", err)
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+type sshKeysOpts struct {
+	planFilename       string
+	planOverlays       []string
+	generatedAssetsDir string
+}
+
+// NewCmdSSHKeys creates a new command for managing the pinned host keys of the nodes
+func NewCmdSSHKeys(out io.Writer) *cobra.Command {
+	opts := &sshKeysOpts{}
+	cmd := &cobra.Command{
+		Use:   "ssh-keys",
+		Short: "manage the host keys of the nodes that kismatic trusts",
+		Long: `Manage the host keys of the nodes that kismatic trusts.
+
+The host key of a node is pinned in the known_hosts file of the generated assets
+directory the first time kismatic connects to the node. Connections to the node,
+including the ones made by ansible, are refused if its host key changes.
+
+HOST must be one of the following:
+- A hostname defined in the plan file
+- An IP address of a node defined in the plan file
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFilename)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &opts.planOverlays)
+	cmd.PersistentFlags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+
+	cmd.AddCommand(NewCmdSSHKeysScan(out, opts))
+	cmd.AddCommand(NewCmdSSHKeysTrust(out, opts))
+	cmd.AddCommand(NewCmdSSHKeysForget(out, opts))
+	return cmd
+}
+
+// sshKeysNode is a node of the plan, the address of its SSH server, and the
+// bastion host it is reached through
+type sshKeysNode struct {
+	host    string
+	address string
+	bastion *ssh.Bastion
+}
+
+// sshKeysNodes returns the nodes of the plan with the given hostnames or IPs,
+// or all the nodes of the plan when no host is given
+func sshKeysNodes(opts *sshKeysOpts, hosts []string) ([]sshKeysNode, error) {
+	planner := &install.FilePlanner{File: opts.planFilename, Overlays: opts.planOverlays}
+	if !planner.PlanExists() {
+		return nil, planFileNotFoundErr{filename: opts.planFilename}
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan file: %v", err)
+	}
+	if len(hosts) == 0 {
+		for _, n := range plan.GetUniqueNodes() {
+			hosts = append(hosts, n.Host)
+		}
+	}
+	nodes := []sshKeysNode{}
+	for _, h := range hosts {
+		con, err := plan.GetSSHConnection(h)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, sshKeysNode{
+			host:    con.Node.Host,
+			address: net.JoinHostPort(con.Node.IP, strconv.Itoa(con.SSHConfig.Port)),
+			bastion: con.SSHConfig.BastionHost(),
+		})
+	}
+	return nodes, nil
+}
+
+func sshKnownHosts(opts *sshKeysOpts) (*ssh.KnownHosts, error) {
+	file, err := filepath.Abs(install.KnownHostsFile(opts.generatedAssetsDir))
+	if err != nil {
+		return nil, fmt.Errorf("error getting absolute path of the known hosts file: %v", err)
+	}
+	return &ssh.KnownHosts{File: file}, nil
+}
+
+// useKnownHostsFile verifies the host keys of the nodes that the command
+// connects to against the known_hosts file of the generated assets directory.
+// Commands without a generated assets directory use the one next to the plan
+// file.
+func useKnownHostsFile(cmd *cobra.Command) error {
+	var dir string
+	if f := cmd.Flags().Lookup("generated-assets-dir"); f != nil {
+		dir = f.Value.String()
+	} else if f := cmd.Flags().Lookup("plan-file"); f != nil {
+		dir = filepath.Join(filepath.Dir(f.Value.String()), "generated")
+	} else {
+		return nil
+	}
+	return ssh.SetKnownHostsFile(install.KnownHostsFile(dir))
+}
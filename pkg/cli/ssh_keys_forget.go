@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSSHKeysForget creates a new command for removing the pinned host keys of nodes
+func NewCmdSSHKeysForget(out io.Writer, opts *sshKeysOpts) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "forget HOST...",
+		Short: "remove the pinned host keys of the nodes",
+		Long: `Remove the pinned host keys of the nodes. The host key of a node is pinned
+again the next time kismatic connects to it.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Usage()
+			}
+			return doSSHKeysForget(out, opts, args)
+		},
+	}
+	return cmd
+}
+
+func doSSHKeysForget(out io.Writer, opts *sshKeysOpts, hosts []string) error {
+	nodes, err := sshKeysNodes(opts, hosts)
+	if err != nil {
+		return err
+	}
+	knownHosts, err := sshKnownHosts(opts)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		removed, err := knownHosts.Forget(n.address)
+		if err != nil {
+			return err
+		}
+		if removed {
+			fmt.Fprintf(out, "Removed host key of %s (%s)\n", n.host, n.address)
+		} else {
+			fmt.Fprintf(out, "No host key pinned for %s (%s)\n", n.host, n.address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSSHKeysScan creates a new command for pinning the host keys of the nodes
+func NewCmdSSHKeysScan(out io.Writer, opts *sshKeysOpts) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan [HOST...]",
+		Short: "pin the host keys of the nodes that are not known, and report the keys that changed",
+		Long: `Pin the host keys of the nodes that are not known, and report the nodes with a
+host key that does not match the pinned key. All the nodes of the plan file are
+scanned when no host is given.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doSSHKeysScan(out, opts, args)
+		},
+	}
+	return cmd
+}
+
+func doSSHKeysScan(out io.Writer, opts *sshKeysOpts, hosts []string) error {
+	nodes, err := sshKeysNodes(opts, hosts)
+	if err != nil {
+		return err
+	}
+	knownHosts, err := sshKnownHosts(opts)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tADDRESS\tSTATUS\tFINGERPRINT")
+	changed := 0
+	for _, n := range nodes {
+		status, key, err := knownHosts.Pin(n.address, n.bastion)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\terror: %v\t-\n", n.host, n.address, err)
+			continue
+		}
+		switch status {
+		case ssh.HostKeyUnknown:
+			fmt.Fprintf(w, "%s\t%s\tadded\t%s\n", n.host, n.address, ssh.Fingerprint(key))
+		case ssh.HostKeyChanged:
+			changed++
+			fmt.Fprintf(w, "%s\t%s\tCHANGED\t%s\n", n.host, n.address, ssh.Fingerprint(key))
+		default:
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", n.host, n.address, status, ssh.Fingerprint(key))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if changed > 0 {
+		return fmt.Errorf("the host key of %d node(s) does not match the pinned key. Verify the new keys, and run 'kismatic ssh-keys trust' for the nodes", changed)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSSHKeysTrust creates a new command for replacing the pinned host keys of nodes
+func NewCmdSSHKeysTrust(out io.Writer, opts *sshKeysOpts) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trust HOST...",
+		Short: "pin the current host keys of the nodes, replacing the keys that were pinned",
+		Long: `Pin the current host keys of the nodes, replacing the keys that were pinned.
+
+Use this command after the host key of a node was changed, for example when the
+node was reinstalled. Verify the fingerprints that are printed before running
+kismatic against the nodes.
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Usage()
+			}
+			return doSSHKeysTrust(out, opts, args)
+		},
+	}
+	return cmd
+}
+
+func doSSHKeysTrust(out io.Writer, opts *sshKeysOpts, hosts []string) error {
+	nodes, err := sshKeysNodes(opts, hosts)
+	if err != nil {
+		return err
+	}
+	knownHosts, err := sshKnownHosts(opts)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		key, err := ssh.ScanHostKey(n.address, n.bastion)
+		if err != nil {
+			return err
+		}
+		if err := knownHosts.Trust(n.address, key); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Trusted host key of %s (%s): %s\n", n.host, n.address, ssh.Fingerprint(key))
+	}
+	return nil
+}
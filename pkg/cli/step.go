@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -12,6 +11,7 @@ import (
 
 type stepCmd struct {
 	out      io.Writer
+	errOut   io.Writer
 	planFile string
 	task     string
 	planner  install.Planner
@@ -19,16 +19,20 @@ type stepCmd struct {
 
 	// Flags
 	generatedAssetsDir string
+	runsDir            string
 	restartServices    bool
 	verbose            bool
 	outputFormat       string
+	retry              install.RetryPolicy
+	forceUnlock        bool
 	limit              []string
 }
 
 // NewCmdStep returns the step command
-func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
+func NewCmdStep(out io.Writer, errOut io.Writer, opts *installOpts) *cobra.Command {
 	stepCmd := &stepCmd{
 		out:      out,
+		errOut:   errOut,
 		planFile: opts.planFilename,
 	}
 	cmd := &cobra.Command{
@@ -38,27 +42,35 @@ func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
 			if len(args) != 1 {
 				return cmd.Usage()
 			}
+			ctx, stop := cancelOnInterrupt(errOut)
+			defer stop()
 			execOpts := install.ExecutorOptions{
 				GeneratedAssetsDirectory: stepCmd.generatedAssetsDir,
+				RunsDirectory:            stepCmd.runsDir,
 				OutputFormat:             stepCmd.outputFormat,
 				Verbose:                  stepCmd.verbose,
+				Retry:                    stepCmd.retry,
+				Context:                  ctx,
 			}
-			executor, err := install.NewExecutor(out, os.Stderr, execOpts)
+			executor, err := install.NewExecutor(out, errOut, execOpts)
 			if err != nil {
 				return err
 			}
 			stepCmd.task = args[0]
 			stepCmd.planFile = opts.planFilename
-			stepCmd.planner = &install.FilePlanner{File: stepCmd.planFile}
+			stepCmd.planner = opts.planner()
 			stepCmd.executor = executor
-			return stepCmd.run()
+			return withClusterLock(out, stepCmd.generatedAssetsDir, cmd.CommandPath(), stepCmd.forceUnlock, stepCmd.run)
 		},
 	}
 	cmd.Flags().StringSliceVar(&stepCmd.limit, "limit", []string{}, "comma-separated list of hostnames to limit the execution to a subset of nodes")
 	cmd.Flags().StringVar(&stepCmd.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &stepCmd.runsDir)
 	cmd.Flags().BoolVar(&stepCmd.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.Flags().BoolVar(&stepCmd.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&stepCmd.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&stepCmd.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
+	addRetryFlags(cmd.Flags(), &stepCmd.retry)
+	addForceUnlockFlag(cmd.Flags(), &stepCmd.forceUnlock)
 	return cmd
 }
 
@@ -69,19 +81,21 @@ func (c stepCmd) run() error {
 		outputFormat:       c.outputFormat,
 		skipPreFlight:      true,
 		generatedAssetsDir: c.generatedAssetsDir,
+		runsDir:            c.runsDir,
 		limit:              c.limit,
 	}
-	if err := doValidate(c.out, c.planner, valOpts); err != nil {
+	if err := doValidate(c.out, c.errOut, c.planner, valOpts); err != nil {
 		return err
 	}
 	plan, err := c.planner.Read()
 	if err != nil {
 		return fmt.Errorf("error reading plan file: %v", err)
 	}
-	util.PrintHeader(c.out, "Running Task", '=')
+	out := humanOutput(c.out, c.errOut, c.outputFormat)
+	util.PrintHeader(out, "Running Task", '=')
 	if err := c.executor.RunPlay(c.task, plan, c.restartServices, c.limit...); err != nil {
 		return err
 	}
-	util.PrintColor(c.out, util.Green, "\nTask completed successfully\n\n")
+	util.PrintColor(out, util.Green, "\nTask completed successfully\n\n")
 	return nil
 }
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
 	"github.com/apprenda/kismatic/pkg/data"
@@ -15,20 +14,28 @@ import (
 
 type upgradeOpts struct {
 	generatedAssetsDir string
+	runsDir            string
 	verbose            bool
 	outputFormat       string
 	skipPreflight      bool
 	ignoreSafetyChecks bool
 	online             bool
 	planFile           string
+	planOverlays       []string
 	restartServices    bool
 	partialAllowed     bool
 	maxParallelWorkers int
 	dryRun             bool
+	dryRunDir          string
+	eventWebhook       string
+	webhookAnsible     bool
+	profile            bool
+	retry              install.RetryPolicy
+	forceUnlock        bool
 }
 
 // NewCmdUpgrade returns the upgrade command
-func NewCmdUpgrade(in io.Reader, out io.Writer) *cobra.Command {
+func NewCmdUpgrade(in io.Reader, out io.Writer, errOut io.Writer) *cobra.Command {
 	var opts upgradeOpts
 	cmd := &cobra.Command{
 		Use:   "upgrade",
@@ -51,22 +58,29 @@ Nodes in the cluster are upgraded in the following order:
 	}
 
 	cmd.PersistentFlags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.PersistentFlags(), &opts.runsDir)
 	cmd.PersistentFlags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.PersistentFlags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.PersistentFlags().BoolVar(&opts.skipPreflight, "skip-preflight", false, "skip upgrade pre-flight checks")
 	cmd.PersistentFlags().BoolVar(&opts.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.PersistentFlags().BoolVar(&opts.partialAllowed, "partial-ok", false, "allow the upgrade of ready nodes, and skip nodes that have been deemed unready for upgrade")
-	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "simulate the upgrade, but don't actually upgrade the cluster")
+	cmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "simulate the upgrade, but don't actually upgrade the cluster. The inventory, cluster catalog, playbook and hosts of each ansible run are rendered in the dry-run directory instead")
+	cmd.PersistentFlags().StringVar(&opts.dryRunDir, "dry-run-dir", install.DefaultDryRunDirectory, "path to the directory where the ansible runs of a dry-run are rendered")
+	addEventWebhookFlags(cmd.PersistentFlags(), &opts.eventWebhook, &opts.webhookAnsible)
+	addProfileFlag(cmd.PersistentFlags(), &opts.profile)
+	addRetryFlags(cmd.PersistentFlags(), &opts.retry)
+	addForceUnlockFlag(cmd.PersistentFlags(), &opts.forceUnlock)
 	addPlanFileFlag(cmd.PersistentFlags(), &opts.planFile)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &opts.planOverlays)
 
 	// Subcommands
-	cmd.AddCommand(NewCmdUpgradeOffline(in, out, &opts))
-	cmd.AddCommand(NewCmdUpgradeOnline(in, out, &opts))
+	cmd.AddCommand(NewCmdUpgradeOffline(in, out, errOut, &opts))
+	cmd.AddCommand(NewCmdUpgradeOnline(in, out, errOut, &opts))
 	return cmd
 }
 
 // NewCmdUpgradeOffline returns the command for running offline upgrades
-func NewCmdUpgradeOffline(in io.Reader, out io.Writer, opts *upgradeOpts) *cobra.Command {
+func NewCmdUpgradeOffline(in io.Reader, out io.Writer, errOut io.Writer, opts *upgradeOpts) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "offline",
 		Short: "Perform an offline upgrade of your Kubernetes cluster",
@@ -81,7 +95,9 @@ availability. For this reason, this method should not be used for clusters that
 production workloads.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doUpgrade(in, out, opts)
+			return withClusterLock(out, opts.generatedAssetsDir, cmd.CommandPath(), opts.forceUnlock, func() error {
+				return doUpgrade(in, out, errOut, opts)
+			})
 		},
 	}
 	cmd.Flags().IntVar(&opts.maxParallelWorkers, "max-parallel-workers", 1, "the maximum number of worker nodes to be upgraded in parallel")
@@ -89,7 +105,7 @@ production workloads.
 }
 
 // NewCmdUpgradeOnline returns the command for running online upgrades
-func NewCmdUpgradeOnline(in io.Reader, out io.Writer, opts *upgradeOpts) *cobra.Command {
+func NewCmdUpgradeOnline(in io.Reader, out io.Writer, errOut io.Writer, opts *upgradeOpts) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "online",
 		Short: "Perform an online upgrade of your Kubernetes cluster",
@@ -104,33 +120,57 @@ before any changes are applied.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.online = true
-			return doUpgrade(in, out, opts)
+			return withClusterLock(out, opts.generatedAssetsDir, cmd.CommandPath(), opts.forceUnlock, func() error {
+				return doUpgrade(in, out, errOut, opts)
+			})
 		},
 	}
 	cmd.PersistentFlags().BoolVar(&opts.ignoreSafetyChecks, "ignore-safety-checks", false, "ignore upgrade safety checks and continue with the upgrade")
 	return &cmd
 }
 
-func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
+func doUpgrade(in io.Reader, out io.Writer, errOut io.Writer, opts *upgradeOpts) error {
 	if opts.maxParallelWorkers < 1 {
 		return fmt.Errorf("max-parallel-workers must be greater or equal to 1, got: %d", opts.maxParallelWorkers)
 	}
 
+	stdout := out
+	out = humanOutput(out, errOut, opts.outputFormat)
+	phases := newPhaseMarker(stdout, opts.outputFormat)
+	sink, err := newEventSink(opts.eventWebhook, opts.webhookAnsible)
+	if err != nil {
+		return err
+	}
+	defer closeEventSink(out, sink)
 	planFile := opts.planFile
-	planner := install.FilePlanner{File: planFile}
+	planner := install.FilePlanner{File: planFile, Overlays: opts.planOverlays}
+	var dryRunDir string
+	if opts.dryRun {
+		if dryRunDir, err = install.NewDryRunDirectory(opts.dryRunDir); err != nil {
+			return err
+		}
+	}
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	executorOpts := install.ExecutorOptions{
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
 		OutputFormat:             opts.outputFormat,
 		Verbose:                  opts.verbose,
 		DryRun:                   opts.dryRun,
+		DryRunDirectory:          dryRunDir,
+		EventSink:                sink,
+		Profile:                  opts.profile,
+		Retry:                    opts.retry,
+		Context:                  ctx,
 	}
-	executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+	executor, err := install.NewExecutor(stdout, errOut, executorOpts)
 	if err != nil {
 		return err
 	}
 	preflightExecOpts := executorOpts
 	preflightExecOpts.DryRun = false // We always want to run preflight, even if doing a dry-run
-	preflightExec, err := install.NewPreFlightExecutor(out, os.Stderr, preflightExecOpts)
+	preflightExec, err := install.NewPreFlightExecutor(stdout, errOut, preflightExecOpts)
 	if err != nil {
 		return err
 	}
@@ -149,11 +189,13 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 	}
 
 	// Validate the plan file before we do anything
-	if err = validatePlan(out, plan); err != nil {
-		return err
-	}
-
-	if err = validateSSHConnectivity(out, plan); err != nil {
+	err = phases.run(install.PhaseValidation, func() error {
+		if err := validatePlan(out, plan); err != nil {
+			return err
+		}
+		return validateSSHConnectivity(out, plan)
+	})
+	if err != nil {
 		return err
 	}
 
@@ -163,7 +205,11 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 	}
 
 	util.PrintHeader(out, "Generating Kubeconfig File", '=')
-	isDiff, err := install.RegenerateKubeconfig(plan, opts.generatedAssetsDir)
+	var isDiff bool
+	err = phases.run(install.PhaseKubeconfig, func() (err error) {
+		isDiff, err = install.RegenerateKubeconfig(plan, opts.generatedAssetsDir)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("error generating kubeconfig file: %v", err)
 	}
@@ -206,7 +252,7 @@ func doUpgrade(in io.Reader, out io.Writer, opts *upgradeOpts) error {
 	if len(toUpgrade) == 0 {
 		fmt.Fprintln(out, "All nodes are at the target version. Skipping node upgrades.")
 	} else {
-		if err = upgradeNodes(in, out, *plan, *opts, toUpgrade, executor, preflightExec); err != nil {
+		if err = upgradeNodes(in, out, *plan, *opts, toUpgrade, executor, preflightExec, sink); err != nil {
 			return err
 		}
 	}
@@ -221,6 +267,9 @@ when performing a full upgrade. When you are ready, you may use "kismatic upgrad
 without the "--partial-ok" flag to perform a full upgrade.
 
 `)
+		if opts.dryRun {
+			return install.PrintDryRunSummary(out, dryRunDir)
+		}
 		return nil
 	}
 
@@ -236,15 +285,17 @@ without the "--partial-ok" flag to perform a full upgrade.
 		}
 	}
 
-	if !opts.dryRun {
-		fmt.Fprintln(out)
-		util.PrintColor(out, util.Green, "The cluster was upgraded successfully!\n")
+	if opts.dryRun {
 		fmt.Fprintln(out)
+		return install.PrintDryRunSummary(out, dryRunDir)
 	}
+	fmt.Fprintln(out)
+	util.PrintColor(out, util.Green, "The cluster was upgraded successfully!\n")
+	fmt.Fprintln(out)
 	return nil
 }
 
-func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOpts, nodesNeedUpgrade []install.ListableNode, executor install.Executor, preflightExec install.PreFlightExecutor) error {
+func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOpts, nodesNeedUpgrade []install.ListableNode, executor install.Executor, preflightExec install.PreFlightExecutor, sink install.EventSink) error {
 	// Run safety checks if doing an online upgrade
 	unsafeNodes := []install.ListableNode{}
 	if opts.online {
@@ -265,8 +316,13 @@ func upgradeNodes(in io.Reader, out io.Writer, plan install.Plan, opts upgradeOp
 					util.PrintError(out)
 				}
 				fmt.Fprintln(out)
+				e := install.SinkEvent{Type: install.EventSafetyCheckFailed, Cluster: plan.Cluster.Name, Node: node.Node.Host, Roles: node.Roles}
 				for _, err := range errs {
-					fmt.Println("-", err.Error())
+					fmt.Fprintln(out, "-", err.Error())
+					e.Errors = append(e.Errors, err.Error())
+				}
+				if sink != nil && !opts.dryRun {
+					sink.Send(e)
 				}
 				unsafeNodes = append(unsafeNodes, node)
 			} else {
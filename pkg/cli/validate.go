@@ -5,7 +5,6 @@ import (
 	"io"
 	"path/filepath"
 
-	"os"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -14,6 +13,7 @@ import (
 
 type validateOpts struct {
 	generatedAssetsDir string
+	runsDir            string
 	planFile           string
 	verbose            bool
 	outputFormat       string
@@ -22,7 +22,7 @@ type validateOpts struct {
 }
 
 // NewCmdValidate creates a new install validate command
-func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
+func NewCmdValidate(out io.Writer, errOut io.Writer, installOpts *installOpts) *cobra.Command {
 	opts := &validateOpts{}
 	cmd := &cobra.Command{
 		Use:   "validate",
@@ -31,20 +31,30 @@ func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
 			}
-			planner := &install.FilePlanner{File: installOpts.planFilename}
+			planner := installOpts.planner()
 			opts.planFile = installOpts.planFilename
-			return doValidate(out, planner, opts)
+			return doValidate(out, errOut, planner, opts)
 		},
 	}
 	cmd.Flags().StringSliceVar(&opts.limit, "limit", []string{}, "comma-separated list of hostnames to limit the execution to a subset of nodes")
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &opts.runsDir)
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw)")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw|json)")
 	cmd.Flags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
 	return cmd
 }
 
-func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) error {
+func doValidate(out io.Writer, errOut io.Writer, planner install.Planner, opts *validateOpts) error {
+	phases := newPhaseMarker(out, opts.outputFormat)
+	return phases.run(install.PhaseValidation, func() error {
+		return validate(out, humanOutput(out, errOut, opts.outputFormat), errOut, planner, opts)
+	})
+}
+
+// validate writes the human readable output to out, and the output of the
+// pre-flight executor to stdout and errOut
+func validate(stdout io.Writer, out io.Writer, errOut io.Writer, planner install.Planner, opts *validateOpts) error {
 	util.PrintHeader(out, "Validating", '=')
 	// Check if plan file exists
 	if !planner.PlanExists() {
@@ -91,11 +101,15 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 		return nil
 	}
 	// Run pre-flight
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	options := install.ExecutorOptions{
-		OutputFormat: opts.outputFormat,
-		Verbose:      opts.verbose,
+		OutputFormat:  opts.outputFormat,
+		Verbose:       opts.verbose,
+		RunsDirectory: opts.runsDir,
+		Context:       ctx,
 	}
-	e, err := install.NewPreFlightExecutor(out, os.Stderr, options)
+	e, err := install.NewPreFlightExecutor(stdout, errOut, options)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/apprenda/kismatic/pkg/install"
@@ -17,7 +18,7 @@ func TestValidateCmdPlanNotFound(t *testing.T) {
 		verbose:      false,
 		outputFormat: "table",
 	}
-	err := doValidate(out, fp, opts)
+	err := doValidate(out, out, fp, opts)
 	if err == nil {
 		t.Errorf("validate did not return an error when the plan does not exist")
 	}
@@ -38,7 +39,7 @@ func TestValidateCmdPlanInvalid(t *testing.T) {
 		verbose:      false,
 		outputFormat: "table",
 	}
-	err := doValidate(out, fp, opts)
+	err := doValidate(out, out, fp, opts)
 	if err == nil {
 		t.Errorf("did not return an error with an invalid plan")
 	}
@@ -47,3 +48,26 @@ func TestValidateCmdPlanInvalid(t *testing.T) {
 		t.Errorf("did not read the plan file")
 	}
 }
+
+func TestValidateCmdJSONOutputWritesHumanOutputToErrOut(t *testing.T) {
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	fp := &fakePlanner{
+		exists: true,
+		plan:   &install.Plan{},
+	}
+	opts := &validateOpts{
+		planFile:     "planFile",
+		verbose:      false,
+		outputFormat: "json",
+	}
+	if err := doValidate(out, errOut, fp, opts); err == nil {
+		t.Errorf("did not return an error with an invalid plan")
+	}
+	if errOut.Len() == 0 {
+		t.Errorf("did not write the human readable output to errOut")
+	}
+	if strings.Contains(out.String(), "Validating") {
+		t.Errorf("wrote the human readable output to out:\n%s", out.String())
+	}
+}
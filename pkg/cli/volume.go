@@ -7,8 +7,8 @@ import (
 )
 
 // NewCmdVolume returns the storage command
-func NewCmdVolume(in io.Reader, out io.Writer) *cobra.Command {
-	var planFile string
+func NewCmdVolume(in io.Reader, out io.Writer, errOut io.Writer) *cobra.Command {
+	planOpts := &installOpts{}
 	cmd := &cobra.Command{
 		Use:   "volume",
 		Short: "manage storage volumes on your Kubernetes cluster",
@@ -16,9 +16,10 @@ func NewCmdVolume(in io.Reader, out io.Writer) *cobra.Command {
 			return cmd.Usage()
 		},
 	}
-	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
-	cmd.AddCommand(NewCmdVolumeAdd(out, &planFile))
-	cmd.AddCommand(NewCmdVolumeList(out, &planFile))
-	cmd.AddCommand(NewCmdVolumeDelete(in, out, &planFile))
+	addPlanFileFlag(cmd.PersistentFlags(), &planOpts.planFilename)
+	addPlanOverlayFlag(cmd.PersistentFlags(), &planOpts.planOverlays)
+	cmd.AddCommand(NewCmdVolumeAdd(out, errOut, planOpts))
+	cmd.AddCommand(NewCmdVolumeList(out, planOpts))
+	cmd.AddCommand(NewCmdVolumeDelete(in, out, errOut, planOpts))
 	return cmd
 }
@@ -20,12 +20,14 @@ type volumeAddOptions struct {
 	verbose            bool
 	outputFormat       string
 	generatedAssetsDir string
+	runsDir            string
 	reclaimPolicy      string
 	accessModes        string
+	forceUnlock        bool
 }
 
 // NewCmdVolumeAdd returns the command for adding storage volumes
-func NewCmdVolumeAdd(out io.Writer, planFile *string) *cobra.Command {
+func NewCmdVolumeAdd(out io.Writer, errOut io.Writer, planOpts *installOpts) *cobra.Command {
 	opts := volumeAddOptions{}
 	cmd := &cobra.Command{
 		Use:   "add size_in_gigabytes [volume-name]",
@@ -34,7 +36,9 @@ func NewCmdVolumeAdd(out io.Writer, planFile *string) *cobra.Command {
 
 This function requires a target cluster that has storage nodes.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doVolumeAdd(out, opts, *planFile, args)
+			return withClusterLock(out, opts.generatedAssetsDir, cmd.CommandPath(), opts.forceUnlock, func() error {
+				return doVolumeAdd(out, errOut, opts, planOpts.planner(), args)
+			})
 		},
 		Example: `  # Create a 10GB distributed and replicated volume named "storage01"
   # with StorageClass "durable". Grant access to the volume to any client with an IP
@@ -47,14 +51,16 @@ This function requires a target cluster that has storage nodes.`,
 	cmd.Flags().StringVarP(&opts.storageClass, "storage-class", "c", "kismatic", "The StorageClass to present for claims in Kubernetes. Classes should identify properties of volumes in business terms, such as 'durable' or 'fast-reads'")
 	cmd.Flags().StringSliceVarP(&opts.allowAddress, "allow-address", "a", nil, "Comma delimited list of address wildcards permitted access to the volume in addition to Kubernetes nodes.")
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw|json)`)
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &opts.runsDir)
 	cmd.Flags().StringVar(&opts.reclaimPolicy, "reclaim-policy", "Retain", "Persistent volume reclaim policy (options Retain|Recycle|Delete)")
 	cmd.Flags().StringVar(&opts.accessModes, "access-modes", "ReadWriteMany", "Comma-separated list of access modes for the persistent volume (options ReadWriteOnce|ReadOnlyMany|ReadWriteMany)")
+	addForceUnlockFlag(cmd.Flags(), &opts.forceUnlock)
 	return cmd
 }
 
-func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []string) error {
+func doVolumeAdd(out io.Writer, errOut io.Writer, opts volumeAddOptions, planner *install.FilePlanner, args []string) error {
 	// get volume name and size from arguments
 	var volumeName string
 	var volumeSizeStrGB string
@@ -76,17 +82,22 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 	}
 
 	// setup ansible for execution
-	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	execOpts := install.ExecutorOptions{
 		OutputFormat: opts.outputFormat,
 		Verbose:      opts.verbose,
 		// Need to refactor executor code... this will do for now as we don't need the generated assets dir in this command
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
+		Context:                  ctx,
 	}
-	exec, err := install.NewExecutor(out, out, execOpts)
+	stdout := out
+	out = humanOutput(out, errOut, opts.outputFormat)
+	exec, err := install.NewExecutor(stdout, out, execOpts)
 	if err != nil {
 		return err
 	}
@@ -99,11 +110,12 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 	vopts := &validateOpts{
 		outputFormat:       opts.outputFormat,
 		verbose:            opts.verbose,
-		planFile:           planFile,
+		planFile:           planner.File,
 		skipPreFlight:      true,
 		generatedAssetsDir: opts.generatedAssetsDir,
+		runsDir:            opts.runsDir,
 	}
-	if err := doValidate(out, planner, vopts); err != nil {
+	if err := doValidate(stdout, errOut, planner, vopts); err != nil {
 		return err
 	}
 
@@ -15,11 +15,13 @@ type volumeDeleteOptions struct {
 	verbose            bool
 	outputFormat       string
 	generatedAssetsDir string
+	runsDir            string
 	force              bool
+	forceUnlock        bool
 }
 
 // NewCmdVolumeDelete returns the command for deleting storage volumes
-func NewCmdVolumeDelete(in io.Reader, out io.Writer, planFile *string) *cobra.Command {
+func NewCmdVolumeDelete(in io.Reader, out io.Writer, errOut io.Writer, planOpts *installOpts) *cobra.Command {
 	opts := volumeDeleteOptions{}
 	cmd := &cobra.Command{
 		Use:   "delete volume-name",
@@ -37,17 +39,21 @@ WARNING all data in the volume will be lost.`,
 					os.Exit(0)
 				}
 			}
-			return doVolumeDelete(out, opts, *planFile, args)
+			return withClusterLock(out, opts.generatedAssetsDir, cmd.CommandPath(), opts.forceUnlock, func() error {
+				return doVolumeDelete(out, errOut, opts, planOpts.planner(), args)
+			})
 		},
 	}
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw)`)
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options simple|raw|json)`)
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	addRunsDirFlag(cmd.Flags(), &opts.runsDir)
 	cmd.Flags().BoolVar(&opts.force, "force", false, `do not prompt`)
+	addForceUnlockFlag(cmd.Flags(), &opts.forceUnlock)
 	return cmd
 }
 
-func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, args []string) error {
+func doVolumeDelete(out io.Writer, errOut io.Writer, opts volumeDeleteOptions, planner *install.FilePlanner, args []string) error {
 	// get volume name and size from arguments
 	var volumeName string
 	switch len(args) {
@@ -58,17 +64,22 @@ func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, ar
 	}
 
 	// setup ansible for execution
-	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
+	ctx, stop := cancelOnInterrupt(errOut)
+	defer stop()
 	execOpts := install.ExecutorOptions{
 		OutputFormat: opts.outputFormat,
 		Verbose:      opts.verbose,
 		// Need to refactor executor code... this will do for now as we don't need the generated assets dir in this command
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
+		Context:                  ctx,
 	}
-	exec, err := install.NewExecutor(out, out, execOpts)
+	stdout := out
+	out = humanOutput(out, errOut, opts.outputFormat)
+	exec, err := install.NewExecutor(stdout, out, execOpts)
 	if err != nil {
 		return err
 	}
@@ -81,11 +92,12 @@ func doVolumeDelete(out io.Writer, opts volumeDeleteOptions, planFile string, ar
 	vopts := &validateOpts{
 		outputFormat:       opts.outputFormat,
 		verbose:            opts.verbose,
-		planFile:           planFile,
+		planFile:           planner.File,
 		skipPreFlight:      true,
 		generatedAssetsDir: opts.generatedAssetsDir,
+		runsDir:            opts.runsDir,
 	}
-	if err := doValidate(out, planner, vopts); err != nil {
+	if err := doValidate(stdout, errOut, planner, vopts); err != nil {
 		return err
 	}
 
@@ -17,7 +17,7 @@ type volumeListOptions struct {
 }
 
 // NewCmdVolumeList returns the command for listgin storage volumes
-func NewCmdVolumeList(out io.Writer, planFile *string) *cobra.Command {
+func NewCmdVolumeList(out io.Writer, planOpts *installOpts) *cobra.Command {
 	opts := volumeListOptions{}
 	cmd := &cobra.Command{
 		Use:   "list",
@@ -25,7 +25,7 @@ func NewCmdVolumeList(out io.Writer, planFile *string) *cobra.Command {
 		Long: `List storage volumes to the Kubernetes cluster.
 This function requires a target cluster that has storage nodes.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doVolumeList(out, opts, *planFile, args)
+			return doVolumeList(out, opts, planOpts.planner(), args)
 		},
 	}
 
@@ -33,16 +33,15 @@ This function requires a target cluster that has storage nodes.`,
 	return cmd
 }
 
-func doVolumeList(out io.Writer, opts volumeListOptions, planFile string, args []string) error {
+func doVolumeList(out io.Writer, opts volumeListOptions, planner *install.FilePlanner, args []string) error {
 	// verify command
 	if opts.outputFormat != "simple" && opts.outputFormat != "json" {
 		return fmt.Errorf("output format %q is not supported", opts.outputFormat)
 	}
 
 	// Setup ansible
-	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
-		return planFileNotFoundErr{filename: planFile}
+		return planFileNotFoundErr{filename: planner.File}
 	}
 
 	plan, err := planner.Read()
@@ -67,17 +67,26 @@ func ListVersions(plan *Plan) (ClusterVersion, error) {
 		Nodes: []ListableNode{},
 	}
 
-	sshDeets := plan.Cluster.SSH
 	ketVerFile := "/etc/kismatic-version"
 	componentVerFile := "/etc/component-versions"
-	for i, node := range nodes {
-		client, err := ssh.NewClient(node.IP, sshDeets.Port, sshDeets.User, sshDeets.Key)
+	// read the version files of all the nodes in parallel
+	commands := []ssh.Command{}
+	for _, node := range nodes {
+		sshDeets := plan.Cluster.SSH.ForNode(node)
+		client, err := ssh.NewClient(node.IP, sshDeets.Port, sshDeets.User, sshDeets.Key, sshDeets.BastionHost())
 		if err != nil {
 			return cv, fmt.Errorf("error creating SSH client: %v", err)
 		}
-
+		client.SudoPassword = sshDeets.BecomePassword
+		commands = append(commands,
+			ssh.Command{Host: node.Host, Client: client, Args: []string{"cat", ketVerFile}},
+			ssh.Command{Host: node.Host, Client: client, Args: []string{"cat", componentVerFile}},
+		)
+	}
+	results := ssh.RunParallel(commands, ssh.DefaultParallelism)
+	for i, node := range nodes {
 		// get KET version
-		ketOutput, err := client.Output(false, fmt.Sprintf("cat %s", ketVerFile))
+		ketOutput, err := results[2*i].Output, results[2*i].Err
 		if err != nil {
 			// the output var contains the actual error message from the cat command, which has
 			// more meaningful info
@@ -90,7 +99,7 @@ func ListVersions(plan *Plan) (ClusterVersion, error) {
 		}
 
 		// get component versions
-		versionsOutput, err := client.Output(false, fmt.Sprintf("cat %s", componentVerFile))
+		versionsOutput, err := results[2*i+1].Output, results[2*i+1].Err
 		// don't fail if the file is not found, will default to empty
 		// TODO remove
 		if err != nil && !strings.Contains(versionsOutput, "No such file or directory") {
@@ -1,6 +1,7 @@
 package install
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -531,20 +532,27 @@ func (f *fakePKI) GenerateCertificate(name string, validityPeriod string, common
 }
 
 type fakeRunner struct {
-	eventChan         chan ansible.Event
 	err               error
 	incomingCatalog   ansible.ClusterCatalog
 	allNodesPlaybooks []string
 }
 
-func (f *fakeRunner) StartPlaybook(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog) (<-chan ansible.Event, error) {
+func (f *fakeRunner) StartPlaybook(ctx context.Context, playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog) (<-chan ansible.Event, error) {
 	f.allNodesPlaybooks = append(f.allNodesPlaybooks, playbookFile)
-	return f.eventChan, f.err
+	return f.events(), f.err
 }
 func (f *fakeRunner) WaitPlaybook() error { return f.err }
-func (f *fakeRunner) StartPlaybookOnNode(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog, node ...string) (<-chan ansible.Event, error) {
+func (f *fakeRunner) StartPlaybookOnNode(ctx context.Context, playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog, node ...string) (<-chan ansible.Event, error) {
 	f.incomingCatalog = cc
-	return f.eventChan, f.err
+	return f.events(), f.err
+}
+
+// events returns a stream without events, which ends like the stream of a
+// playbook that exited
+func (f *fakeRunner) events() <-chan ansible.Event {
+	events := make(chan ansible.Event)
+	close(events)
+	return events
 }
 
 func fakeRunnerExplainer(execError error) func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
@@ -0,0 +1,274 @@
+package install
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const runCheckpointsFile = "checkpoints.yaml"
+
+// The possible states of a play
+const (
+	PlayStatusRunning   = "running"
+	PlayStatusSucceeded = "succeeded"
+	PlayStatusFailed    = "failed"
+)
+
+// The possible states of a host in a play
+const (
+	HostStatusOK          = "ok"
+	HostStatusFailed      = "failed"
+	HostStatusUnreachable = "unreachable"
+)
+
+// PlayCheckpoint is the completion of a play of a run
+type PlayCheckpoint struct {
+	// File is the playbook file that contains the play
+	File   string `yaml:"file"`
+	Name   string `yaml:"name"`
+	Status string `yaml:"status"`
+	// Hosts is the status of the play on each of the hosts it ran on
+	Hosts map[string]string `yaml:"hosts,omitempty"`
+	// FailedTask is the first task of the play that failed
+	FailedTask string `yaml:"failed_task,omitempty"`
+}
+
+// Checkpoints are the plays of a run, in the order they were started
+type Checkpoints struct {
+	// PlanHash is the hash of the plan that was used for the run
+	PlanHash string           `yaml:"plan_hash"`
+	Plays    []PlayCheckpoint `yaml:"plays"`
+}
+
+// planHash returns the hash of the plan, used to determine whether a run
+// was started on the same plan. The secrets are hashed as the references
+// they were resolved from, so that rotating a secret does not change the hash.
+func planHash(p *Plan) (string, error) {
+	// the become password is not part of the cluster, and can be entered
+	// again on each run
+	c := *withSecretReferences(p)
+	c.Cluster.SSH.BecomePassword = ""
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling plan: %v", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func writeCheckpoints(runDirectory string, c Checkpoints) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error marshaling checkpoints: %v", err)
+	}
+	file := filepath.Join(runDirectory, runCheckpointsFile)
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("error writing checkpoints to %q: %v", file, err)
+	}
+	return nil
+}
+
+func readCheckpoints(runDirectory string) (*Checkpoints, error) {
+	b, err := ioutil.ReadFile(filepath.Join(runDirectory, runCheckpointsFile))
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoints{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("error unmarshaling checkpoints in %q: %v", runDirectory, err)
+	}
+	return c, nil
+}
+
+// completedPlays returns the playbook files that were completed by the last
+// run of the task, in the order they were run. The last run must have been
+// started on the plan with the given hash, and must not have succeeded.
+func completedPlays(runsDirectory string, task string, hash string) ([]string, error) {
+	runs, err := listRuns(runsDirectory, task)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("there is no run of %q to resume in %q", task, runsDirectory)
+	}
+	last := runs[0]
+	if last.Status == RunStatusSucceeded {
+		return nil, fmt.Errorf("the last run of %q in %q succeeded, there is nothing to resume", task, last.Directory)
+	}
+	c, err := readCheckpoints(last.Directory)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("the last run of %q in %q did not record checkpoints, and cannot be resumed", task, last.Directory)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoints of %q: %v", last.Directory, err)
+	}
+	if c.PlanHash != hash {
+		return nil, fmt.Errorf("the plan has changed since the last run of %q in %q was started, and it cannot be resumed", task, last.Directory)
+	}
+	return c.completedFiles(), nil
+}
+
+// completedFiles returns the playbook files whose plays all succeeded, in the
+// order they were run
+func (c Checkpoints) completedFiles() []string {
+	// A file is completed when all of its plays succeeded. Plays run in
+	// order, so no play is completed after the first one that did not succeed.
+	completed := []string{}
+	for _, p := range c.Plays {
+		if p.Status != PlayStatusSucceeded {
+			if len(completed) > 0 && completed[len(completed)-1] == p.File {
+				completed = completed[:len(completed)-1]
+			}
+			break
+		}
+		if p.File == "" || (len(completed) > 0 && completed[len(completed)-1] == p.File) {
+			continue
+		}
+		completed = append(completed, p.File)
+	}
+	return completed
+}
+
+// checkpointExplainer records the completion of each play and host in the
+// run directory, before passing the events on to the explainer
+type checkpointExplainer struct {
+	explainer    explain.AnsibleEventExplainer
+	runDirectory string
+
+	mu          sync.Mutex
+	checkpoints Checkpoints
+	task        string
+}
+
+// ExplainEvent records the checkpoint and explains the event
+func (c *checkpointExplainer) ExplainEvent(e ansible.Event) {
+	c.record(e)
+	c.explainer.ExplainEvent(e)
+}
+
+func (c *checkpointExplainer) record(e ansible.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch event := e.(type) {
+	case *ansible.PlayStartEvent:
+		c.endPlay(false)
+		c.checkpoints.Plays = append(c.checkpoints.Plays, PlayCheckpoint{
+			File:   event.File,
+			Name:   event.Name,
+			Status: PlayStatusRunning,
+			Hosts:  map[string]string{},
+		})
+	case *ansible.PlaybookEndEvent:
+		c.endPlay(false)
+	case *ansible.TaskStartEvent:
+		c.task = event.Name
+		return
+	case *ansible.HandlerTaskStartEvent:
+		c.task = event.Name
+		return
+	case *ansible.RunnerOKEvent:
+		c.setHostStatus(event.Host, HostStatusOK)
+		return
+	case *ansible.RunnerSkippedEvent:
+		c.setHostStatus(event.Host, HostStatusOK)
+		return
+	case *ansible.RunnerFailedEvent:
+		if event.IgnoreErrors {
+			c.setHostStatus(event.Host, HostStatusOK)
+			return
+		}
+		c.setHostStatus(event.Host, HostStatusFailed)
+	case *ansible.RunnerUnreachableEvent:
+		c.setHostStatus(event.Host, HostStatusUnreachable)
+	default:
+		return
+	}
+	writeCheckpoints(c.runDirectory, c.checkpoints) // error deliberately ignored, the run must go on
+}
+
+// finish records the status of the play that was running when ansible exited
+func (c *checkpointExplainer) finish(failed bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endPlay(failed)
+	return writeCheckpoints(c.runDirectory, c.checkpoints)
+}
+
+// restart records the play that was running as failed, and starts over
+// the checkpoints for the next attempt of the playbook. Returns the files
+// that were completed by the attempt.
+func (c *checkpointExplainer) restart() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endPlay(true)
+	completed := c.checkpoints.completedFiles()
+	c.checkpoints.Plays = nil
+	return completed, writeCheckpoints(c.runDirectory, c.checkpoints)
+}
+
+func (c *checkpointExplainer) endPlay(failed bool) {
+	if len(c.checkpoints.Plays) == 0 {
+		return
+	}
+	p := &c.checkpoints.Plays[len(c.checkpoints.Plays)-1]
+	if p.Status != PlayStatusRunning {
+		return
+	}
+	p.Status = PlayStatusSucceeded
+	if failed {
+		p.Status = PlayStatusFailed
+	}
+	for _, s := range p.Hosts {
+		if s != HostStatusOK {
+			p.Status = PlayStatusFailed
+		}
+	}
+}
+
+// setHostStatus sets the status of the host in the running play. A host
+// that failed remains failed.
+func (c *checkpointExplainer) setHostStatus(host string, status string) {
+	if len(c.checkpoints.Plays) == 0 || host == "" {
+		return
+	}
+	p := &c.checkpoints.Plays[len(c.checkpoints.Plays)-1]
+	if s, ok := p.Hosts[host]; ok && s != HostStatusOK {
+		return
+	}
+	p.Hosts[host] = status
+	if status != HostStatusOK && p.FailedTask == "" {
+		p.FailedTask = c.task
+	}
+}
+
+// Failure returns the first play that failed, or nil if no play failed
+func (c Checkpoints) Failure() *PlayCheckpoint {
+	for _, p := range c.Plays {
+		if p.Status == PlayStatusFailed {
+			return &p
+		}
+	}
+	return nil
+}
+
+// FailedHosts returns the hosts the play failed on, or was unreachable, sorted
+func (p PlayCheckpoint) FailedHosts() []string {
+	hosts := []string{}
+	for h, s := range p.Hosts {
+		if s != HostStatusOK {
+			hosts = append(hosts, h)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,204 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+type noopExplainer struct{}
+
+func (noopExplainer) ExplainEvent(e ansible.Event) {}
+
+func playStart(file, name string) ansible.Event {
+	e := &ansible.PlayStartEvent{File: file}
+	e.Name = name
+	return e
+}
+
+func taskStart(name string) ansible.Event {
+	e := &ansible.TaskStartEvent{}
+	e.Name = name
+	return e
+}
+
+func runnerOK(host string) ansible.Event {
+	e := &ansible.RunnerOKEvent{}
+	e.Host = host
+	return e
+}
+
+func runnerFailed(host string, ignoreErrors bool) ansible.Event {
+	e := &ansible.RunnerFailedEvent{}
+	e.Host = host
+	e.IgnoreErrors = ignoreErrors
+	return e
+}
+
+func runnerUnreachable(host string) ansible.Event {
+	e := &ansible.RunnerUnreachableEvent{}
+	e.Host = host
+	return e
+}
+
+func TestCheckpointExplainer(t *testing.T) {
+	runDir, err := ioutil.TempDir("", "ket-test-checkpoints")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+
+	c := &checkpointExplainer{
+		explainer:    noopExplainer{},
+		runDirectory: runDir,
+		checkpoints:  Checkpoints{PlanHash: "abc"},
+	}
+	events := []ansible.Event{
+		playStart("_all.yaml", "Gather Facts"),
+		runnerOK("etcd01"),
+		runnerOK("master01"),
+		playStart("_docker.yaml", "Install Docker"),
+		runnerOK("etcd01"),
+		runnerFailed("master01", true),
+		playStart("_etcd-k8s.yaml", "Start etcd"),
+		taskStart("start etcd"),
+		runnerFailed("etcd01", false),
+		runnerOK("etcd01"),
+		runnerUnreachable("etcd02"),
+	}
+	for _, e := range events {
+		c.ExplainEvent(e)
+	}
+	if err := c.finish(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := readCheckpoints(runDir)
+	if err != nil {
+		t.Fatalf("error reading checkpoints: %v", err)
+	}
+	expected := &Checkpoints{
+		PlanHash: "abc",
+		Plays: []PlayCheckpoint{
+			{File: "_all.yaml", Name: "Gather Facts", Status: PlayStatusSucceeded, Hosts: map[string]string{"etcd01": HostStatusOK, "master01": HostStatusOK}},
+			{File: "_docker.yaml", Name: "Install Docker", Status: PlayStatusSucceeded, Hosts: map[string]string{"etcd01": HostStatusOK, "master01": HostStatusOK}},
+			{File: "_etcd-k8s.yaml", Name: "Start etcd", Status: PlayStatusFailed, Hosts: map[string]string{"etcd01": HostStatusFailed, "etcd02": HostStatusUnreachable}, FailedTask: "start etcd"},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected checkpoints:\n%+v\ngot:\n%+v", expected, got)
+	}
+	f := got.Failure()
+	if f == nil || f.File != "_etcd-k8s.yaml" {
+		t.Fatalf("expected the etcd play to be the failure, got %+v", f)
+	}
+	if hosts := f.FailedHosts(); !reflect.DeepEqual(hosts, []string{"etcd01", "etcd02"}) {
+		t.Errorf("unexpected failed hosts: %v", hosts)
+	}
+}
+
+func TestCompletedPlays(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-completed-plays")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+
+	writeRun := func(dir string, status string, c *Checkpoints) {
+		runDir := filepath.Join(runsDir, "apply", dir)
+		if err := os.MkdirAll(runDir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		if err := writeRunRecord(runDir, RunRecord{Task: "apply", Status: status}); err != nil {
+			t.Fatalf("error writing run record: %v", err)
+		}
+		if c == nil {
+			return
+		}
+		if err := writeCheckpoints(runDir, *c); err != nil {
+			t.Fatalf("error writing checkpoints: %v", err)
+		}
+	}
+
+	if _, err := completedPlays(runsDir, "apply", "abc"); err == nil {
+		t.Errorf("expected an error when there are no runs")
+	}
+
+	writeRun("2018-01-01-10-00-00", RunStatusFailed, nil)
+	if _, err := completedPlays(runsDir, "apply", "abc"); err == nil {
+		t.Errorf("expected an error when the last run has no checkpoints")
+	}
+
+	writeRun("2018-01-02-10-00-00", RunStatusFailed, &Checkpoints{
+		PlanHash: "abc",
+		Plays: []PlayCheckpoint{
+			{File: "_all.yaml", Status: PlayStatusSucceeded},
+			{File: "_docker.yaml", Status: PlayStatusSucceeded},
+			{File: "_etcd-k8s.yaml", Status: PlayStatusSucceeded},
+			{File: "_etcd-k8s.yaml", Status: PlayStatusFailed},
+			{File: "_kubelet.yaml", Status: PlayStatusRunning},
+		},
+	})
+	got, err := completedPlays(runsDir, "apply", "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"_all.yaml", "_docker.yaml"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected completed plays %v, got %v", expected, got)
+	}
+
+	if _, err := completedPlays(runsDir, "apply", "def"); err == nil {
+		t.Errorf("expected an error when the plan has changed")
+	}
+
+	writeRun("2018-01-03-10-00-00", RunStatusSucceeded, &Checkpoints{PlanHash: "abc"})
+	if _, err := completedPlays(runsDir, "apply", "abc"); err == nil {
+		t.Errorf("expected an error when the last run succeeded")
+	}
+}
+
+func TestPlanHash(t *testing.T) {
+	p := &Plan{}
+	p.Cluster.Name = "foo"
+	h1, err := planHash(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h2, _ := planHash(p)
+	if h1 != h2 {
+		t.Errorf("expected the hash to be stable")
+	}
+	p.Cluster.Name = "bar"
+	h3, _ := planHash(p)
+	if h1 == h3 {
+		t.Errorf("expected the hash to change when the plan changes")
+	}
+
+	// the secrets that are resolved from references can be rotated
+	os.Setenv("KET_TEST_PLAN_HASH_PASSWORD", "secret")
+	defer os.Unsetenv("KET_TEST_PLAN_HASH_PASSWORD")
+	resolvedHash := func() string {
+		p := &Plan{}
+		p.DockerRegistry.Password = "${env:KET_TEST_PLAN_HASH_PASSWORD}"
+		p.AddOns.CNI = &CNI{Provider: cniProviderWeave}
+		p.AddOns.CNI.Options.Weave.Password = "${env:KET_TEST_PLAN_HASH_PASSWORD}"
+		if err := resolveSecrets(p); err != nil {
+			t.Fatalf("unexpected error resolving secrets: %v", err)
+		}
+		if p.AddOns.CNI.Options.Weave.Password != os.Getenv("KET_TEST_PLAN_HASH_PASSWORD") {
+			t.Fatalf("expected the secret to be resolved, got %q", p.AddOns.CNI.Options.Weave.Password)
+		}
+		h, _ := planHash(p)
+		return h
+	}
+	h4 := resolvedHash()
+	os.Setenv("KET_TEST_PLAN_HASH_PASSWORD", "rotated")
+	if h5 := resolvedHash(); h4 != h5 {
+		t.Errorf("expected the hash not to change when a secret is rotated")
+	}
+}
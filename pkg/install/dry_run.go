@@ -0,0 +1,134 @@
+package install
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/util"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultDryRunDirectory is the directory where the tasks of a dry-run
+	// are rendered when no other directory is specified.
+	DefaultDryRunDirectory = "./dry-run"
+
+	dryRunTasksFile = "tasks.yaml"
+)
+
+// DryRunTask is a task that the executor would have run, and that was
+// rendered in the dry-run directory instead
+type DryRunTask struct {
+	// Order in which the task would have run, starting from 1
+	Order    int      `yaml:"order"`
+	Task     string   `yaml:"task"`
+	Playbook string   `yaml:"playbook"`
+	Limit    []string `yaml:"limit,omitempty"`
+	// Directory of the inventory and cluster catalog of the task, relative
+	// to the dry-run directory
+	Directory string `yaml:"directory"`
+}
+
+// NewDryRunDirectory creates the directory a dry-run is rendered in, named
+// after the current time, in the base directory
+func NewDryRunDirectory(base string) (string, error) {
+	dir := filepath.Join(base, time.Now().Format(runDirectoryTimeFormat))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("error creating dry-run directory: %v", err)
+	}
+	return dir, nil
+}
+
+// ReadDryRun returns the tasks that were rendered in the dry-run directory,
+// in the order they would have run
+func ReadDryRun(dir string) ([]DryRunTask, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, dryRunTasksFile))
+	if os.IsNotExist(err) {
+		return []DryRunTask{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading dry-run tasks: %v", err)
+	}
+	tasks := []DryRunTask{}
+	if err := yaml.Unmarshal(b, &tasks); err != nil {
+		return nil, fmt.Errorf("error unmarshaling dry-run tasks in %q: %v", dir, err)
+	}
+	return tasks, nil
+}
+
+// renderDryRun writes the inventory and the cluster catalog of the task to
+// the dry-run directory, with the secrets of the catalog redacted, and records
+// the task after the tasks that were rendered before it
+func (ae *ansibleExecutor) renderDryRun(t task) error {
+	dir := ae.options.DryRunDirectory
+	tasks, err := ReadDryRun(dir)
+	if err != nil {
+		return err
+	}
+	dt := DryRunTask{
+		Order:     len(tasks) + 1,
+		Task:      t.name,
+		Playbook:  t.playbook,
+		Limit:     t.limit,
+		Directory: fmt.Sprintf("%02d-%s", len(tasks)+1, t.name),
+	}
+	taskDir := filepath.Join(dir, dt.Directory)
+	if err := os.MkdirAll(taskDir, 0700); err != nil {
+		return fmt.Errorf("error creating directory for %q: %v", t.name, err)
+	}
+	inventoryFile := filepath.Join(taskDir, "inventory.ini")
+	if err := ioutil.WriteFile(inventoryFile, t.inventory.ToINI(), 0600); err != nil {
+		return fmt.Errorf("error writing inventory file to %q: %v", inventoryFile, err)
+	}
+	redacted := t.clusterCatalog.Redacted()
+	cc, err := redacted.ToYAML()
+	if err != nil {
+		return fmt.Errorf("error writing cluster catalog data to yaml: %v", err)
+	}
+	catalogFile := filepath.Join(taskDir, "clustercatalog.yaml")
+	if err := ioutil.WriteFile(catalogFile, cc, 0600); err != nil {
+		return fmt.Errorf("error writing cluster catalog file to %q: %v", catalogFile, err)
+	}
+	b, err := yaml.Marshal(append(tasks, dt))
+	if err != nil {
+		return fmt.Errorf("error marshaling dry-run tasks: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, dryRunTasksFile), b, 0600); err != nil {
+		return fmt.Errorf("error writing dry-run tasks: %v", err)
+	}
+	hosts := "all hosts"
+	if len(t.limit) > 0 {
+		hosts = strings.Join(t.limit, ", ")
+	}
+	util.PrettyPrintOk(ae.stdout, "Dry-run: rendered %q on %s in %q", t.playbook, hosts, taskDir)
+	return nil
+}
+
+// PrintDryRunSummary prints the tasks that were rendered in the dry-run
+// directory, in the order they would have run
+func PrintDryRunSummary(out io.Writer, dir string) error {
+	tasks, err := ReadDryRun(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Dry-run: %d playbook runs were rendered in %q\n\n", len(tasks), dir)
+	if len(tasks) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORDER\tTASK\tPLAYBOOK\tHOSTS\tDIRECTORY")
+	for _, t := range tasks {
+		hosts := "all"
+		if len(t.Limit) > 0 {
+			hosts = strings.Join(t.Limit, ",")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.Order, t.Task, t.Playbook, hosts, t.Directory)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,237 @@
+package install
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+// The types of the events sent to an event sink
+const (
+	EventRunStarted          = "run_started"
+	EventRunFinished         = "run_finished"
+	EventNodeUpgradeStarted  = "node_upgrade_started"
+	EventNodeUpgradeFinished = "node_upgrade_finished"
+	EventSafetyCheckFailed   = "safety_check_failed"
+	EventAnsible             = "ansible"
+)
+
+// SinkEvent is an installation event sent to an event sink
+type SinkEvent struct {
+	Time string `json:"time"`
+	Type string `json:"type"`
+	// Cluster is the name of the cluster in the plan file
+	Cluster string `json:"cluster,omitempty"`
+	// Run is the ID of the run the event belongs to
+	Run    string `json:"run,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Node and Roles are set on node upgrade and safety check events
+	Node  string   `json:"node,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	// Errors are the unsafe conditions that were detected by a safety check
+	Errors []string `json:"errors,omitempty"`
+	// Ansible is set on events of type "ansible"
+	Ansible *explain.JSONEvent `json:"ansible,omitempty"`
+}
+
+// EventSink receives the installation events. Send must not block on ansible
+// events, but may block until there is room for a lifecycle event, as
+// lifecycle events are never dropped.
+type EventSink interface {
+	Send(SinkEvent)
+	// Close delivers the events that are still buffered, and returns an
+	// error if any event could not be delivered
+	Close() error
+}
+
+// WebhookOptions configure a webhook event sink
+type WebhookOptions struct {
+	// URL the events are posted to
+	URL string
+	// Retries is the number of times the delivery of an event is retried
+	Retries int
+	// RetryInterval is the wait before the first retry, doubled on every retry
+	RetryInterval time.Duration
+	// BufferSize is the number of events that are buffered, waiting to be
+	// delivered. Ansible events are dropped when the buffer is full, and
+	// lifecycle events wait for room in the buffer.
+	BufferSize int
+	// Timeout of each request
+	Timeout time.Duration
+	// FlushTimeout is how long Close waits for the buffered events to be delivered
+	FlushTimeout time.Duration
+	// AnsibleEvents enables the delivery of the ansible events of each task.
+	// Only the lifecycle events are delivered otherwise.
+	AnsibleEvents bool
+}
+
+// WebhookSink posts each event as a JSON object to a URL, in the order they were sent
+type WebhookSink struct {
+	options WebhookOptions
+	client  *http.Client
+	events  chan SinkEvent
+	done    chan struct{}
+
+	// sending is held while an event is buffered, so that the buffer is not
+	// closed while a lifecycle event waits for room in it
+	sending sync.RWMutex
+	closed  bool
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// NewWebhookSink returns a webhook event sink that starts delivering events
+// right away. Unset options are defaulted.
+func NewWebhookSink(options WebhookOptions) (*WebhookSink, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("the webhook URL cannot be empty")
+	}
+	if options.Retries < 0 {
+		return nil, fmt.Errorf("the webhook retries cannot be negative")
+	}
+	if options.RetryInterval == 0 {
+		options.RetryInterval = time.Second
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 1000
+	}
+	if options.Timeout == 0 {
+		options.Timeout = 10 * time.Second
+	}
+	if options.FlushTimeout == 0 {
+		options.FlushTimeout = 30 * time.Second
+	}
+	s := &WebhookSink{
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+		events:  make(chan SinkEvent, options.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go s.deliver()
+	return s, nil
+}
+
+// Send buffers the event for delivery. Ansible events are skipped unless
+// enabled, and are dropped if the buffer is full. Lifecycle events are never
+// dropped, Send blocks until there is room for them in the buffer.
+func (s *WebhookSink) Send(e SinkEvent) {
+	if e.Type == EventAnsible && !s.options.AnsibleEvents {
+		return
+	}
+	s.sending.RLock()
+	defer s.sending.RUnlock()
+	if s.closed {
+		return
+	}
+	if e.Time == "" {
+		e.Time = time.Now().Format(time.RFC3339Nano)
+	}
+	if e.Type != EventAnsible {
+		s.events <- e
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+	}
+}
+
+// Close stops accepting events, and waits for the buffered events to be delivered
+func (s *WebhookSink) Close() error {
+	s.sending.Lock()
+	if s.closed {
+		s.sending.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.events)
+	s.sending.Unlock()
+
+	select {
+	case <-s.done:
+	case <-time.After(s.options.FlushTimeout):
+		return fmt.Errorf("timed out delivering the events to %q", s.options.URL)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dropped > 0 {
+		return fmt.Errorf("%d events could not be delivered to %q", s.dropped, s.options.URL)
+	}
+	return nil
+}
+
+func (s *WebhookSink) deliver() {
+	defer close(s.done)
+	for e := range s.events {
+		if err := s.post(e); err != nil {
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+		}
+	}
+}
+
+// post sends the event, retrying when the request fails, or the server
+// returns an error that may be temporary
+func (s *WebhookSink) post(e SinkEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	wait := s.options.RetryInterval
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = s.postOnce(b)
+		if err == nil || !retry || attempt >= s.options.Retries {
+			return err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (s *WebhookSink) postOnce(body []byte) (retry bool, err error) {
+	resp, err := s.client.Post(s.options.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook returned %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// sinkExplainer sends the ansible events to the event sink, before passing
+// them on to the explainer
+type sinkExplainer struct {
+	explainer explain.AnsibleEventExplainer
+	json      *explain.JSONExplainer
+}
+
+func newSinkExplainer(explainer explain.AnsibleEventExplainer, sink EventSink, cluster string, run string) *sinkExplainer {
+	return &sinkExplainer{
+		explainer: explainer,
+		json: explain.NewJSONEventExplainer(func(j explain.JSONEvent) {
+			sink.Send(SinkEvent{Time: j.Time, Type: EventAnsible, Cluster: cluster, Run: run, Ansible: &j})
+		}),
+	}
+}
+
+// ExplainEvent sends the event to the sink and explains it
+func (s *sinkExplainer) ExplainEvent(e ansible.Event) {
+	s.json.ExplainEvent(e)
+	s.explainer.ExplainEvent(e)
+}
@@ -0,0 +1,215 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type webhookServer struct {
+	mu       sync.Mutex
+	events   []SinkEvent
+	requests int
+	// failures is the number of requests that fail before the server
+	// starts accepting events
+	failures int
+	status   int
+	block    chan struct{}
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.requests <= s.failures {
+		w.WriteHeader(s.status)
+		return
+	}
+	e := SinkEvent{}
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.events = append(s.events, e)
+}
+
+func TestWebhookSinkDeliversEventsInOrder(t *testing.T) {
+	s := &webhookServer{failures: 2, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, Retries: 2, RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sink.Send(SinkEvent{Type: EventRunStarted, Run: "apply/2018-01-01-10-00-00"})
+	sink.Send(SinkEvent{Type: EventRunFinished, Run: "apply/2018-01-01-10-00-00", Status: RunStatusSucceeded})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected error closing the sink: %v", err)
+	}
+
+	if len(s.events) != 2 {
+		t.Fatalf("expected 2 events to be delivered, got %d", len(s.events))
+	}
+	if s.events[0].Type != EventRunStarted || s.events[1].Type != EventRunFinished || s.events[1].Status != RunStatusSucceeded {
+		t.Errorf("unexpected events: %+v", s.events)
+	}
+	if s.events[0].Time == "" {
+		t.Errorf("expected the event time to be set")
+	}
+	if s.requests != 4 {
+		t.Errorf("expected 4 requests, got %d", s.requests)
+	}
+}
+
+func TestWebhookSinkGivesUp(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           int
+		expectedRequests int
+	}{
+		{"server error is retried", http.StatusInternalServerError, 3},
+		{"client error is not retried", http.StatusBadRequest, 1},
+	}
+	for _, test := range tests {
+		s := &webhookServer{failures: 10, status: test.status}
+		server := httptest.NewServer(s)
+		sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, Retries: 2, RetryInterval: time.Millisecond})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		sink.Send(SinkEvent{Type: EventRunStarted})
+		if err := sink.Close(); err == nil {
+			t.Errorf("%s: expected an error when the event could not be delivered", test.name)
+		}
+		if s.requests != test.expectedRequests {
+			t.Errorf("%s: expected %d requests, got %d", test.name, test.expectedRequests, s.requests)
+		}
+		server.Close()
+	}
+}
+
+func TestWebhookSinkDropsEventsWhenBufferIsFull(t *testing.T) {
+	s := &webhookServer{block: make(chan struct{})}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, BufferSize: 2, AnsibleEvents: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Send must not block while the server is not responding
+	for i := 0; i < 10; i++ {
+		sink.Send(SinkEvent{Type: EventAnsible})
+	}
+	close(s.block)
+	if err := sink.Close(); err == nil {
+		t.Errorf("expected an error when events were dropped")
+	}
+	if len(s.events) < 1 || len(s.events) > 3 {
+		t.Errorf("expected the buffered events to be delivered, got %d events", len(s.events))
+	}
+}
+
+func TestWebhookSinkNeverDropsLifecycleEvents(t *testing.T) {
+	s := &webhookServer{block: make(chan struct{})}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, BufferSize: 2, AnsibleEvents: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 0; i < 10; i++ {
+			sink.Send(SinkEvent{Type: EventAnsible})
+			sink.Send(SinkEvent{Type: EventNodeUpgradeFinished, Node: fmt.Sprintf("worker%d", i)})
+		}
+		sink.Send(SinkEvent{Type: EventRunFinished, Status: RunStatusSucceeded})
+	}()
+	// Send blocks on the lifecycle events while the buffer is full
+	select {
+	case <-sent:
+		t.Fatalf("expected Send to wait for room in the buffer")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(s.block)
+	<-sent
+	// ansible events were dropped
+	if err := sink.Close(); err == nil {
+		t.Errorf("expected an error when events were dropped")
+	}
+
+	var nodes []string
+	for _, e := range s.events {
+		if e.Type == EventNodeUpgradeFinished {
+			nodes = append(nodes, e.Node)
+		}
+	}
+	if len(nodes) != 10 || nodes[0] != "worker0" || nodes[9] != "worker9" {
+		t.Errorf("expected the node upgrade events to be delivered in order, got %v", nodes)
+	}
+	if last := s.events[len(s.events)-1]; last.Type != EventRunFinished {
+		t.Errorf("expected the run finished event to be delivered last, got %+v", last)
+	}
+}
+
+func TestWebhookSinkSkipsAnsibleEventsByDefault(t *testing.T) {
+	s := &webhookServer{}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e := newSinkExplainer(noopExplainer{}, sink, "prod", "apply/2018-01-01-10-00-00")
+	e.ExplainEvent(playStart("_etcd-k8s.yaml", "Start etcd"))
+	e.ExplainEvent(taskStart("start etcd"))
+	sink.Send(SinkEvent{Type: EventRunFinished, Status: RunStatusSucceeded})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected error closing the sink: %v", err)
+	}
+
+	if len(s.events) != 1 || s.events[0].Type != EventRunFinished {
+		t.Errorf("expected only the run finished event to be delivered, got %+v", s.events)
+	}
+}
+
+func TestSinkExplainer(t *testing.T) {
+	s := &webhookServer{}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	sink, err := NewWebhookSink(WebhookOptions{URL: server.URL, AnsibleEvents: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e := newSinkExplainer(noopExplainer{}, sink, "prod", "apply/2018-01-01-10-00-00")
+	e.ExplainEvent(playStart("_etcd-k8s.yaml", "Start etcd"))
+	e.ExplainEvent(taskStart("start etcd"))
+	e.ExplainEvent(runnerFailed("etcd01", false))
+	if err := sink.Close(); err != nil {
+		t.Fatalf("unexpected error closing the sink: %v", err)
+	}
+
+	if len(s.events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(s.events))
+	}
+	failed := s.events[2]
+	if failed.Type != EventAnsible || failed.Cluster != "prod" || failed.Run != "apply/2018-01-01-10-00-00" {
+		t.Errorf("unexpected event: %+v", failed)
+	}
+	if failed.Ansible == nil || failed.Ansible.Type != "runner_failed" || failed.Ansible.Host != "etcd01" || failed.Ansible.Task != "start etcd" || failed.Ansible.Play != "Start etcd" {
+		t.Errorf("unexpected ansible event: %+v", failed.Ansible)
+	}
+}
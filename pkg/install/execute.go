@@ -1,18 +1,22 @@
 package install
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"strings"
 
 	"github.com/apprenda/kismatic/pkg/ansible"
 	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/ssh"
 	"github.com/apprenda/kismatic/pkg/tls"
 	"github.com/apprenda/kismatic/pkg/util"
 )
@@ -51,7 +55,7 @@ type ExecutorOptions struct {
 	// GeneratedAssetsDirectory is the location where generated assets
 	// are to be stored
 	GeneratedAssetsDirectory string
-	// OutputFormat sets the format of the executor
+	// OutputFormat sets the format of the executor: simple, raw or json
 	OutputFormat string
 	// Verbose output from the executor
 	Verbose bool
@@ -59,10 +63,37 @@ type ExecutorOptions struct {
 	RunsDirectory string
 	// DiagnosticsDirecty is where the doDiagnostics information about the cluster will be dumped
 	DiagnosticsDirecty string
-	// DryRun determines if the executor should actually run the task
+	// DryRun determines if the executor should actually run the task. When
+	// set, the tasks are rendered in the DryRunDirectory instead.
 	DryRun bool
+	// DryRunDirectory is where the inventory, the cluster catalog, the
+	// playbook and the hosts of each task are rendered on a dry-run
+	DryRunDirectory string
+	// Resume the installation from the last run of apply, skipping the
+	// plays that were completed
+	Resume bool
+	// EventSink receives the ansible events and the lifecycle events of
+	// the runs, when set
+	EventSink EventSink
+	// Profile prints the slowest plays, tasks and hosts after each run. The
+	// report is always stored in the run directory.
+	Profile bool
+	// Retry re-runs the playbooks that failed on the hosts they failed on
+	Retry RetryPolicy
+	// Context stops the executor when it is done. The playbook that is
+	// running stops once its running task completes, and no other playbook
+	// is started.
+	Context context.Context
 }
 
+// The phases that are marked in the json output, in addition to the tasks
+// that are run by the executor
+const (
+	PhaseValidation   = "validation"
+	PhaseCertificates = "certificates"
+	PhaseKubeconfig   = "kubeconfig"
+)
+
 // NewExecutor returns an executor for performing installations according to the installation plan.
 func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (Executor, error) {
 	ansibleDir := "ansible"
@@ -70,18 +101,16 @@ func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (E
 		return nil, fmt.Errorf("GeneratedAssetsDirectory option cannot be empty")
 	}
 	if options.RunsDirectory == "" {
-		options.RunsDirectory = "./runs"
+		options.RunsDirectory = DefaultRunsDirectory
+	}
+	if options.DryRun && options.DryRunDirectory == "" {
+		return nil, fmt.Errorf("DryRunDirectory option cannot be empty on a dry-run")
 	}
 
 	// Setup the console output format
-	var outFormat ansible.OutputFormat
-	switch options.OutputFormat {
-	case "raw":
-		outFormat = ansible.RawFormat
-	case "simple":
-		outFormat = ansible.JSONLinesFormat
-	default:
-		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
+	outFormat, stdout, jsonExplainer, err := consoleOutput(options.OutputFormat, stdout, errOut)
+	if err != nil {
+		return nil, err
 	}
 	certsDir := filepath.Join(options.GeneratedAssetsDirectory, "keys")
 	pki := &LocalPKI{
@@ -93,6 +122,7 @@ func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (E
 		options:             options,
 		stdout:              stdout,
 		consoleOutputFormat: outFormat,
+		jsonExplainer:       jsonExplainer,
 		ansibleDir:          ansibleDir,
 		certsDir:            certsDir,
 		pki:                 pki,
@@ -103,23 +133,19 @@ func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (E
 func NewPreFlightExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (PreFlightExecutor, error) {
 	ansibleDir := "ansible"
 	if options.RunsDirectory == "" {
-		options.RunsDirectory = "./runs"
+		options.RunsDirectory = DefaultRunsDirectory
 	}
 	// Setup the console output format
-	var outFormat ansible.OutputFormat
-	switch options.OutputFormat {
-	case "raw":
-		outFormat = ansible.RawFormat
-	case "simple":
-		outFormat = ansible.JSONLinesFormat
-	default:
-		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
+	outFormat, stdout, jsonExplainer, err := consoleOutput(options.OutputFormat, stdout, errOut)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ansibleExecutor{
 		options:             options,
 		stdout:              stdout,
 		consoleOutputFormat: outFormat,
+		jsonExplainer:       jsonExplainer,
 		ansibleDir:          ansibleDir,
 	}, nil
 }
@@ -128,7 +154,7 @@ func NewPreFlightExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOp
 func NewDiagnosticsExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (DiagnosticsExecutor, error) {
 	ansibleDir := "ansible"
 	if options.RunsDirectory == "" {
-		options.RunsDirectory = "./runs"
+		options.RunsDirectory = DefaultRunsDirectory
 	}
 	if options.DiagnosticsDirecty == "" {
 		wd, err := os.Getwd()
@@ -139,20 +165,16 @@ func NewDiagnosticsExecutor(stdout io.Writer, errOut io.Writer, options Executor
 	}
 
 	// Setup the console output format
-	var outFormat ansible.OutputFormat
-	switch options.OutputFormat {
-	case "raw":
-		outFormat = ansible.RawFormat
-	case "simple":
-		outFormat = ansible.JSONLinesFormat
-	default:
-		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
+	outFormat, stdout, jsonExplainer, err := consoleOutput(options.OutputFormat, stdout, errOut)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ansibleExecutor{
 		options:             options,
 		stdout:              stdout,
 		consoleOutputFormat: outFormat,
+		jsonExplainer:       jsonExplainer,
 		ansibleDir:          ansibleDir,
 	}, nil
 }
@@ -161,6 +183,7 @@ type ansibleExecutor struct {
 	options             ExecutorOptions
 	stdout              io.Writer
 	consoleOutputFormat ansible.OutputFormat
+	jsonExplainer       *explain.JSONExplainer
 	ansibleDir          string
 	certsDir            string
 	pki                 PKI
@@ -187,54 +210,177 @@ type task struct {
 }
 
 // execute will run the given task, and setup all what's needed for us to run ansible.
-func (ae *ansibleExecutor) execute(t task) error {
+func (ae *ansibleExecutor) execute(t task) (err error) {
 	if ae.options.DryRun {
-		return nil
+		// The inventory is rendered as ansible gets it on a real run, without
+		// pinning the host keys or starting the agent
+		setKnownHostsFile(t.inventory)
+		removeEncryptedKeys(t.inventory)
+		return ae.renderDryRun(t)
+	}
+	ctx := ae.context()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%q was not run: %v", t.name, ansible.ErrInterrupted)
+	}
+	ae.phaseStarted(t.name)
+	defer func() { ae.phaseFinished(t.name, err) }()
+	if err = pinHostKeys(t.inventory, t.plan.Cluster.SSH.BastionHost()); err != nil {
+		return err
+	}
+	agent, err := startSSHAgent(t.inventory)
+	if err != nil {
+		return err
+	}
+	var agentSocket string
+	if agent != nil {
+		defer agent.Close()
+		agentSocket = agent.Socket
 	}
 	runDirectory, err := ae.createRunDirectory(t.name)
 	if err != nil {
 		return fmt.Errorf("error creating working directory for %q: %v", t.name, err)
 	}
+	runID := t.name + "/" + filepath.Base(runDirectory)
+	ae.sendEvent(SinkEvent{Type: EventRunStarted, Cluster: t.plan.Cluster.Name, Run: runID})
+	defer func() {
+		e := SinkEvent{Type: EventRunFinished, Cluster: t.plan.Cluster.Name, Run: runID, Status: ae.runStatus(err)}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		ae.sendEvent(e)
+	}()
+	record := RunRecord{
+		Task:     t.name,
+		Playbook: t.playbook,
+		Limit:    t.limit,
+		Status:   RunStatusRunning,
+		Start:    time.Now().Format(time.RFC3339),
+		Owner:    lockOwner(),
+		PID:      os.Getpid(),
+	}
+	if err = writeRunRecord(runDirectory, record); err != nil {
+		return err
+	}
 	// Save the plan file that was used for this execution
 	fp := FilePlanner{
-		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+		File: filepath.Join(runDirectory, runPlanFile),
 	}
-	if err = fp.Write(&t.plan); err != nil {
+	// The nodes that were read from inventory sources are recorded, as the
+	// sources can change between runs
+	if err = fp.Write(withInventoryNodes(&t.plan)); err != nil {
 		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
 	}
+	hash, err := planHash(withInventoryNodes(&t.plan))
+	if err != nil {
+		return err
+	}
+	runExplainer := t.explainer
+	if ae.options.EventSink != nil {
+		runExplainer = newSinkExplainer(t.explainer, ae.options.EventSink, t.plan.Cluster.Name, runID)
+	}
+	checkpoints := &checkpointExplainer{
+		explainer:    runExplainer,
+		runDirectory: runDirectory,
+		checkpoints:  Checkpoints{PlanHash: hash},
+	}
+	if err = writeCheckpoints(runDirectory, checkpoints.checkpoints); err != nil {
+		return err
+	}
 	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
 	ansibleLogFile, err := os.Create(ansibleLogFilename)
 	if err != nil {
 		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
 	}
-	runner, explainer, err := ae.ansibleRunnerWithExplainer(t.explainer, ansibleLogFile, runDirectory)
+	profile := newProfileExplainer(checkpoints)
+	failures := &failedHostsExplainer{explainer: profile}
+	runner, explainer, err := ae.ansibleRunnerWithExplainer(failures, ansibleLogFile, runDirectory, t.plan.Cluster.SSH.BecomePassword, agentSocket)
 	if err != nil {
 		return err
 	}
 
-	// Start running ansible with the given playbook
-	var eventStream <-chan ansible.Event
-	if t.limit != nil && len(t.limit) != 0 {
-		eventStream, err = runner.StartPlaybookOnNode(t.playbook, t.inventory, t.clusterCatalog, t.limit...)
-	} else {
-		eventStream, err = runner.StartPlaybook(t.playbook, t.inventory, t.clusterCatalog)
-	}
+	cc := t.clusterCatalog
+	limit := t.limit
+	for attempt := 0; ; attempt++ {
+		// Start running ansible with the given playbook
+		var eventStream <-chan ansible.Event
+		if limit != nil && len(limit) != 0 {
+			eventStream, err = runner.StartPlaybookOnNode(ctx, t.playbook, t.inventory, cc, limit...)
+		} else {
+			eventStream, err = runner.StartPlaybook(ctx, t.playbook, t.inventory, cc)
+		}
+		if err != nil {
+			record.Status = ae.runStatus(err)
+			record.Error = err.Error()
+			writeRunRecord(runDirectory, record) // error deliberately ignored
+			return fmt.Errorf("error running ansible playbook: %v", err)
+		}
+		// Ansible blocks until explainer starts reading from stream. Start
+		// explainer in a separate go routine
+		explained := make(chan struct{})
+		go func() {
+			explainer.Explain(eventStream)
+			close(explained)
+		}()
+
+		// Wait until ansible exits, and its events are explained
+		err = runner.WaitPlaybook()
+		<-explained
+		failedHosts, othersStopped := failures.reset()
+		if err == nil || ctx.Err() != nil || attempt == ae.options.Retry.Attempts || len(failedHosts) == 0 {
+			break
+		}
+		// The plays stop on all hosts when a host fails, so the playbook
+		// cannot be re-run on the failed hosts only when other hosts ran it
+		if othersStopped {
+			fmt.Fprintf(ae.stdout, "Playbook failed on %s, and stopped on the other hosts. It is not retried, as retrying on the failed hosts only would leave the other hosts behind\n", strings.Join(failedHosts, ", "))
+			break
+		}
+		// Re-run the playbook on the hosts that failed only. The plays that
+		// were completed are skipped.
+		wait := ae.options.Retry.wait(attempt + 1)
+		fmt.Fprintf(ae.stdout, "Playbook failed on %s, retrying on these hosts from the play that failed in %v (attempt %d of %d)\n", strings.Join(failedHosts, ", "), wait, attempt+1, ae.options.Retry.Attempts)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		completed, _ := checkpoints.restart() // error deliberately ignored, the run must go on
+		cc.CompletedPlays = mergeCompletedPlays(cc.CompletedPlays, completed)
+		limit = failedHosts
+		record.Retries = attempt + 1
+		writeRunRecord(runDirectory, record) // error deliberately ignored, the run must go on
+	}
+	checkpoints.finish(err != nil) // error deliberately ignored, the run record is more important
+	report := profile.finish()
+	writeProfileReport(runDirectory, report) // error deliberately ignored, the run record is more important
+	if ae.options.Profile {
+		PrintProfileReport(ae.stdout, report)
+	}
+	record.End = time.Now().Format(time.RFC3339)
+	record.Status = ae.runStatus(err)
 	if err != nil {
-		return fmt.Errorf("error running ansible playbook: %v", err)
+		record.Error = err.Error()
 	}
-	// Ansible blocks until explainer starts reading from stream. Start
-	// explainer in a separate go routine
-	go explainer.Explain(eventStream)
-
-	// Wait until ansible exits
-	if err = runner.WaitPlaybook(); err != nil {
+	if recErr := writeRunRecord(runDirectory, record); recErr != nil {
+		return recErr
+	}
+	if err != nil {
 		return fmt.Errorf("error running playbook: %v", err)
 	}
 	return nil
 }
 
-// GenerateCertificatesprivate generates keys and certificates for the cluster, if needed
+// GenerateCertificates generates keys and certificates for the cluster, if needed
 func (ae *ansibleExecutor) GenerateCertificates(p *Plan, useExistingCA bool) error {
+	ae.phaseStarted(PhaseCertificates)
+	err := ae.generateCertificates(p, useExistingCA)
+	ae.phaseFinished(PhaseCertificates, err)
+	return err
+}
+
+func (ae *ansibleExecutor) generateCertificates(p *Plan, useExistingCA bool) error {
 	if err := os.MkdirAll(ae.certsDir, 0777); err != nil {
 		return fmt.Errorf("error creating directory %s for storing TLS assets: %v", ae.certsDir, err)
 	}
@@ -289,6 +435,17 @@ func (ae *ansibleExecutor) Install(p *Plan, restartServices bool, nodes ...strin
 	if restartServices {
 		cc.EnableRestart()
 	}
+	if ae.options.Resume {
+		hash, err := planHash(withInventoryNodes(p))
+		if err != nil {
+			return err
+		}
+		completed, err := completedPlays(ae.options.RunsDirectory, "apply", hash)
+		if err != nil {
+			return fmt.Errorf("error resuming installation: %v", err)
+		}
+		cc.CompletedPlays = completed
+	}
 	t := task{
 		name:           "apply",
 		playbook:       "kubernetes.yaml",
@@ -299,6 +456,9 @@ func (ae *ansibleExecutor) Install(p *Plan, restartServices bool, nodes ...strin
 		limit:          nodes,
 	}
 	util.PrintHeader(ae.stdout, "Installing Cluster", '=')
+	if ae.options.Resume {
+		util.PrettyPrintOk(ae.stdout, "Resuming installation, skipping %d completed plays", len(cc.CompletedPlays))
+	}
 	return ae.execute(t)
 }
 
@@ -465,7 +625,7 @@ func (ae *ansibleExecutor) AddVolume(plan *Plan, volume StorageVolume) error {
 	allowedNodes = append(allowedNodes, plan.Storage.Nodes...)
 
 	allowed := volume.AllowAddresses
-	allowed = append(allowed, plan.Cluster.Networking.PodCIDRBlock)
+	allowed = append(allowed, cidrBlocks(plan.Cluster.Networking.PodCIDRBlock)...)
 	for _, n := range allowedNodes {
 		ip := n.IP
 		if n.InternalIP != "" {
@@ -609,7 +769,18 @@ func (ae *ansibleExecutor) upgradeNodes(plan Plan, onlineUpgrade bool, restartSe
 		util.PrintHeader(ae.stdout, "Upgrade Nodes:", '=')
 		util.PrintTable(ae.stdout, nodeRoles)
 	}
-	return ae.execute(t)
+	for _, n := range nodes {
+		ae.sendEvent(SinkEvent{Type: EventNodeUpgradeStarted, Cluster: plan.Cluster.Name, Node: n.Node.Host, Roles: n.Roles})
+	}
+	err = ae.execute(t)
+	for _, n := range nodes {
+		e := SinkEvent{Type: EventNodeUpgradeFinished, Cluster: plan.Cluster.Name, Node: n.Node.Host, Roles: n.Roles, Status: ae.runStatus(err)}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		ae.sendEvent(e)
+	}
+	return err
 }
 
 func (ae *ansibleExecutor) ValidateControlPlane(plan Plan) error {
@@ -684,7 +855,7 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 		AdminPassword:                 p.Cluster.AdminPassword,
 		TLSDirectory:                  tlsDir,
 		ServicesCIDR:                  p.Cluster.Networking.ServiceCIDRBlock,
-		PodCIDR:                       p.Cluster.Networking.PodCIDRBlock,
+		PodCIDR:                       p.Cluster.Networking.primaryPodCIDR(),
 		DNSServiceIP:                  dnsIP,
 		EnableModifyHosts:             p.Cluster.Networking.UpdateHostsFiles,
 		EnablePackageInstallation:     !p.Cluster.DisablePackageInstallation,
@@ -800,6 +971,8 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 		cc.CNI.Options.Calico.WorkloadMTU = p.AddOns.CNI.Options.Calico.WorkloadMTU
 		cc.CNI.Options.Calico.FelixInputMTU = p.AddOns.CNI.Options.Calico.FelixInputMTU
 		cc.CNI.Options.Calico.IPAutodetectionMethod = p.AddOns.CNI.Options.Calico.IPAutodetectionMethod
+		cc.CNI.Options.Calico.IP6AutodetectionMethod = p.AddOns.CNI.Options.Calico.IP6AutodetectionMethod
+		cc.CNI.Options.Calico.IPv4PoolCIDR, cc.CNI.Options.Calico.IPv6PoolCIDR = p.Cluster.Networking.podCIDRs()
 		// Weave
 		cc.CNI.Options.Weave.Password = p.AddOns.CNI.Options.Weave.Password
 		if cc.CNI.Provider == cniProviderContiv {
@@ -877,14 +1050,14 @@ func (ae *ansibleExecutor) buildClusterCatalog(p *Plan) (*ansible.ClusterCatalog
 
 func (ae *ansibleExecutor) createRunDirectory(runName string) (string, error) {
 	start := time.Now()
-	runDirectory := filepath.Join(ae.options.RunsDirectory, runName, start.Format("2006-01-02-15-04-05"))
+	runDirectory := filepath.Join(ae.options.RunsDirectory, runName, start.Format(runDirectoryTimeFormat))
 	if err := os.MkdirAll(runDirectory, 0777); err != nil {
 		return "", fmt.Errorf("error creating directory: %v", err)
 	}
 	return runDirectory, nil
 }
 
-func (ae *ansibleExecutor) ansibleRunnerWithExplainer(explainer explain.AnsibleEventExplainer, ansibleLog io.Writer, runDirectory string) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+func (ae *ansibleExecutor) ansibleRunnerWithExplainer(explainer explain.AnsibleEventExplainer, ansibleLog io.Writer, runDirectory string, becomePassword string, sshAuthSock string) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
 	if ae.runnerExplainerFactory != nil {
 		return ae.runnerExplainerFactory(explainer, ansibleLog)
 	}
@@ -899,7 +1072,7 @@ func (ae *ansibleExecutor) ansibleRunnerWithExplainer(explainer explain.AnsibleE
 	}
 
 	// Send stdout and stderr to ansibleOut
-	runner, err := ansible.NewRunner(ansibleOut, ansibleOut, ae.ansibleDir, runDirectory)
+	runner, err := ansible.NewRunner(ansibleOut, ansibleOut, ae.ansibleDir, runDirectory, becomePassword, sshAuthSock)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error creating ansible runner: %v", err)
 	}
@@ -912,6 +1085,9 @@ func (ae *ansibleExecutor) ansibleRunnerWithExplainer(explainer explain.AnsibleE
 }
 
 func (ae *ansibleExecutor) defaultExplainer() explain.AnsibleEventExplainer {
+	if ae.jsonExplainer != nil {
+		return ae.jsonExplainer
+	}
 	var out io.Writer
 	switch ae.consoleOutputFormat {
 	case ansible.JSONLinesFormat:
@@ -923,6 +1099,9 @@ func (ae *ansibleExecutor) defaultExplainer() explain.AnsibleEventExplainer {
 }
 
 func (ae *ansibleExecutor) preflightExplainer() explain.AnsibleEventExplainer {
+	if ae.jsonExplainer != nil {
+		return ae.jsonExplainer
+	}
 	var out io.Writer
 	switch ae.consoleOutputFormat {
 	case ansible.JSONLinesFormat:
@@ -933,6 +1112,66 @@ func (ae *ansibleExecutor) preflightExplainer() explain.AnsibleEventExplainer {
 	return explain.PreflightExplainer(ae.options.Verbose, out)
 }
 
+// sendEvent sends the event to the event sink, if there is one. Nothing is
+// sent on a dry run.
+func (ae *ansibleExecutor) sendEvent(e SinkEvent) {
+	if ae.options.EventSink == nil || ae.options.DryRun {
+		return
+	}
+	ae.options.EventSink.Send(e)
+}
+
+// phaseStarted marks the start of a phase in the json output
+func (ae *ansibleExecutor) phaseStarted(phase string) {
+	if ae.jsonExplainer != nil {
+		ae.jsonExplainer.PhaseStarted(phase)
+	}
+}
+
+// phaseFinished marks the end of a phase in the json output
+func (ae *ansibleExecutor) phaseFinished(phase string, err error) {
+	if ae.jsonExplainer != nil {
+		ae.jsonExplainer.PhaseFinished(phase, err)
+	}
+}
+
+// context returns the context of the executor, which is never done when the
+// options don't set one
+func (ae *ansibleExecutor) context() context.Context {
+	if ae.options.Context == nil {
+		return context.Background()
+	}
+	return ae.options.Context
+}
+
+// runStatus returns the status of a run that ended with the error
+func (ae *ansibleExecutor) runStatus(err error) string {
+	switch {
+	case err == nil:
+		return RunStatusSucceeded
+	case ae.context().Err() != nil:
+		return RunStatusInterrupted
+	default:
+		return RunStatusFailed
+	}
+}
+
+// consoleOutput returns the ansible output format, and the writer for human
+// readable output, of the given output format. The json output format writes
+// a JSON object per line to stdout, so human readable output goes to errOut.
+func consoleOutput(format string, stdout io.Writer, errOut io.Writer) (ansible.OutputFormat, io.Writer, *explain.JSONExplainer, error) {
+	switch format {
+	case "raw":
+		return ansible.RawFormat, stdout, nil, nil
+	case "simple":
+		return ansible.JSONLinesFormat, stdout, nil, nil
+	case "json":
+		return ansible.JSONLinesFormat, errOut, explain.NewJSONExplainer(stdout), nil
+	default:
+		return "", nil, nil, fmt.Errorf("Output format %q is not supported", format)
+	}
+}
+
 func buildInventoryFromPlan(p *Plan) ansible.Inventory {
 	etcdNodes := []ansible.Node{}
 	for _, n := range p.Etcd.Nodes {
@@ -987,9 +1226,91 @@ func buildInventoryFromPlan(p *Plan) ansible.Inventory {
 	return inventory
 }
 
+// KnownHostsFile returns the path to the known_hosts file, where the host
+// keys of the nodes are pinned, in the generated assets directory
+func KnownHostsFile(generatedAssetsDir string) string {
+	return filepath.Join(generatedAssetsDir, "known_hosts")
+}
+
+// pinHostKeys pins the host keys of the nodes in the inventory that are not
+// known yet, so that ansible can verify them against the known_hosts file
+func pinHostKeys(inventory ansible.Inventory, bastion *ssh.Bastion) error {
+	addresses := setKnownHostsFile(inventory)
+	if len(addresses) == 0 {
+		return nil
+	}
+	return ssh.PinHostKeys(addresses, bastion)
+}
+
+// setKnownHostsFile sets the known_hosts file that ansible verifies the host
+// keys of the nodes in the inventory against, and returns the addresses of the
+// nodes. Returns nil if host keys are not verified.
+func setKnownHostsFile(inventory ansible.Inventory) []string {
+	knownHosts := ssh.KnownHostsFile()
+	if knownHosts == "" {
+		return nil
+	}
+	addresses := []string{}
+	seen := map[string]bool{}
+	for _, role := range inventory.Roles {
+		for i := range role.Nodes {
+			n := &role.Nodes[i]
+			n.SSHKnownHostsFile = knownHosts
+			addr := net.JoinHostPort(n.PublicIP, strconv.Itoa(n.SSHPort))
+			if !seen[addr] {
+				seen[addr] = true
+				addresses = append(addresses, addr)
+			}
+		}
+	}
+	return addresses
+}
+
+// startSSHAgent serves the keys of the inventory that are protected by a
+// passphrase with an agent, as the ssh processes of ansible cannot prompt for
+// the passphrases. The keys are removed from the inventory, so that ansible
+// authenticates with the agent. Returns nil if no key is encrypted.
+func startSSHAgent(inventory ansible.Inventory) (*ssh.Agent, error) {
+	keys := removeEncryptedKeys(inventory)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return ssh.StartAgent(keys)
+}
+
+// removeEncryptedKeys removes the keys that are protected by a passphrase
+// from the inventory, and returns them
+func removeEncryptedKeys(inventory ansible.Inventory) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	encrypted := func(key string) bool {
+		if key == "" || !ssh.EncryptedKey(key) {
+			return false
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+		return true
+	}
+	for _, role := range inventory.Roles {
+		for i := range role.Nodes {
+			n := &role.Nodes[i]
+			if encrypted(n.SSHPrivateKey) {
+				n.SSHPrivateKey = ""
+			}
+			if n.Bastion != nil && encrypted(n.Bastion.SSHPrivateKey) {
+				n.Bastion = &ansible.Bastion{Host: n.Bastion.Host, SSHPort: n.Bastion.SSHPort, SSHUser: n.Bastion.SSHUser}
+			}
+		}
+	}
+	return keys
+}
+
 // Converts plan node to ansible node
-func installNodeToAnsibleNode(n *Node, s *SSHConfig) ansible.Node {
-	return ansible.Node{
+func installNodeToAnsibleNode(n *Node, clusterSSH *SSHConfig) ansible.Node {
+	s := clusterSSH.ForNode(*n)
+	node := ansible.Node{
 		Host:          n.Host,
 		PublicIP:      n.IP,
 		InternalIP:    n.InternalIP,
@@ -997,6 +1318,15 @@ func installNodeToAnsibleNode(n *Node, s *SSHConfig) ansible.Node {
 		SSHUser:       s.User,
 		SSHPort:       s.Port,
 	}
+	if b := s.BastionHost(); b != nil {
+		node.Bastion = &ansible.Bastion{
+			Host:          b.Host,
+			SSHPort:       b.Port,
+			SSHUser:       b.User,
+			SSHPrivateKey: b.Key,
+		}
+	}
+	return node
 }
 
 // Prepend each line of the incoming stream with a timestamp
@@ -0,0 +1,166 @@
+package explain
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// The types of the objects written by the JSON explainer
+const (
+	JSONPlaybookStart     = "playbook_start"
+	JSONPlaybookEnd       = "playbook_end"
+	JSONPlayStart         = "play_start"
+	JSONTaskStart         = "task_start"
+	JSONHandlerTaskStart  = "handler_task_start"
+	JSONRunnerOK          = "runner_ok"
+	JSONRunnerFailed      = "runner_failed"
+	JSONRunnerSkipped     = "runner_skipped"
+	JSONRunnerUnreachable = "runner_unreachable"
+	JSONRunnerItemOK      = "runner_item_ok"
+	JSONRunnerItemFailed  = "runner_item_failed"
+	JSONRunnerItemRetry   = "runner_item_retry"
+	JSONPhaseStart        = "phase_start"
+	JSONPhaseEnd          = "phase_end"
+)
+
+// The status of a phase that ended
+const (
+	PhaseSucceeded = "succeeded"
+	PhaseFailed    = "failed"
+)
+
+// JSONEvent is an ansible event, or a kismatic phase marker, as written
+// by the JSON explainer
+type JSONEvent struct {
+	Time string `json:"time"`
+	Type string `json:"type"`
+	// Phase is the kismatic phase that started or ended
+	Phase  string `json:"phase,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	Playbook     string   `json:"playbook,omitempty"`
+	Play         string   `json:"play,omitempty"`
+	Task         string   `json:"task,omitempty"`
+	Host         string   `json:"host,omitempty"`
+	Message      string   `json:"message,omitempty"`
+	Command      []string `json:"command,omitempty"`
+	Stdout       string   `json:"stdout,omitempty"`
+	Stderr       string   `json:"stderr,omitempty"`
+	Item         string   `json:"item,omitempty"`
+	Attempts     int      `json:"attempts,omitempty"`
+	MaxRetries   int      `json:"max_retries,omitempty"`
+	IgnoreErrors bool     `json:"ignore_errors,omitempty"`
+}
+
+// JSONExplainer writes every ansible event, and the start and end of
+// kismatic phases, as a JSON object per line
+type JSONExplainer struct {
+	emit func(JSONEvent)
+
+	mu       sync.Mutex
+	playbook string
+	play     string
+	task     string
+}
+
+// NewJSONExplainer returns a JSON explainer that writes to out
+func NewJSONExplainer(out io.Writer) *JSONExplainer {
+	return &JSONExplainer{
+		emit: func(j JSONEvent) {
+			b, err := json.Marshal(j)
+			if err != nil {
+				return
+			}
+			out.Write(append(b, '\n'))
+		},
+	}
+}
+
+// NewJSONEventExplainer returns a JSON explainer that passes the events to
+// emit, instead of writing them
+func NewJSONEventExplainer(emit func(JSONEvent)) *JSONExplainer {
+	return &JSONExplainer{emit: emit}
+}
+
+// ExplainEvent writes the ansible event as a JSON object
+func (e *JSONExplainer) ExplainEvent(ev ansible.Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	j := JSONEvent{}
+	switch event := ev.(type) {
+	case *ansible.PlaybookStartEvent:
+		e.playbook, e.play, e.task = event.Name, "", ""
+		j.Type = JSONPlaybookStart
+	case *ansible.PlaybookEndEvent:
+		j.Type = JSONPlaybookEnd
+	case *ansible.PlayStartEvent:
+		e.play, e.task = event.Name, ""
+		j.Type = JSONPlayStart
+	case *ansible.TaskStartEvent:
+		e.task = event.Name
+		j.Type = JSONTaskStart
+	case *ansible.HandlerTaskStartEvent:
+		e.task = event.Name
+		j.Type = JSONHandlerTaskStart
+	case *ansible.RunnerOKEvent:
+		j.Type = JSONRunnerOK
+	case *ansible.RunnerFailedEvent:
+		j.Type = JSONRunnerFailed
+	case *ansible.RunnerSkippedEvent:
+		j.Type = JSONRunnerSkipped
+	case *ansible.RunnerUnreachableEvent:
+		j.Type = JSONRunnerUnreachable
+	case *ansible.RunnerItemOKEvent:
+		j.Type = JSONRunnerItemOK
+	case *ansible.RunnerItemFailedEvent:
+		j.Type = JSONRunnerItemFailed
+	case *ansible.RunnerItemRetryEvent:
+		j.Type = JSONRunnerItemRetry
+	default:
+		return
+	}
+	if r, ok := ev.(ansible.ResultEvent); ok {
+		host, result, ignoreErrors := r.HostResult()
+		j.Host = host
+		j.Message = result.Message
+		j.Command = result.Command
+		j.Stdout = result.Stdout
+		j.Stderr = result.Stderr
+		j.Item = result.Item
+		j.Attempts = result.Attempts
+		j.MaxRetries = result.MaxRetries
+		j.IgnoreErrors = ignoreErrors
+	}
+	j.Playbook, j.Play, j.Task = e.playbook, e.play, e.task
+	e.write(j)
+}
+
+// PhaseStarted writes the marker of the start of a kismatic phase
+func (e *JSONExplainer) PhaseStarted(phase string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.write(JSONEvent{Type: JSONPhaseStart, Phase: phase})
+}
+
+// PhaseFinished writes the marker of the end of a kismatic phase, and
+// whether it failed
+func (e *JSONExplainer) PhaseFinished(phase string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	j := JSONEvent{Type: JSONPhaseEnd, Phase: phase, Status: PhaseSucceeded}
+	if err != nil {
+		j.Status = PhaseFailed
+		j.Error = err.Error()
+	}
+	e.write(j)
+}
+
+func (e *JSONExplainer) write(j JSONEvent) {
+	j.Time = time.Now().Format(time.RFC3339Nano)
+	e.emit(j)
+}
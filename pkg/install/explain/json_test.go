@@ -0,0 +1,80 @@
+package explain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestJSONExplainer(t *testing.T) {
+	out := &bytes.Buffer{}
+	e := NewJSONExplainer(out)
+
+	playbookStart := &ansible.PlaybookStartEvent{}
+	playbookStart.Name = "kubernetes.yaml"
+	playStart := &ansible.PlayStartEvent{}
+	playStart.Name = "Start etcd"
+	taskStart := &ansible.TaskStartEvent{}
+	taskStart.Name = "start etcd"
+	ok := &ansible.RunnerOKEvent{}
+	ok.Host = "etcd01"
+	retry := &ansible.RunnerItemRetryEvent{}
+	retry.Host = "etcd02"
+	retry.Result.Attempts = 1
+	retry.Result.MaxRetries = 3
+	failed := &ansible.RunnerFailedEvent{}
+	failed.Host = "etcd02"
+	failed.Result.Message = "etcd did not start"
+
+	e.PhaseStarted("certificates")
+	e.PhaseFinished("certificates", nil)
+	e.PhaseStarted("apply")
+	for _, ev := range []ansible.Event{playbookStart, playStart, taskStart, ok, retry, failed} {
+		e.ExplainEvent(ev)
+	}
+	e.PhaseFinished("apply", errors.New("error running playbook"))
+
+	expected := []JSONEvent{
+		{Type: JSONPhaseStart, Phase: "certificates"},
+		{Type: JSONPhaseEnd, Phase: "certificates", Status: PhaseSucceeded},
+		{Type: JSONPhaseStart, Phase: "apply"},
+		{Type: JSONPlaybookStart, Playbook: "kubernetes.yaml"},
+		{Type: JSONPlayStart, Playbook: "kubernetes.yaml", Play: "Start etcd"},
+		{Type: JSONTaskStart, Playbook: "kubernetes.yaml", Play: "Start etcd", Task: "start etcd"},
+		{Type: JSONRunnerOK, Playbook: "kubernetes.yaml", Play: "Start etcd", Task: "start etcd", Host: "etcd01"},
+		{Type: JSONRunnerItemRetry, Playbook: "kubernetes.yaml", Play: "Start etcd", Task: "start etcd", Host: "etcd02", Attempts: 1, MaxRetries: 3},
+		{Type: JSONRunnerFailed, Playbook: "kubernetes.yaml", Play: "Start etcd", Task: "start etcd", Host: "etcd02", Message: "etcd did not start"},
+		{Type: JSONPhaseEnd, Phase: "apply", Status: PhaseFailed, Error: "error running playbook"},
+	}
+	scanner := bufio.NewScanner(out)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		got := JSONEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+			t.Fatalf("line %d is not a JSON object: %v", i, err)
+		}
+		if got.Time == "" {
+			t.Errorf("line %d does not have a time", i)
+		}
+		got.Time = ""
+		if i >= len(expected) {
+			continue
+		}
+		if !equalJSONEvents(got, expected[i]) {
+			t.Errorf("line %d: expected %+v, got %+v", i, expected[i], got)
+		}
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d lines, got %d", len(expected), i)
+	}
+}
+
+func equalJSONEvents(a, b JSONEvent) bool {
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	return bytes.Equal(ab, bb)
+}
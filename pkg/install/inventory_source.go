@@ -0,0 +1,200 @@
+package install
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	inventorySourceCSV  = "csv"
+	inventorySourceJSON = "json"
+	inventorySourceExec = "exec"
+)
+
+func inventorySourceTypes() []string {
+	return []string{inventorySourceCSV, inventorySourceJSON, inventorySourceExec}
+}
+
+// inventoryNode is a node as it is listed by an inventory source
+type inventoryNode struct {
+	Host       string            `json:"host"`
+	IP         string            `json:"ip"`
+	InternalIP string            `json:"internalip"`
+	Labels     map[string]string `json:"labels"`
+}
+
+// inventoryGroup is a node group that can be populated by an inventory source
+type inventoryGroup struct {
+	name          string
+	source        *InventorySource
+	expectedCount int
+	nodes         *[]Node
+}
+
+func inventoryGroups(p *Plan) []inventoryGroup {
+	return []inventoryGroup{
+		{name: "etcd", source: p.Etcd.InventorySource, expectedCount: p.Etcd.ExpectedCount, nodes: &p.Etcd.Nodes},
+		{name: "master", source: p.Master.InventorySource, expectedCount: p.Master.ExpectedCount, nodes: &p.Master.Nodes},
+		{name: "worker", source: p.Worker.InventorySource, expectedCount: p.Worker.ExpectedCount, nodes: &p.Worker.Nodes},
+		{name: "ingress", source: p.Ingress.InventorySource, expectedCount: p.Ingress.ExpectedCount, nodes: &p.Ingress.Nodes},
+		{name: "storage", source: p.Storage.InventorySource, expectedCount: p.Storage.ExpectedCount, nodes: &p.Storage.Nodes},
+	}
+}
+
+// HasInventorySource returns true if the nodes of the node group are read
+// from an inventory source
+func (p *Plan) HasInventorySource(group string) bool {
+	for _, g := range inventoryGroups(p) {
+		if g.name == group {
+			return g.source != nil
+		}
+	}
+	return false
+}
+
+// loadInventorySources populates the node groups that have an inventory source
+func loadInventorySources(p *Plan) error {
+	for _, g := range inventoryGroups(p) {
+		if g.source == nil {
+			continue
+		}
+		if len(*g.nodes) > 0 {
+			return fmt.Errorf("%s nodes cannot be listed in the plan file when an inventory source is set", g.name)
+		}
+		nodes, err := g.source.nodes(g.name)
+		if err != nil {
+			return fmt.Errorf("error reading %s nodes from inventory source %q: %v", g.name, g.source.Path, err)
+		}
+		if len(nodes) != g.expectedCount {
+			return fmt.Errorf("inventory source %q returned %d %s nodes, but the expected count is %d", g.source.Path, len(nodes), g.name, g.expectedCount)
+		}
+		*g.nodes = nodes
+	}
+	return nil
+}
+
+// withoutInventoryNodes returns a copy of the plan where the node groups that
+// are populated by an inventory source don't list their nodes.
+func withoutInventoryNodes(p *Plan) *Plan {
+	c := *p
+	for _, g := range inventoryGroups(&c) {
+		if g.source != nil {
+			*g.nodes = []Node{}
+		}
+	}
+	return &c
+}
+
+// withInventoryNodes returns a copy of the plan where the nodes that were read
+// from inventory sources are listed in the node groups, and the inventory
+// sources are removed.
+func withInventoryNodes(p *Plan) *Plan {
+	c := *p
+	c.Etcd.InventorySource = nil
+	c.Master.InventorySource = nil
+	c.Worker.InventorySource = nil
+	c.Ingress.InventorySource = nil
+	c.Storage.InventorySource = nil
+	return &c
+}
+
+func (s InventorySource) nodes(group string) ([]Node, error) {
+	switch s.Type {
+	case inventorySourceCSV:
+		f, err := os.Open(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readCSVInventory(f)
+	case inventorySourceJSON:
+		f, err := os.Open(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readJSONInventory(f)
+	case inventorySourceExec:
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(s.Path, s.Args...)
+		cmd.Env = append(os.Environ(), "KISMATIC_NODE_GROUP="+group)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("error running executable: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return readJSONInventory(&stdout)
+	}
+	return nil, fmt.Errorf("inventory source type %q is not supported. Supported types are %v", s.Type, inventorySourceTypes())
+}
+
+func readJSONInventory(r io.Reader) ([]Node, error) {
+	invNodes := []inventoryNode{}
+	if err := json.NewDecoder(r).Decode(&invNodes); err != nil {
+		return nil, fmt.Errorf("error decoding nodes: %v", err)
+	}
+	nodes := []Node{}
+	for _, n := range invNodes {
+		nodes = append(nodes, Node{Host: n.Host, IP: n.IP, InternalIP: n.InternalIP, Labels: n.Labels})
+	}
+	return nodes, nil
+}
+
+func readCSVInventory(r io.Reader) ([]Node, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the header row is missing")
+	}
+	columns := map[string]int{}
+	for i, c := range records[0] {
+		c = strings.ToLower(strings.TrimSpace(c))
+		switch c {
+		case "host", "ip", "internalip", "labels":
+			columns[c] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q", c)
+		}
+	}
+	if _, ok := columns["host"]; !ok {
+		return nil, fmt.Errorf("the host column is missing")
+	}
+	if _, ok := columns["ip"]; !ok {
+		return nil, fmt.Errorf("the ip column is missing")
+	}
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	nodes := []Node{}
+	for line, record := range records[1:] {
+		n := Node{
+			Host:       value(record, "host"),
+			IP:         value(record, "ip"),
+			InternalIP: value(record, "internalip"),
+		}
+		if labels := value(record, "labels"); labels != "" {
+			n.Labels = map[string]string{}
+			for _, l := range strings.Split(labels, ";") {
+				kv := strings.SplitN(l, "=", 2)
+				if len(kv) != 2 {
+					return nil, fmt.Errorf("line %d: label %q is not formatted as key=value", line+2, l)
+				}
+				n.Labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
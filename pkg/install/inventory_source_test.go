@@ -0,0 +1,130 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVInventory(t *testing.T) {
+	tests := []struct {
+		csv         string
+		expected    []Node
+		shouldError bool
+	}{
+		{
+			csv: "host,ip,internalip,labels\nworker01,10.0.0.1,192.168.0.1,env=prod;tier=web\nworker02,10.0.0.2,,\n",
+			expected: []Node{
+				{Host: "worker01", IP: "10.0.0.1", InternalIP: "192.168.0.1", Labels: map[string]string{"env": "prod", "tier": "web"}},
+				{Host: "worker02", IP: "10.0.0.2"},
+			},
+		},
+		{
+			// columns can be in any order
+			csv:      "ip,host\n10.0.0.1,worker01\n",
+			expected: []Node{{Host: "worker01", IP: "10.0.0.1"}},
+		},
+		{
+			csv:         "host\nworker01\n",
+			shouldError: true,
+		},
+		{
+			csv:         "host,ip,foo\nworker01,10.0.0.1,bar\n",
+			shouldError: true,
+		},
+		{
+			csv:         "host,ip,labels\nworker01,10.0.0.1,env\n",
+			shouldError: true,
+		},
+		{
+			csv:         "",
+			shouldError: true,
+		},
+	}
+	for i, test := range tests {
+		nodes, err := readCSVInventory(strings.NewReader(test.csv))
+		if err != nil && !test.shouldError {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if err == nil && test.shouldError {
+			t.Errorf("test %d: expected an error, but didn't get one", i)
+		}
+		if err == nil && !reflect.DeepEqual(nodes, test.expected) {
+			t.Errorf("test %d: expected %+v, but got %+v", i, test.expected, nodes)
+		}
+	}
+}
+
+func TestFilePlannerInventorySources(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-inventory")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	files := map[string]string{
+		"workers.json": `[{"host": "worker01", "ip": "10.0.0.2", "labels": {"env": "prod"}}, {"host": "worker02", "ip": "10.0.0.3"}]`,
+		"inventory.sh": "#!/bin/sh\necho \"[{\\\"host\\\": \\\"$KISMATIC_NODE_GROUP-01\\\", \\\"ip\\\": \\\"10.0.0.1\\\"}]\"\n",
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(tmp, name), []byte(content), 0755); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+	}
+	plan := `apiVersion: v1
+master:
+  expected_count: 1
+  inventory_source:
+    type: exec
+    path: ` + filepath.Join(tmp, "inventory.sh") + `
+worker:
+  expected_count: 2
+  inventory_source:
+    type: json
+    path: ` + filepath.Join(tmp, "workers.json") + `
+`
+	file := filepath.Join(tmp, "kismatic-cluster.yaml")
+	if err = ioutil.WriteFile(file, []byte(plan), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	fp := &FilePlanner{File: file}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	expectedMasters := []Node{{Host: "master-01", IP: "10.0.0.1"}}
+	if !reflect.DeepEqual(p.Master.Nodes, expectedMasters) {
+		t.Errorf("expected masters %+v, got %+v", expectedMasters, p.Master.Nodes)
+	}
+	expectedWorkers := []Node{
+		{Host: "worker01", IP: "10.0.0.2", Labels: map[string]string{"env": "prod"}},
+		{Host: "worker02", IP: "10.0.0.3"},
+	}
+	if !reflect.DeepEqual(p.Worker.Nodes, expectedWorkers) {
+		t.Errorf("expected workers %+v, got %+v", expectedWorkers, p.Worker.Nodes)
+	}
+
+	// nodes read from inventory sources are not written to the plan file
+	out := &FilePlanner{File: filepath.Join(tmp, "written.yaml")}
+	if err = out.Write(p); err != nil {
+		t.Fatalf("unexpected error writing plan: %v", err)
+	}
+	written, err := out.Read()
+	if err != nil {
+		t.Fatalf("unexpected error reading written plan: %v", err)
+	}
+	if !reflect.DeepEqual(written.Worker.Nodes, expectedWorkers) || written.Worker.InventorySource == nil {
+		t.Errorf("expected written plan to keep the inventory source")
+	}
+
+	// the expected count is checked against the inventory
+	p.Worker.ExpectedCount = 3
+	if err = out.Write(p); err != nil {
+		t.Fatalf("unexpected error writing plan: %v", err)
+	}
+	if _, err = out.Read(); err == nil {
+		t.Errorf("expected an error when the expected count does not match the inventory")
+	}
+}
@@ -0,0 +1,192 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// clusterLockFile is the file in the generated assets directory that holds
+// the lock of the cluster
+const clusterLockFile = "kismatic.lock"
+
+// ClusterLock is an advisory lock on the generated assets directory of a
+// cluster. It is held by the commands that change the cluster, so that two
+// of them don't run against the same cluster at the same time.
+type ClusterLock struct {
+	// Owner is the user and the host the command was run by
+	Owner   string    `yaml:"owner"`
+	PID     int       `yaml:"pid"`
+	Command string    `yaml:"command"`
+	Start   time.Time `yaml:"start"`
+	file    string
+}
+
+// ClusterLockedError is returned when the cluster is locked by another command
+type ClusterLockedError struct {
+	File string
+	Lock ClusterLock
+}
+
+func (e ClusterLockedError) Error() string {
+	return fmt.Sprintf("the cluster is locked by %q, run by %s with PID %d since %s. "+
+		"Wait for the command to finish, or if it is not running anymore, remove the lock in %q with --force-unlock",
+		e.Lock.Command, e.Lock.Owner, e.Lock.PID, e.Lock.Start.Format(time.RFC3339), e.File)
+}
+
+// LockCluster takes the lock of the cluster whose assets are generated in
+// the directory. Returns a ClusterLockedError if the lock is held by another
+// command.
+func LockCluster(generatedAssetsDir string, command string) (*ClusterLock, error) {
+	if err := os.MkdirAll(generatedAssetsDir, 0777); err != nil {
+		return nil, fmt.Errorf("error creating directory %q: %v", generatedAssetsDir, err)
+	}
+	file := filepath.Join(generatedAssetsDir, clusterLockFile)
+	l := &ClusterLock{
+		Owner:   lockOwner(),
+		PID:     os.Getpid(),
+		Command: command,
+		Start:   time.Now(),
+		file:    file,
+	}
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling cluster lock: %v", err)
+	}
+	// The lock is written to a temporary file, that is linked to the lock
+	// file. Linking fails if the lock file exists, and other commands never
+	// see a lock that is not completely written.
+	f, err := ioutil.TempFile(generatedAssetsDir, "."+clusterLockFile)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cluster lock: %v", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing cluster lock: %v", err)
+	}
+	err = os.Link(f.Name(), file)
+	if os.IsExist(err) {
+		held, readErr := readClusterLock(file)
+		if readErr != nil {
+			return nil, fmt.Errorf("the cluster is locked, but the lock cannot be read: %v. Remove the lock with --force-unlock", readErr)
+		}
+		return nil, ClusterLockedError{File: file, Lock: *held}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating cluster lock %q: %v", file, err)
+	}
+	return l, nil
+}
+
+// Release releases the lock of the cluster. The lock is left alone if it was
+// removed with ForceUnlockCluster, and taken by another command since.
+func (l *ClusterLock) Release() error {
+	held, err := readClusterLock(l.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if held.PID != l.PID || !held.Start.Equal(l.Start) {
+		return nil
+	}
+	return l.remove()
+}
+
+func (l *ClusterLock) remove() error {
+	if err := os.Remove(l.file); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing cluster lock %q: %v", l.file, err)
+	}
+	return nil
+}
+
+// ForceUnlockCluster removes the lock of the cluster, even though it is held
+// by another command. It is used to remove the lock of a command that did not
+// exit cleanly, and refuses to remove the lock of a command that is still
+// running on this host. Returns the lock that was removed, or nil if the
+// cluster was not locked.
+func ForceUnlockCluster(generatedAssetsDir string) (*ClusterLock, error) {
+	file := filepath.Join(generatedAssetsDir, clusterLockFile)
+	l, err := readClusterLock(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		// a lock that cannot be read is removed as well
+		l = &ClusterLock{Owner: "unknown", Command: "unknown", file: file}
+	}
+	if l.runningOnThisHost() {
+		return nil, fmt.Errorf("the cluster is locked by %q, run by %s with PID %d, which is still running on this host. "+
+			"Wait for the command to finish, or stop it before removing the lock", l.Command, l.Owner, l.PID)
+	}
+	if err := l.remove(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func readClusterLock(file string) (*ClusterLock, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error reading cluster lock %q: %v", file, err)
+	}
+	l := &ClusterLock{file: file}
+	if err := yaml.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("error unmarshaling cluster lock %q: %v", file, err)
+	}
+	return l, nil
+}
+
+// runningOnThisHost returns true if the lock was taken on this host, by a
+// process that is still running
+func (l *ClusterLock) runningOnThisHost() bool {
+	return onThisHost(l.Owner) && processRunning(l.PID)
+}
+
+// onThisHost returns true if the user@host owner is on this host
+func onThisHost(owner string) bool {
+	host, err := os.Hostname()
+	return err == nil && owner[strings.LastIndex(owner, "@")+1:] == host
+}
+
+// processRunning returns true if the process with the PID is running on this
+// host
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	// signal 0 checks that the process exists, without signaling it
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// lockOwner returns user@host of the current process
+func lockOwner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return name + "@" + host
+}
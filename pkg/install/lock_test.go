@@ -0,0 +1,162 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// changeClusterLock rewrites the lock of the cluster
+func changeClusterLock(t *testing.T, dir string, change func(l *ClusterLock)) {
+	file := filepath.Join(dir, clusterLockFile)
+	l, err := readClusterLock(file)
+	if err != nil {
+		t.Fatalf("error reading lock: %v", err)
+	}
+	change(l)
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		t.Fatalf("error marshaling lock: %v", err)
+	}
+	if err = ioutil.WriteFile(file, b, 0644); err != nil {
+		t.Fatalf("error writing lock: %v", err)
+	}
+}
+
+// exitedPID returns the PID of a process that exited
+func exitedPID(t *testing.T) int {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error running process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestLockCluster(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-lock")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "generated")
+
+	lock, err := LockCluster(dir, "kismatic install apply")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lock.PID != os.Getpid() || lock.Owner == "" {
+		t.Errorf("expected the lock to record the owner and the PID, got %+v", lock)
+	}
+
+	// conflicting commands refuse to start
+	_, err = LockCluster(dir, "kismatic upgrade online")
+	lockedErr, ok := err.(ClusterLockedError)
+	if !ok {
+		t.Fatalf("expected a ClusterLockedError, got %v", err)
+	}
+	if lockedErr.Lock.Command != "kismatic install apply" || lockedErr.Lock.PID != os.Getpid() || !lockedErr.Lock.Start.Equal(lock.Start) {
+		t.Errorf("expected the error to describe the lock that is held, got %+v", lockedErr.Lock)
+	}
+
+	// the lock can be taken once released
+	if err = lock.Release(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock, err = LockCluster(dir, "kismatic upgrade online")
+	if err != nil {
+		t.Fatalf("unexpected error taking the released lock: %v", err)
+	}
+
+	// the lock of a command that is running on this host is not removed
+	if _, err = ForceUnlockCluster(dir); err == nil {
+		t.Errorf("expected the lock of a running command to be kept")
+	}
+
+	// a stale lock can be removed
+	changeClusterLock(t, dir, func(l *ClusterLock) { l.PID = exitedPID(t) })
+	stale, err := ForceUnlockCluster(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stale == nil || stale.Command != "kismatic upgrade online" {
+		t.Errorf("expected the removed lock to be returned, got %+v", stale)
+	}
+	if stale, err = ForceUnlockCluster(dir); stale != nil || err != nil {
+		t.Errorf("expected nothing to be removed when the cluster is not locked, got %+v: %v", stale, err)
+	}
+	if _, err = LockCluster(dir, "kismatic install apply"); err != nil {
+		t.Errorf("unexpected error taking the lock after removing it: %v", err)
+	}
+	// releasing a lock that was removed leaves the lock of the new owner
+	if err = lock.Release(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err = LockCluster(dir, "kismatic upgrade online"); err == nil {
+		t.Errorf("expected the lock of the new owner to be kept")
+	}
+}
+
+func TestForceUnlockClusterOfAnotherHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-lock")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err = LockCluster(dir, "kismatic install apply"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the PID is running on this host, but the lock was taken on another one
+	changeClusterLock(t, dir, func(l *ClusterLock) { l.Owner = "kismatic@another-host.example.com" })
+	stale, err := ForceUnlockCluster(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stale == nil || stale.Owner != "kismatic@another-host.example.com" {
+		t.Errorf("expected the lock of the other host to be removed, got %+v", stale)
+	}
+}
+
+func TestLockClusterConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-lock")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	errs := make([]error, 20)
+	var wg sync.WaitGroup
+	wg.Add(len(errs))
+	for i := range errs {
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = LockCluster(dir, "kismatic install apply")
+		}(i)
+	}
+	wg.Wait()
+	var locked int
+	for _, err := range errs {
+		if err == nil {
+			locked++
+			continue
+		}
+		// the commands that lost see the complete lock
+		if _, ok := err.(ClusterLockedError); !ok {
+			t.Errorf("expected a ClusterLockedError, got %v", err)
+		}
+	}
+	if locked != 1 {
+		t.Errorf("expected a single command to take the lock, got %d", locked)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != clusterLockFile {
+		t.Errorf("expected only the lock file to be left, got %d files", len(files))
+	}
+}
@@ -0,0 +1,62 @@
+package install
+
+import (
+	"net"
+	"strings"
+)
+
+// cidrBlocks returns the CIDR blocks of a comma-separated list of CIDR blocks
+func cidrBlocks(s string) []string {
+	blocks := []string{}
+	for _, b := range strings.Split(s, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// isIPv6 returns true if the IP address is an IPv6 address
+func isIPv6(ip net.IP) bool {
+	return ip != nil && ip.To4() == nil
+}
+
+// isIPv6CIDR returns true if the CIDR block is an IPv6 CIDR block
+func isIPv6CIDR(cidr string) bool {
+	ip, _, err := net.ParseCIDR(cidr)
+	return err == nil && isIPv6(ip)
+}
+
+// isIPv6Address returns true if the string is an IPv6 address
+func isIPv6Address(s string) bool {
+	return isIPv6(net.ParseIP(s))
+}
+
+// primaryPodCIDR returns the pod CIDR block that is used by Kubernetes.
+// When the cluster is dual-stack, this is the first CIDR block of the list.
+func (n NetworkConfig) primaryPodCIDR() string {
+	blocks := cidrBlocks(n.PodCIDRBlock)
+	if len(blocks) == 0 {
+		return ""
+	}
+	return blocks[0]
+}
+
+// podCIDRs returns the IPv4 and IPv6 pod CIDR blocks. Either is empty when the
+// cluster does not use the IP family.
+func (n NetworkConfig) podCIDRs() (ipv4 string, ipv6 string) {
+	for _, b := range cidrBlocks(n.PodCIDRBlock) {
+		if isIPv6CIDR(b) {
+			ipv6 = b
+		} else {
+			ipv4 = b
+		}
+	}
+	return ipv4, ipv6
+}
+
+// IPv6Enabled returns true if the cluster uses IPv6 for pod or service networking
+func (n NetworkConfig) IPv6Enabled() bool {
+	_, ipv6 := n.podCIDRs()
+	return ipv6 != "" || isIPv6CIDR(n.ServiceCIDRBlock)
+}
@@ -0,0 +1,165 @@
+package install
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// dockerBridgeCIDR is the CIDR block of the default docker0 bridge, which is
+// created on all nodes.
+const dockerBridgeCIDR = "172.17.0.0/16"
+
+// namedNetwork is a network that addresses of the cluster must not collide with
+type namedNetwork struct {
+	name string
+	cidr *net.IPNet
+}
+
+func (n namedNetwork) String() string {
+	return fmt.Sprintf("%s %q", n.name, n.cidr.String())
+}
+
+// networkTopology cross-checks the networks, the node addresses, the load
+// balancer and the proxies of the cluster for overlaps and addresses that are
+// not routable from the nodes.
+type networkTopology struct {
+	Plan *Plan
+}
+
+func (t networkTopology) validate() (bool, []error) {
+	v := newValidator()
+	p := t.Plan
+	networks := t.clusterNetworks()
+
+	// The cluster networks must not overlap each other, or the docker bridge
+	_, bridge, _ := net.ParseCIDR(dockerBridgeCIDR)
+	for i, a := range networks {
+		for _, b := range networks[i+1:] {
+			if networksOverlap(a.cidr, b.cidr) {
+				v.addError(fmt.Errorf("The %s overlaps with the %s", a, b))
+			}
+		}
+		if networksOverlap(a.cidr, bridge) {
+			v.addError(fmt.Errorf("The %s overlaps with the Docker bridge network %q", a, dockerBridgeCIDR))
+		}
+	}
+	reserved := append(networks, namedNetwork{name: "Docker bridge network", cidr: bridge})
+
+	// Node addresses must be routable on the nodes' network
+	for _, n := range p.GetUniqueNodes() {
+		for _, addr := range []string{n.IP, n.InternalIP} {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				continue
+			}
+			if r, ok := containingNetwork(reserved, ip); ok {
+				v.addError(fmt.Errorf("Node %q: IP %q is in the %s", n.Host, addr, r))
+			}
+		}
+	}
+
+	// The load balancer must be reachable from all nodes
+	lbHost, _, lbErr := p.ClusterAddress()
+	if ip := net.ParseIP(lbHost); lbErr == nil && ip != nil {
+		if ip.IsLoopback() || ip.IsUnspecified() {
+			v.addError(fmt.Errorf("Load balancer IP %q is not reachable from the other nodes", lbHost))
+		} else if r, ok := containingNetwork(reserved, ip); ok {
+			v.addError(fmt.Errorf("Load balancer IP %q is in the %s", lbHost, r))
+		} else if _, service, err := net.ParseCIDR(p.Cluster.Networking.ServiceCIDRBlock); err == nil && isIPv6(ip) != isIPv6(service.IP) {
+			v.addError(fmt.Errorf("Load balancer IP %q must be of the same IP family as the Service CIDR block %q", lbHost, p.Cluster.Networking.ServiceCIDRBlock))
+		}
+	}
+
+	// Proxies must be reachable from all nodes, and must not receive the
+	// requests to the load balancer
+	nc := p.Cluster.Networking
+	proxies := []struct {
+		name string
+		url  string
+	}{
+		{"HTTP proxy", nc.HTTPProxy},
+		{"HTTPS proxy", nc.HTTPSProxy},
+	}
+	proxySet := false
+	for _, proxy := range proxies {
+		if proxy.url == "" {
+			continue
+		}
+		proxySet = true
+		u, err := url.Parse(proxy.url)
+		if err != nil || u.Host == "" {
+			v.addError(fmt.Errorf("Invalid %s %q provided, must be a URL such as http://proxy.example.com:3128", proxy.name, proxy.url))
+			continue
+		}
+		if ip := net.ParseIP(u.Hostname()); ip != nil {
+			if r, ok := containingNetwork(reserved, ip); ok {
+				v.addError(fmt.Errorf("The %s %q is in the %s", proxy.name, proxy.url, r))
+			}
+		}
+	}
+	if proxySet && lbErr == nil && !util.Contains(lbHost, p.AllAddresses()) && !noProxyMatches(nc.NoProxy, lbHost) {
+		v.addError(fmt.Errorf("Load balancer %q must be listed in no_proxy, otherwise the requests to the Kubernetes API server are sent through the proxy", lbHost))
+	}
+
+	return v.valid()
+}
+
+// clusterNetworks returns the pod and service networks. CIDR blocks that
+// cannot be parsed are ignored, as they are reported by the network
+// configuration validation.
+func (t networkTopology) clusterNetworks() []namedNetwork {
+	networks := []namedNetwork{}
+	for _, b := range cidrBlocks(t.Plan.Cluster.Networking.PodCIDRBlock) {
+		if _, cidr, err := net.ParseCIDR(b); err == nil {
+			networks = append(networks, namedNetwork{name: "Pod CIDR block", cidr: cidr})
+		}
+	}
+	if _, cidr, err := net.ParseCIDR(t.Plan.Cluster.Networking.ServiceCIDRBlock); err == nil {
+		networks = append(networks, namedNetwork{name: "Service CIDR block", cidr: cidr})
+	}
+	return networks
+}
+
+// networksOverlap returns true if the networks share at least one address
+func networksOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+func containingNetwork(networks []namedNetwork, ip net.IP) (namedNetwork, bool) {
+	for _, n := range networks {
+		if n.cidr.Contains(ip) {
+			return n, true
+		}
+	}
+	return namedNetwork{}, false
+}
+
+// noProxyMatches returns true if the host is excluded from the proxy by the
+// comma-separated no_proxy list. Entries can be host names, domain suffixes,
+// IPs or CIDR blocks.
+func noProxyMatches(noProxy string, host string) bool {
+	ip := net.ParseIP(host)
+	for _, e := range strings.Split(noProxy, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if e == "*" || e == host {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(e); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if ip == nil && strings.HasSuffix(host, "."+strings.TrimPrefix(e, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package install
+
+import "testing"
+
+func TestNetworkTopologyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *Plan)
+		valid  bool
+	}{
+		{
+			name:   "valid plan",
+			modify: func(p *Plan) {},
+			valid:  true,
+		},
+		{
+			name:   "pod and service CIDR blocks overlap",
+			modify: func(p *Plan) { p.Cluster.Networking.ServiceCIDRBlock = "172.16.128.0/24" },
+		},
+		{
+			name:   "pod CIDR block overlaps the docker bridge",
+			modify: func(p *Plan) { p.Cluster.Networking.PodCIDRBlock = "172.16.0.0/12" },
+		},
+		{
+			name:   "service CIDR block overlaps the docker bridge",
+			modify: func(p *Plan) { p.Cluster.Networking.ServiceCIDRBlock = "172.17.10.0/24" },
+		},
+		{
+			name:   "node IP in the pod CIDR block",
+			modify: func(p *Plan) { p.Worker.Nodes[0].IP = "172.16.0.10" },
+		},
+		{
+			name:   "node internal IP in the service CIDR block",
+			modify: func(p *Plan) { p.Worker.Nodes[0].InternalIP = "172.20.0.10" },
+		},
+		{
+			name:   "node IP in the docker bridge",
+			modify: func(p *Plan) { p.Worker.Nodes[0].IP = "172.17.0.10" },
+		},
+		{
+			name:   "load balancer IP in the service CIDR block",
+			modify: func(p *Plan) { p.Master.LoadBalancer = "172.20.0.1:6443" },
+		},
+		{
+			name:   "load balancer IP is loopback",
+			modify: func(p *Plan) { p.Master.LoadBalancer = "127.0.0.1:6443" },
+		},
+		{
+			name:   "load balancer IP is outside the node network",
+			modify: func(p *Plan) { p.Master.LoadBalancer = "10.20.0.10:6443" },
+			valid:  true,
+		},
+		{
+			name:   "proxy is not a URL",
+			modify: func(p *Plan) { p.Cluster.Networking.HTTPProxy = "proxy" },
+		},
+		{
+			name: "proxy in the pod CIDR block",
+			modify: func(p *Plan) {
+				p.Cluster.Networking.HTTPProxy = "http://172.16.0.5:3128"
+				p.Cluster.Networking.NoProxy = "test"
+			},
+		},
+		{
+			name:   "load balancer is not excluded from the proxy",
+			modify: func(p *Plan) { p.Cluster.Networking.HTTPSProxy = "http://proxy.example.com:3128" },
+		},
+		{
+			name: "load balancer is excluded from the proxy",
+			modify: func(p *Plan) {
+				p.Master.LoadBalancer = "lb.example.com:6443"
+				p.Cluster.Networking.HTTPSProxy = "http://proxy.example.com:3128"
+				p.Cluster.Networking.NoProxy = "localhost,.example.com"
+			},
+			valid: true,
+		},
+		{
+			name: "load balancer IP is excluded from the proxy by CIDR",
+			modify: func(p *Plan) {
+				p.Master.LoadBalancer = "10.20.0.10:6443"
+				p.Cluster.Networking.HTTPProxy = "http://10.20.0.1:3128"
+				p.Cluster.Networking.NoProxy = "10.20.0.0/16"
+			},
+			valid: true,
+		},
+		{
+			name: "load balancer is a master node",
+			modify: func(p *Plan) {
+				p.Master.LoadBalancer = "192.168.205.11:6443"
+				p.Cluster.Networking.HTTPProxy = "http://proxy.example.com:3128"
+			},
+			valid: true,
+		},
+	}
+	for _, test := range tests {
+		p := validPlan()
+		test.modify(&p)
+		valid, errs := networkTopology{Plan: &p}.validate()
+		if valid != test.valid {
+			t.Errorf("%s: expected valid to be %v, but got %v: %v", test.name, test.valid, valid, errs)
+		}
+	}
+}
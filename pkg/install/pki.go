@@ -420,6 +420,9 @@ func clusterCertsSubjectAlternateNames(plan Plan) ([]string, error) {
 		"127.0.0.1",
 		kubeServiceIP,
 	}
+	if plan.Cluster.Networking.IPv6Enabled() {
+		defaultCertHosts = append(defaultCertHosts, "::1")
+	}
 	return defaultCertHosts, nil
 }
 
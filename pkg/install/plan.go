@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"regexp"
@@ -48,73 +49,108 @@ type Planner interface {
 // FilePlanner is a file-based installation planner
 type FilePlanner struct {
 	File string
+	// Overlays are plan files that are merged, in order, on top of the
+	// plan file when it is read.
+	Overlays []string
+	// KeepSecretReferences disables the resolution of the secret references
+	// in the plan file when it is read.
+	KeepSecretReferences bool
 }
 
-// Read the plan from the file system
+// Read the plan from the file system.
+// Secret references are replaced with the secrets they refer to, unless
+// KeepSecretReferences is set.
 func (fp *FilePlanner) Read() (*Plan, error) {
-	d, err := ioutil.ReadFile(fp.File)
+	return fp.read(!fp.KeepSecretReferences)
+}
+
+// Migrate reads the plan from the file system and converts it to the current
+// version of the plan file schema. The plan file is not modified. The
+// migrated contents are the plan as it was read, migrated, without the
+// defaults or the nodes of the inventory sources.
+func (fp *FilePlanner) Migrate() (*PlanMigration, error) {
+	if len(fp.Overlays) > 0 {
+		return nil, fmt.Errorf("cannot migrate a plan file that has overlays")
+	}
+	original, err := ioutil.ReadFile(fp.File)
 	if err != nil {
 		return nil, fmt.Errorf("could not read file: %v", err)
 	}
-
 	p := &Plan{}
-	if err = yaml.Unmarshal(d, p); err != nil {
+	if err = yaml.Unmarshal(original, p); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal plan: %v", err)
 	}
-
-	// read deprecated fields and set it the new version of the cluster file
-	readDeprecatedFields(p)
-
-	// set nil values to defaults
+	fromVersion := p.APIVersion
+	if err = migratePlan(p); err != nil {
+		return nil, err
+	}
+	// a deprecated field is kept by the migration when its replacement is
+	// also set, and would be rejected once the plan is at the current version
+	if fields := deprecatedPlanFields(p); len(fields) > 0 {
+		return nil, fmt.Errorf("the deprecated fields %s cannot be migrated, as their replacements are also set. Remove them from the plan file before migrating it", strings.Join(fields, ", "))
+	}
+	var migrated bytes.Buffer
+	if err := writePlan(&migrated, p); err != nil {
+		return nil, err
+	}
 	setDefaults(p)
+	return &PlanMigration{
+		FromVersion: fromVersion,
+		ToVersion:   p.APIVersion,
+		Plan:        p,
+		Original:    original,
+		Migrated:    migrated.Bytes(),
+	}, nil
+}
 
+// Render reads the plan from the file system, merging the overlays, and
+// writes the resulting plan to out.
+func (fp *FilePlanner) Render(out io.Writer) (*Plan, error) {
+	p, err := fp.Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := writePlan(out, p); err != nil {
+		return nil, err
+	}
 	return p, nil
 }
 
-func readDeprecatedFields(p *Plan) {
-	// set load_balancer from fqdn:6443
-	// set extra_sans from short_name
-	if p.Master.LoadBalancer == "" {
-		if p.Master.LoadBalancedShortName != nil && *p.Master.LoadBalancedShortName != "" {
-			if p.Cluster.Certificates.APIServerCertExtraSANs != "" {
-				p.Cluster.Certificates.APIServerCertExtraSANs = p.Cluster.Certificates.APIServerCertExtraSANs + ","
-			}
-			p.Cluster.Certificates.APIServerCertExtraSANs = p.Cluster.Certificates.APIServerCertExtraSANs + *p.Master.LoadBalancedFQDN
-		}
-		if p.Master.LoadBalancedFQDN != nil && *p.Master.LoadBalancedFQDN != "" {
-			p.Master.LoadBalancer = *p.Master.LoadBalancedFQDN + ":6443"
+func (fp *FilePlanner) read(resolveSecretReferences bool) (*Plan, error) {
+	d, err := ioutil.ReadFile(fp.File)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	if len(fp.Overlays) > 0 {
+		if d, err = mergePlanOverlays(d, fp.Overlays); err != nil {
+			return nil, err
 		}
 	}
-	// only set if not already being set by the user
-	// package_manager moved from features: to add_ons: after KET v1.3.3
-	if p.Features != nil && p.Features.PackageManager != nil {
-		p.AddOns.PackageManager.Disable = !p.Features.PackageManager.Enabled
-		// KET v1.3.3 did not have a provider field
-		p.AddOns.PackageManager.Provider = ket133PackageManagerProvider
+
+	p := &Plan{}
+	if err = yaml.Unmarshal(d, p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plan: %v", err)
 	}
-	// allow_package_installation renamed to disable_package_installation after KET v1.4.0
-	if p.Cluster.AllowPackageInstallation != nil {
-		p.Cluster.DisablePackageInstallation = !*p.Cluster.AllowPackageInstallation
+
+	// migrate plans written with an older version of the schema
+	if err = migratePlan(p); err != nil {
+		return nil, err
 	}
 
-	if p.DockerRegistry.Server == "" && p.DockerRegistry.Address != "" && p.DockerRegistry.Port != 0 {
-		p.DockerRegistry.Server = fmt.Sprintf("%s:%d", p.DockerRegistry.Address, p.DockerRegistry.Port)
+	if err = loadInventorySources(p); err != nil {
+		return nil, err
 	}
 
-	if p.Docker.Storage.DirectLVM != nil && p.Docker.Storage.DirectLVM.Enabled && (p.Docker.Storage.Opts == nil || len(p.Docker.Storage.Opts) == 0) {
-		p.Docker.Storage.Driver = "devicemapper"
-		p.Docker.Storage.Opts = map[string]string{
-			"dm.thinpooldev":           "/dev/mapper/docker-thinpool",
-			"dm.use_deferred_removal":  "true",
-			"dm.use_deferred_deletion": fmt.Sprintf("%t", p.Docker.Storage.DirectLVM.EnableDeferredDeletion),
+	if resolveSecretReferences {
+		if err = resolveSecrets(p); err != nil {
+			return nil, err
 		}
-		p.Docker.Storage.DirectLVMBlockDevice.Path = p.Docker.Storage.DirectLVM.BlockDevice
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolPercent = "95"
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolMetaPercent = "1"
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendThreshold = "80"
-		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendPercent = "20"
-		p.Docker.Storage.DirectLVM = nil
 	}
+
+	// set nil values to defaults
+	setDefaults(p)
+
+	return p, nil
 }
 
 func setDefaults(p *Plan) {
@@ -162,10 +198,6 @@ func setDefaults(p *Plan) {
 		p.AddOns.CNI.Provider = cniProviderCalico
 		p.AddOns.CNI.Options.Calico.Mode = "overlay"
 		p.AddOns.CNI.Options.Calico.LogLevel = "info"
-		// read KET <v1.5.0 plan option
-		if p.Cluster.Networking.Type != "" {
-			p.AddOns.CNI.Options.Calico.Mode = p.Cluster.Networking.Type
-		}
 	}
 	if p.AddOns.CNI.Options.Calico.LogLevel == "" {
 		p.AddOns.CNI.Options.Calico.LogLevel = "info"
@@ -182,6 +214,10 @@ func setDefaults(p *Plan) {
 		p.AddOns.CNI.Options.Calico.IPAutodetectionMethod = "first-found"
 	}
 
+	if p.AddOns.CNI.Options.Calico.IP6AutodetectionMethod == "" {
+		p.AddOns.CNI.Options.Calico.IP6AutodetectionMethod = "first-found"
+	}
+
 	if p.AddOns.DNS.Provider == "" {
 		p.AddOns.DNS.Provider = "kubedns"
 	}
@@ -195,19 +231,12 @@ func setDefaults(p *Plan) {
 	if p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas == 0 {
 		p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = 2
 	}
-	// read field from KET < v1.5.0
-	if p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas != 0 {
-		p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas
-	}
 	if p.AddOns.HeapsterMonitoring.Options.Heapster.Sink == "" {
 		p.AddOns.HeapsterMonitoring.Options.Heapster.Sink = "influxdb:http://heapster-influxdb.kube-system.svc:8086"
 	}
 	if p.AddOns.HeapsterMonitoring.Options.Heapster.ServiceType == "" {
 		p.AddOns.HeapsterMonitoring.Options.Heapster.ServiceType = "ClusterIP"
 	}
-	if p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName != "" {
-		p.AddOns.HeapsterMonitoring.Options.InfluxDB.PVCName = p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName
-	}
 
 	if p.Cluster.Certificates.CAExpiry == "" {
 		p.Cluster.Certificates.CAExpiry = defaultCAExpiry
@@ -227,6 +256,22 @@ var yamlKeyRE = regexp.MustCompile(`[^a-zA-Z]*([a-z_\-\/A-Z.\d]+)[ ]*:`)
 
 // Write the plan to the file system
 func (fp *FilePlanner) Write(p *Plan) error {
+	if len(fp.Overlays) > 0 {
+		return fmt.Errorf("cannot write a plan file that has overlays, update the plan file %q or its overlays instead", fp.File)
+	}
+	f, err := os.Create(fp.File)
+	if err != nil {
+		return fmt.Errorf("error making plan file: %v", err)
+	}
+	defer f.Close()
+	return writePlan(f, p)
+}
+
+// writePlan writes the plan as YAML, adding comments to the well-known fields.
+// Secrets that were resolved from a reference are written as the reference, and
+// nodes that were read from an inventory source are not written.
+func writePlan(f io.Writer, p *Plan) error {
+	p = withoutInventoryNodes(withSecretReferences(p))
 	// make a copy of the global comment map
 	oneTimeComments := map[string][]string{}
 	for k, v := range commentMap {
@@ -237,19 +282,14 @@ func (fp *FilePlanner) Write(p *Plan) error {
 		return fmt.Errorf("error marshalling plan to yaml: %v", marshalErr)
 	}
 
-	f, err := os.Create(fp.File)
-	if err != nil {
-		return fmt.Errorf("error making plan file: %v", err)
-	}
-	defer f.Close()
-
 	// the stack keeps track of the object we are in
 	// for example, when we are inside cluster.networking, looking at the key 'foo'
 	// the stack will have [cluster, networking, foo]
 	s := newStack()
 	scanner := bufio.NewScanner(bytes.NewReader(bytez))
 	prevIndent := -1
-	addNewLineBeforeComment := true
+	// no new line before the comment of the first field in the file
+	addNewLineBeforeComment := false
 	var etcdBlock bool
 	for scanner.Scan() {
 		text := scanner.Text()
@@ -272,7 +312,7 @@ func (fp *FilePlanner) Write(p *Plan) error {
 			// Add a new line if we are leaving a major indentation block
 			// (leaving a struct)..
 			if indent < prevIndent {
-				f.WriteString("\n")
+				io.WriteString(f, "\n")
 				// suppress the new line that would be added if this
 				// field has a comment
 				addNewLineBeforeComment = false
@@ -291,7 +331,7 @@ func (fp *FilePlanner) Write(p *Plan) error {
 
 			// Full key match (e.g. "cluster.networking.pod_cidr")
 			if thiscomment, ok := oneTimeComments[strings.Join(s.s, ".")]; ok {
-				if _, err := f.WriteString(getCommentedLine(text, thiscomment, addNewLineBeforeComment)); err != nil {
+				if _, err := io.WriteString(f, getCommentedLine(text, thiscomment, addNewLineBeforeComment)); err != nil {
 					return err
 				}
 				delete(oneTimeComments, matched[1])
@@ -300,7 +340,7 @@ func (fp *FilePlanner) Write(p *Plan) error {
 			}
 		}
 		// we don't want to comment this line... just print it out
-		if _, err := f.WriteString(text + "\n"); err != nil {
+		if _, err := io.WriteString(f, text+"\n"); err != nil {
 			return err
 		}
 		addNewLineBeforeComment = true
@@ -357,6 +397,7 @@ func WritePlanTemplate(planTemplateOpts PlanTemplateOptions, w PlanReadWriter) e
 // template options
 func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 	p := Plan{}
+	p.APIVersion = CurrentPlanAPIVersion
 	p.Cluster.Name = "kubernetes"
 	p.Cluster.Version = kubernetesVersionString
 	p.Cluster.AdminPassword = templateOpts.AdminPassword
@@ -399,6 +440,7 @@ func buildPlanFromTemplateOptions(templateOpts PlanTemplateOptions) Plan {
 	p.AddOns.CNI.Options.Calico.WorkloadMTU = 1500
 	p.AddOns.CNI.Options.Calico.FelixInputMTU = 1440
 	p.AddOns.CNI.Options.Calico.IPAutodetectionMethod = "first-found"
+	p.AddOns.CNI.Options.Calico.IP6AutodetectionMethod = "first-found"
 	// DNS
 	p.AddOns.DNS.Provider = "kubedns"
 	p.AddOns.DNS.Options.Replicas = 2
@@ -461,7 +503,7 @@ func getKubernetesServiceIP(p *Plan) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("error getting kubernetes service IP: %v", err)
 	}
-	return ip.To4().String(), nil
+	return ip.String(), nil
 }
 
 func getDNSServiceIP(p *Plan) (string, error) {
@@ -469,13 +511,14 @@ func getDNSServiceIP(p *Plan) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("error getting DNS service IP: %v", err)
 	}
-	return ip.To4().String(), nil
+	return ip.String(), nil
 }
 
 // The comment map contains is keyed by the value that should be commented
 // in the plan file. The value of the map contains the comment, split into
 // separate lines.
 var commentMap = map[string][]string{
+	"apiVersion":                                         []string{"Version of the plan file schema. Use 'kismatic install plan migrate' to update", "a plan file that was written by an older version of kismatic."},
 	"cluster.admin_password":                             []string{"This password is used to login to the Kubernetes Dashboard and can also be", "used for administration without a security certificate."},
 	"cluster.version":                                    []string{fmt.Sprintf("Kubernetes cluster version (supported minor version %q).", kubernetesMinorVersionString)},
 	"cluster.disable_package_installation":               []string{"Set to true if the nodes have the required packages installed."},
@@ -492,7 +535,7 @@ var commentMap = map[string][]string{
 	"cluster.certificates.ca_expiry":                     []string{"CA certificate expiration period in hours; default is 2 years."},
 	"cluster.certificates.apiserver_cert_extra_sans":     []string{"Optional extra Subject Alternative Names (SANs) to use for the API Server serving certificate.", "Can be both IP addresses and DNS names."},
 	"cluster.ssh":                                        []string{"SSH configuration for cluster nodes."},
-	"cluster.ssh.user":                                   []string{"This user must be able to sudo without password."},
+	"cluster.ssh.user":                                   []string{"This user must be able to sudo. Set become_password when sudo requires a password."},
 	"cluster.ssh.ssh_key":                                []string{"Absolute path to the ssh private key we should use to manage nodes."},
 	"cluster.kube_apiserver":                             []string{"Override configuration of Kubernetes components."},
 	"cluster.cloud_provider":                             []string{"Kubernetes cloud provider integration."},
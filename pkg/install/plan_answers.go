@@ -0,0 +1,130 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PlanAnswers are the answers that are used to generate a plan file without
+// prompting the user.
+type PlanAnswers struct {
+	// Plan is a partial plan file. The settings of the partial plan file take
+	// precedence over the defaults of the plan file template.
+	Plan []byte
+	// Set are plan file settings formatted as path=value, where the path is
+	// the dot-separated list of keys of the setting, such as
+	// add_ons.cni.provider. Values are parsed as YAML. Settings are applied
+	// in order, after the partial plan file.
+	Set []string
+	// Nodes are appended to the node group of the same name
+	Nodes map[string][]Node
+	// NodeCounts are the expected number of nodes of the node group of the
+	// same name. When the expected number of nodes of a group is not given,
+	// it defaults to the number of nodes of the group.
+	NodeCounts    map[string]int
+	AdminPassword string
+}
+
+type answersNodeGroup struct {
+	expectedCount *int
+	nodes         *[]Node
+	defaultCount  int
+}
+
+// BuildPlanFromAnswers fills out a plan with the defaults of the plan file
+// template, and applies the answers on top of it.
+func BuildPlanFromAnswers(a PlanAnswers) (*Plan, error) {
+	p := buildPlanFromTemplateOptions(PlanTemplateOptions{AdminPassword: a.AdminPassword})
+	if len(a.Plan) > 0 {
+		if err := yaml.UnmarshalStrict(a.Plan, &p); err != nil {
+			return nil, fmt.Errorf("error reading answers: %v", err)
+		}
+	}
+	if len(a.Set) > 0 {
+		settings := map[interface{}]interface{}{}
+		for _, s := range a.Set {
+			if err := setPlanValue(settings, s); err != nil {
+				return nil, err
+			}
+		}
+		b, err := yaml.Marshal(settings)
+		if err != nil {
+			return nil, fmt.Errorf("error applying settings: %v", err)
+		}
+		if err := yaml.UnmarshalStrict(b, &p); err != nil {
+			return nil, fmt.Errorf("error applying settings: %v", err)
+		}
+	}
+
+	groups := map[string]answersNodeGroup{
+		"etcd":    {&p.Etcd.ExpectedCount, &p.Etcd.Nodes, 3},
+		"master":  {&p.Master.ExpectedCount, &p.Master.Nodes, 2},
+		"worker":  {&p.Worker.ExpectedCount, &p.Worker.Nodes, 3},
+		"ingress": {&p.Ingress.ExpectedCount, &p.Ingress.Nodes, 0},
+		"storage": {&p.Storage.ExpectedCount, &p.Storage.Nodes, 0},
+	}
+	for name := range a.Nodes {
+		if _, ok := groups[name]; !ok {
+			return nil, fmt.Errorf("%q is not a node group. Node groups are %s", name, strings.Join(roles(), ", "))
+		}
+	}
+	for name := range a.NodeCounts {
+		if _, ok := groups[name]; !ok {
+			return nil, fmt.Errorf("%q is not a node group. Node groups are %s", name, strings.Join(roles(), ", "))
+		}
+	}
+	for _, name := range roles() {
+		g := groups[name]
+		*g.nodes = append(*g.nodes, a.Nodes[name]...)
+		if n, ok := a.NodeCounts[name]; ok {
+			if n < 0 {
+				return nil, fmt.Errorf("the number of %s nodes must be greater than or equal to zero", name)
+			}
+			*g.expectedCount = n
+		}
+		if *g.expectedCount == 0 {
+			*g.expectedCount = len(*g.nodes)
+		}
+		if *g.expectedCount == 0 {
+			*g.expectedCount = g.defaultCount
+		}
+		// Add empty entries for the nodes that were not given, so that they
+		// can be filled out in the plan file
+		for len(*g.nodes) < *g.expectedCount {
+			*g.nodes = append(*g.nodes, Node{})
+		}
+	}
+	return &p, nil
+}
+
+// setPlanValue sets the value of the setting formatted as path=value in the
+// given map, creating the intermediate maps of the path as needed.
+func setPlanValue(m map[interface{}]interface{}, setting string) error {
+	parts := strings.SplitN(setting, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("invalid setting %q: must be formatted as path=value", setting)
+	}
+	keys := strings.Split(strings.TrimSpace(parts[0]), ".")
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(parts[1]), &value); err != nil {
+		return fmt.Errorf("invalid value of setting %q: %v", setting, err)
+	}
+	for i, k := range keys {
+		if k == "" {
+			return fmt.Errorf("invalid setting %q: path contains an empty key", setting)
+		}
+		if i == len(keys)-1 {
+			m[k] = value
+			break
+		}
+		next, ok := m[k].(map[interface{}]interface{})
+		if !ok {
+			next = map[interface{}]interface{}{}
+			m[k] = next
+		}
+		m = next
+	}
+	return nil
+}
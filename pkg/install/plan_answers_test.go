@@ -0,0 +1,154 @@
+package install
+
+import (
+	"testing"
+)
+
+func TestBuildPlanFromAnswers(t *testing.T) {
+	answers := PlanAnswers{
+		Plan: []byte(`
+cluster:
+  name: pipeline
+  networking:
+    pod_cidr_block: 10.10.0.0/16
+add_ons:
+  cni:
+    provider: weave
+  dns:
+    provider: coredns
+  dashboard:
+    disable: true
+docker:
+  storage:
+    direct_lvm_block_device:
+      path: /dev/sdb
+docker_registry:
+  server: registry.example.com:5000
+master:
+  load_balancer: 10.0.0.2:6443
+etcd:
+  nodes:
+  - host: etcd01
+    ip: 10.0.0.1
+`),
+		Set: []string{
+			"cluster.networking.service_cidr_block=10.20.0.0/16",
+			"add_ons.heapster.disable=true",
+			"cluster.cloud_provider.provider=aws",
+		},
+		Nodes: map[string][]Node{
+			"master": {{Host: "master01", IP: "10.0.0.2"}},
+			"worker": {{Host: "worker01", IP: "10.0.0.3"}, {Host: "worker02", IP: "10.0.0.4"}},
+		},
+		NodeCounts: map[string]int{"ingress": 1},
+	}
+	p, err := BuildPlanFromAnswers(answers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "pipeline" {
+		t.Errorf("expected cluster name pipeline, got %q", p.Cluster.Name)
+	}
+	if p.Cluster.Networking.PodCIDRBlock != "10.10.0.0/16" || p.Cluster.Networking.ServiceCIDRBlock != "10.20.0.0/16" {
+		t.Errorf("unexpected networking: %+v", p.Cluster.Networking)
+	}
+	// Defaults of the template are kept
+	if p.Cluster.SSH.User != "kismaticuser" || p.Cluster.Certificates.Expiry != "17520h" {
+		t.Errorf("expected template defaults to be kept, got %+v", p.Cluster)
+	}
+	if p.AddOns.CNI.Provider != "weave" || p.AddOns.DNS.Provider != "coredns" {
+		t.Errorf("unexpected add-ons: CNI %q, DNS %q", p.AddOns.CNI.Provider, p.AddOns.DNS.Provider)
+	}
+	if p.AddOns.CNI.Options.Calico.Mode != "overlay" {
+		t.Errorf("expected calico options defaults to be kept, got %+v", p.AddOns.CNI.Options.Calico)
+	}
+	if !p.AddOns.Dashboard.Disable || !p.AddOns.HeapsterMonitoring.Disable {
+		t.Errorf("expected dashboard and heapster to be disabled")
+	}
+	if p.Docker.Storage.DirectLVMBlockDevice.Path != "/dev/sdb" || p.Docker.Storage.DirectLVMBlockDevice.ThinpoolPercent != "95" {
+		t.Errorf("unexpected docker storage: %+v", p.Docker.Storage.DirectLVMBlockDevice)
+	}
+	if p.DockerRegistry.Server != "registry.example.com:5000" {
+		t.Errorf("unexpected docker registry: %q", p.DockerRegistry.Server)
+	}
+	if p.Cluster.CloudProvider.Provider != "aws" {
+		t.Errorf("unexpected cloud provider: %q", p.Cluster.CloudProvider.Provider)
+	}
+
+	tests := []struct {
+		group         string
+		expectedCount int
+		nodes         []Node
+	}{
+		{"etcd", p.Etcd.ExpectedCount, p.Etcd.Nodes},
+		{"master", p.Master.ExpectedCount, p.Master.Nodes},
+		{"worker", p.Worker.ExpectedCount, p.Worker.Nodes},
+		{"ingress", p.Ingress.ExpectedCount, p.Ingress.Nodes},
+		{"storage", p.Storage.ExpectedCount, p.Storage.Nodes},
+	}
+	expected := map[string]int{"etcd": 1, "master": 1, "worker": 2, "ingress": 1, "storage": 0}
+	for _, test := range tests {
+		if test.expectedCount != expected[test.group] {
+			t.Errorf("expected %d %s nodes, got %d", expected[test.group], test.group, test.expectedCount)
+		}
+		if len(test.nodes) != test.expectedCount {
+			t.Errorf("expected %d entries in %s nodes, got %d", test.expectedCount, test.group, len(test.nodes))
+		}
+	}
+	if p.Worker.Nodes[1].Host != "worker02" {
+		t.Errorf("expected worker02 to be the second worker, got %q", p.Worker.Nodes[1].Host)
+	}
+}
+
+func TestBuildPlanFromAnswersDefaults(t *testing.T) {
+	p, err := BuildPlanFromAnswers(PlanAnswers{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Etcd.ExpectedCount != 3 || p.Master.ExpectedCount != 2 || p.Worker.ExpectedCount != 3 {
+		t.Errorf("unexpected node counts: etcd %d, master %d, worker %d", p.Etcd.ExpectedCount, p.Master.ExpectedCount, p.Worker.ExpectedCount)
+	}
+	if p.Ingress.ExpectedCount != 0 || p.Storage.ExpectedCount != 0 {
+		t.Errorf("expected optional node groups to be empty")
+	}
+	if len(p.Etcd.Nodes) != 3 {
+		t.Errorf("expected empty entries for the etcd nodes, got %d", len(p.Etcd.Nodes))
+	}
+}
+
+func TestBuildPlanFromAnswersErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers PlanAnswers
+	}{
+		{
+			name:    "unknown field in answers",
+			answers: PlanAnswers{Plan: []byte("cluster:\n  nmae: foo\n")},
+		},
+		{
+			name:    "unknown setting",
+			answers: PlanAnswers{Set: []string{"add_ons.cni.providr=weave"}},
+		},
+		{
+			name:    "setting without value",
+			answers: PlanAnswers{Set: []string{"add_ons.cni.provider"}},
+		},
+		{
+			name:    "setting with empty key",
+			answers: PlanAnswers{Set: []string{"add_ons..provider=weave"}},
+		},
+		{
+			name:    "unknown node group",
+			answers: PlanAnswers{Nodes: map[string][]Node{"etc": {{Host: "etcd01"}}}},
+		},
+		{
+			name:    "negative node count",
+			answers: PlanAnswers{NodeCounts: map[string]int{"storage": -1}},
+		},
+	}
+	for _, test := range tests {
+		if _, err := BuildPlanFromAnswers(test.answers); err == nil {
+			t.Errorf("%s: expected an error, but got none", test.name)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package install
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fullInstallPlay is the playbook that runs all the plays of the installation
+const fullInstallPlay = "kubernetes.yaml"
+
+// PlanDiff is the set of differences between two plans
+type PlanDiff struct {
+	// Nodes that are only in the new plan
+	NodesAdded []NodeDiff
+	// Nodes that are only in the old plan
+	NodesRemoved []NodeDiff
+	// Nodes that are in both plans, but have different settings
+	NodesChanged []NodeDiff
+	// Add-ons that were enabled or disabled
+	AddOnsToggled []AddOnToggle
+	// All other fields that changed, excluding node groups and add-on toggles
+	Fields []FieldDiff
+}
+
+// NodeDiff is the difference of a node in a node group
+type NodeDiff struct {
+	// The node group, e.g. etcd, master, worker
+	Group string
+	Host  string
+	// The fields of the node that changed, if any
+	Fields []FieldDiff
+}
+
+// AddOnToggle is an add-on that was enabled or disabled
+type AddOnToggle struct {
+	Name    string
+	Enabled bool
+}
+
+// FieldDiff is a field that has a different value in the two plans
+type FieldDiff struct {
+	// Path of the field in the plan file, e.g. cluster.networking.pod_cidr_block
+	Path string
+	From string
+	To   string
+}
+
+// PlayImpact is a play that should be run to apply the differences
+// between two plans, and the nodes it should run on.
+type PlayImpact struct {
+	Play  string
+	Hosts []string
+}
+
+// Empty returns true if there are no differences
+func (d PlanDiff) Empty() bool {
+	return len(d.NodesAdded) == 0 && len(d.NodesRemoved) == 0 && len(d.NodesChanged) == 0 &&
+		len(d.AddOnsToggled) == 0 && len(d.Fields) == 0
+}
+
+// DiffPlans returns the differences between the from and to plans
+func DiffPlans(from, to *Plan) PlanDiff {
+	d := PlanDiff{}
+	fromGroups, toGroups := nodeGroups(from), nodeGroups(to)
+	for _, g := range nodeGroupNames {
+		fromNodes := nodesByHost(fromGroups[g])
+		for _, n := range toGroups[g] {
+			old, ok := fromNodes[n.Host]
+			if !ok {
+				d.NodesAdded = append(d.NodesAdded, NodeDiff{Group: g, Host: n.Host})
+				continue
+			}
+			fields := []FieldDiff{}
+			diffValues("", reflect.ValueOf(old), reflect.ValueOf(n), &fields)
+			if len(fields) > 0 {
+				d.NodesChanged = append(d.NodesChanged, NodeDiff{Group: g, Host: n.Host, Fields: fields})
+			}
+		}
+		toNodes := nodesByHost(toGroups[g])
+		for _, n := range fromGroups[g] {
+			if _, ok := toNodes[n.Host]; !ok {
+				d.NodesRemoved = append(d.NodesRemoved, NodeDiff{Group: g, Host: n.Host})
+			}
+		}
+	}
+
+	fromAddOns, toAddOns := addOnsEnabled(from), addOnsEnabled(to)
+	for _, a := range addOnNames {
+		if fromAddOns[a] != toAddOns[a] {
+			d.AddOnsToggled = append(d.AddOnsToggled, AddOnToggle{Name: a, Enabled: toAddOns[a]})
+		}
+	}
+
+	diffValues("", reflect.ValueOf(*from), reflect.ValueOf(*to), &d.Fields)
+	return d
+}
+
+// Impact returns the plays that are affected by the differences, in the order
+// they run during the installation, and the nodes of the new plan they run on.
+func (d PlanDiff) Impact(p *Plan) []PlayImpact {
+	hosts := map[string]map[string]bool{}
+	add := func(play string, h ...string) {
+		if hosts[play] == nil {
+			hosts[play] = map[string]bool{}
+		}
+		for _, host := range h {
+			hosts[play][host] = true
+		}
+	}
+	addPlays := func(plays []string) {
+		for _, play := range plays {
+			add(play, playHosts(p, play)...)
+		}
+	}
+
+	for _, n := range d.NodesAdded {
+		add(fullInstallPlay, n.Host)
+	}
+	for _, n := range d.NodesChanged {
+		for _, f := range n.Fields {
+			plays := playsForNodeField(f.Path)
+			for _, play := range plays {
+				add(play, n.Host)
+			}
+		}
+	}
+	for _, a := range d.AddOnsToggled {
+		addPlays(playsForField("add_ons." + a.Name))
+	}
+	for _, f := range d.Fields {
+		addPlays(playsForField(f.Path))
+	}
+
+	impact := []PlayImpact{}
+	for _, play := range append([]string{fullInstallPlay}, installPlayOrder...) {
+		if _, ok := hosts[play]; !ok {
+			continue
+		}
+		pi := PlayImpact{Play: play}
+		for h := range hosts[play] {
+			pi.Hosts = append(pi.Hosts, h)
+		}
+		sort.Strings(pi.Hosts)
+		impact = append(impact, pi)
+	}
+	return impact
+}
+
+var nodeGroupNames = []string{"etcd", "master", "worker", "ingress", "storage"}
+
+func nodeGroups(p *Plan) map[string][]Node {
+	return map[string][]Node{
+		"etcd":    p.Etcd.Nodes,
+		"master":  p.Master.Nodes,
+		"worker":  p.Worker.Nodes,
+		"ingress": p.Ingress.Nodes,
+		"storage": p.Storage.Nodes,
+	}
+}
+
+func nodesByHost(nodes []Node) map[string]Node {
+	m := map[string]Node{}
+	for _, n := range nodes {
+		m[n.Host] = n
+	}
+	return m
+}
+
+var addOnNames = []string{"cni", "dns", "heapster", "metrics_server", "dashboard", "package_manager", "rescheduler"}
+
+func addOnsEnabled(p *Plan) map[string]bool {
+	return map[string]bool{
+		"cni":             p.AddOns.CNI != nil && !p.AddOns.CNI.Disable,
+		"dns":             !p.AddOns.DNS.Disable,
+		"heapster":        p.AddOns.HeapsterMonitoring != nil && !p.AddOns.HeapsterMonitoring.Disable,
+		"metrics_server":  !p.AddOns.MetricsServer.Disable,
+		"dashboard":       !p.AddOns.Dashboard.Disable,
+		"package_manager": !p.AddOns.PackageManager.Disable,
+		"rescheduler":     !p.AddOns.Rescheduler.Disable,
+	}
+}
+
+// skipDiffField returns true for the fields that are compared separately
+func skipDiffField(path string) bool {
+	switch path {
+	case "etcd.nodes", "master.nodes", "worker.nodes", "ingress.nodes", "storage.nodes":
+		return true
+	}
+	parts := strings.Split(path, ".")
+	return len(parts) == 3 && parts[0] == "add_ons" && parts[2] == "disable"
+}
+
+// diffValues walks the values a and b, appending the differences to diffs.
+// Fields are named after their path in the plan file.
+func diffValues(path string, a, b reflect.Value, diffs *[]FieldDiff) {
+	if skipDiffField(path) {
+		return
+	}
+	if a.Kind() == reflect.Ptr {
+		if a.IsNil() && b.IsNil() {
+			return
+		}
+		if a.IsNil() {
+			a = reflect.New(a.Type().Elem())
+		}
+		if b.IsNil() {
+			b = reflect.New(b.Type().Elem())
+		}
+		diffValues(path, a.Elem(), b.Elem(), diffs)
+		return
+	}
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			f := a.Type().Field(i)
+			// unexported fields are not part of the plan file
+			if f.PkgPath != "" {
+				continue
+			}
+			diffValues(fieldPath(path, f), a.Field(i), b.Field(i), diffs)
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[k.String()] = k
+		}
+		names := []string{}
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			av, bv := a.MapIndex(keys[name]), b.MapIndex(keys[name])
+			if av.IsValid() && bv.IsValid() && reflect.DeepEqual(av.Interface(), bv.Interface()) {
+				continue
+			}
+			*diffs = append(*diffs, FieldDiff{
+				Path: path + "." + name,
+				From: formatDiffValue(path, av),
+				To:   formatDiffValue(path, bv),
+			})
+		}
+	default:
+		// slices and basic types are compared as a whole
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return
+		}
+		// an empty list is equivalent to a list that is not set
+		if a.Kind() == reflect.Slice && a.Len() == 0 && b.Len() == 0 {
+			return
+		}
+		*diffs = append(*diffs, FieldDiff{Path: path, From: formatDiffValue(path, a), To: formatDiffValue(path, b)})
+	}
+}
+
+func fieldPath(parent string, f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func formatDiffValue(path string, v reflect.Value) string {
+	if !v.IsValid() {
+		return "(not set)"
+	}
+	if strings.Contains(strings.ToLower(path), "password") {
+		return "(hidden)"
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
+
+// installPlayOrder is the order in which the plays run in kubernetes.yaml.
+// It is checked against the playbook by TestInstallPlaysMatchPlaybook.
+var installPlayOrder = []string{
+	"_all.yaml",
+	"_additional-files.yaml",
+	"_hosts.yaml",
+	"_certs.yaml",
+	"_kubeconfig.yaml",
+	"_certs-etcd.yaml",
+	"_packages-repo.yaml",
+	"_docker.yaml",
+	"_etcd-k8s.yaml",
+	"_etcd-networking.yaml",
+	"_kubelet.yaml",
+	"_kube-apiserver.yaml",
+	"_kube-scheduler.yaml",
+	"_kube-controller-manager.yaml",
+	"_validate-control-plane-node.yaml",
+	"_kube-proxy.yaml",
+	"_label-nodes.yaml",
+	"_calico.yaml",
+	"_calico-validate.yaml",
+	"_calico-network-policy.yaml",
+	"_weave.yaml",
+	"_weave-validate.yaml",
+	"_contiv.yaml",
+	"_rescheduler.yaml",
+	"_cluster-dns.yaml",
+	"_heapster.yaml",
+	"_metrics-server.yaml",
+	"_kube-dashboard.yaml",
+	"_helm.yaml",
+	"_nginx-ingress.yaml",
+	"_storage.yaml",
+	"_nfs-volumes.yaml",
+	"_update-version.yaml",
+}
+
+// the ansible host pattern of each play, checked against the playbooks by
+// TestInstallPlaysMatchPlaybook
+var playHostPatterns = map[string]string{
+	fullInstallPlay:                     "all",
+	"_all.yaml":                         "all",
+	"_additional-files.yaml":            "all",
+	"_hosts.yaml":                       "all",
+	"_certs.yaml":                       "master:worker:ingress:storage",
+	"_kubeconfig.yaml":                  "master:worker:ingress:storage",
+	"_certs-etcd.yaml":                  "etcd",
+	"_packages-repo.yaml":               "all",
+	"_docker.yaml":                      "all",
+	"_etcd-k8s.yaml":                    "etcd",
+	"_etcd-networking.yaml":             "etcd",
+	"_kubelet.yaml":                     "master:worker:ingress:storage",
+	"_kube-apiserver.yaml":              "master",
+	"_kube-scheduler.yaml":              "master",
+	"_kube-controller-manager.yaml":     "master",
+	"_validate-control-plane-node.yaml": "master",
+	"_kube-proxy.yaml":                  "master:worker:ingress:storage",
+	"_label-nodes.yaml":                 "master:worker:ingress:storage",
+	"_calico.yaml":                      "master:worker:ingress:storage",
+	"_calico-validate.yaml":             "master:worker:ingress:storage",
+	"_calico-network-policy.yaml":       "master[0]",
+	"_weave.yaml":                       "master:worker:ingress:storage",
+	"_weave-validate.yaml":              "master:worker:ingress:storage",
+	"_contiv.yaml":                      "master:worker:ingress:storage",
+	"_rescheduler.yaml":                 "master[0]",
+	"_cluster-dns.yaml":                 "master[0]",
+	"_heapster.yaml":                    "master[0]",
+	"_metrics-server.yaml":              "master[0]",
+	"_kube-dashboard.yaml":              "master[0]",
+	"_helm.yaml":                        "master[0]",
+	"_nginx-ingress.yaml":               "ingress",
+	"_storage.yaml":                     "storage",
+	"_nfs-volumes.yaml":                 "master[0]",
+	"_update-version.yaml":              "all",
+}
+
+// playHosts returns the hosts of the plan that the play runs on
+func playHosts(p *Plan, play string) []string {
+	groups := nodeGroups(p)
+	hosts := []string{}
+	for _, pattern := range strings.Split(playHostPatterns[play], ":") {
+		switch {
+		case pattern == "all":
+			for _, n := range p.GetUniqueNodes() {
+				hosts = append(hosts, n.Host)
+			}
+		case strings.HasSuffix(pattern, "[0]"):
+			if nodes := groups[strings.TrimSuffix(pattern, "[0]")]; len(nodes) > 0 {
+				hosts = append(hosts, nodes[0].Host)
+			}
+		default:
+			for _, n := range groups[pattern] {
+				hosts = append(hosts, n.Host)
+			}
+		}
+	}
+	return hosts
+}
+
+var cniPlays = []string{"_etcd-networking.yaml", "_calico.yaml", "_calico-validate.yaml", "_calico-network-policy.yaml", "_weave.yaml", "_weave-validate.yaml", "_contiv.yaml"}
+
+// planFieldPlays maps the fields of the plan to the plays that use them.
+// The entry with the longest matching prefix is used. Fields that are not
+// listed require a full installation.
+var planFieldPlays = map[string][]string{
+	"apiVersion":                            nil,
+	"features":                              nil,
+	"cluster.ssh":                           nil,
+	"cluster.name":                          {"_kubeconfig.yaml", "_kube-apiserver.yaml", "_kube-controller-manager.yaml"},
+	"cluster.admin_password":                {"_kube-apiserver.yaml"},
+	"cluster.disable_package_installation":  {"_packages-repo.yaml"},
+	"cluster.disconnected_installation":     {"_packages-repo.yaml", "_docker.yaml"},
+	"cluster.networking.update_hosts_files": {"_hosts.yaml"},
+	"cluster.networking.http_proxy":         {"_docker.yaml"},
+	"cluster.networking.https_proxy":        {"_docker.yaml"},
+	"cluster.networking.no_proxy":           {"_docker.yaml"},
+	"cluster.networking.pod_cidr_block":     append([]string{"_kube-controller-manager.yaml", "_kube-proxy.yaml"}, cniPlays...),
+	"cluster.networking.service_cidr_block": {"_certs.yaml", "_kubelet.yaml", "_kube-apiserver.yaml", "_kube-controller-manager.yaml", "_cluster-dns.yaml"},
+	"cluster.certificates":                  {"_certs.yaml", "_kubeconfig.yaml", "_certs-etcd.yaml"},
+	"cluster.kube_apiserver":                {"_kube-apiserver.yaml"},
+	"cluster.kube_controller_manager":       {"_kube-controller-manager.yaml"},
+	"cluster.kube_scheduler":                {"_kube-scheduler.yaml"},
+	"cluster.kube_proxy":                    {"_kube-proxy.yaml"},
+	"cluster.kubelet":                       {"_kubelet.yaml"},
+	"cluster.cloud_provider":                {"_kubelet.yaml", "_kube-apiserver.yaml", "_kube-controller-manager.yaml"},
+	"docker":                                {"_docker.yaml"},
+	"docker_registry":                       {"_docker.yaml"},
+	"additional_files":                      {"_additional-files.yaml"},
+	"add_ons.cni":                           cniPlays,
+	"add_ons.cni.options.calico":            {"_calico.yaml", "_calico-validate.yaml"},
+	"add_ons.cni.options.weave":             {"_weave.yaml", "_weave-validate.yaml"},
+	"add_ons.dns":                           {"_cluster-dns.yaml"},
+	"add_ons.heapster":                      {"_heapster.yaml"},
+	"add_ons.metrics_server":                {"_metrics-server.yaml"},
+	"add_ons.dashboard":                     {"_kube-dashboard.yaml"},
+	"add_ons.package_manager":               {"_helm.yaml"},
+	"add_ons.rescheduler":                   {"_rescheduler.yaml"},
+	"master.load_balancer":                  {"_certs.yaml", "_kubeconfig.yaml", "_kubelet.yaml", "_kube-proxy.yaml"},
+	"etcd.expected_count":                   nil,
+	"master.expected_count":                 nil,
+	"worker.expected_count":                 nil,
+	"ingress.expected_count":                nil,
+	"storage.expected_count":                nil,
+	"etcd.inventory_source":                 nil,
+	"master.inventory_source":               nil,
+	"worker.inventory_source":               nil,
+	"ingress.inventory_source":              nil,
+	"storage.inventory_source":              nil,
+	"master.load_balanced_fqdn":             nil,
+	"master.load_balanced_short_name":       nil,
+	"nfs":                                   {"_nfs-volumes.yaml"},
+}
+
+// nodeFieldPlays maps the fields of a node to the plays that use them
+var nodeFieldPlays = map[string][]string{
+	"labels":  {"_label-nodes.yaml"},
+	"taints":  {"_label-nodes.yaml"},
+	"kubelet": {"_kubelet.yaml"},
+}
+
+func playsForField(path string) []string {
+	return longestPrefixMatch(planFieldPlays, path)
+}
+
+func playsForNodeField(path string) []string {
+	return longestPrefixMatch(nodeFieldPlays, path)
+}
+
+func longestPrefixMatch(m map[string][]string, path string) []string {
+	for p := path; p != ""; {
+		if plays, ok := m[p]; ok {
+			return plays
+		}
+		i := strings.LastIndex(p, ".")
+		if i == -1 {
+			break
+		}
+		p = p[:i]
+	}
+	return []string{fullInstallPlay}
+}
@@ -0,0 +1,145 @@
+package install
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestDiffPlans(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(p *Plan)
+		expected PlanDiff
+		impact   []PlayImpact
+	}{
+		{
+			name:     "no changes",
+			modify:   func(p *Plan) {},
+			expected: PlanDiff{},
+			impact:   []PlayImpact{},
+		},
+		{
+			name: "worker added",
+			modify: func(p *Plan) {
+				p.Worker.ExpectedCount = 2
+				p.Worker.Nodes = append(p.Worker.Nodes, Node{Host: "worker02", IP: "192.168.205.13"})
+			},
+			expected: PlanDiff{
+				NodesAdded: []NodeDiff{{Group: "worker", Host: "worker02"}},
+				Fields:     []FieldDiff{{Path: "worker.expected_count", From: "1", To: "2"}},
+			},
+			impact: []PlayImpact{{Play: "kubernetes.yaml", Hosts: []string{"worker02"}}},
+		},
+		{
+			name: "worker removed",
+			modify: func(p *Plan) {
+				p.Worker.Nodes = []Node{}
+			},
+			expected: PlanDiff{
+				NodesRemoved: []NodeDiff{{Group: "worker", Host: "worker01"}},
+			},
+			impact: []PlayImpact{},
+		},
+		{
+			name: "node labels changed",
+			modify: func(p *Plan) {
+				p.Master.Nodes[0].Labels = map[string]string{"com.example/role": "infra"}
+			},
+			expected: PlanDiff{
+				NodesChanged: []NodeDiff{{Group: "master", Host: "master01", Fields: []FieldDiff{{Path: "labels.com.example/role", From: "(not set)", To: `"infra"`}}}},
+			},
+			impact: []PlayImpact{{Play: "_label-nodes.yaml", Hosts: []string{"master01"}}},
+		},
+		{
+			name: "option override added",
+			modify: func(p *Plan) {
+				p.Cluster.APIServerOptions.Overrides = map[string]string{"v": "3"}
+			},
+			expected: PlanDiff{
+				Fields: []FieldDiff{{Path: "cluster.kube_apiserver.option_overrides.v", From: "(not set)", To: `"3"`}},
+			},
+			impact: []PlayImpact{{Play: "_kube-apiserver.yaml", Hosts: []string{"master01"}}},
+		},
+		{
+			name: "add-on disabled",
+			modify: func(p *Plan) {
+				p.AddOns.Dashboard.Disable = true
+			},
+			expected: PlanDiff{
+				AddOnsToggled: []AddOnToggle{{Name: "dashboard", Enabled: false}},
+			},
+			impact: []PlayImpact{{Play: "_kube-dashboard.yaml", Hosts: []string{"master01"}}},
+		},
+		{
+			name: "password is not printed",
+			modify: func(p *Plan) {
+				p.DockerRegistry.Password = "secret"
+			},
+			expected: PlanDiff{
+				Fields: []FieldDiff{{Path: "docker_registry.password", From: "(hidden)", To: "(hidden)"}},
+			},
+			impact: []PlayImpact{{Play: "_docker.yaml", Hosts: []string{"etcd01", "master01", "worker01"}}},
+		},
+		{
+			name: "field without a known play requires a full installation",
+			modify: func(p *Plan) {
+				p.Cluster.Version = "v1.10.6"
+			},
+			expected: PlanDiff{
+				Fields: []FieldDiff{{Path: "cluster.version", From: `"v1.10.5"`, To: `"v1.10.6"`}},
+			},
+			impact: []PlayImpact{{Play: "kubernetes.yaml", Hosts: []string{"etcd01", "master01", "worker01"}}},
+		},
+	}
+	for _, test := range tests {
+		from := validPlan()
+		to := validPlan()
+		test.modify(&to)
+		d := DiffPlans(&from, &to)
+		if !reflect.DeepEqual(d, test.expected) {
+			t.Errorf("%s: expected diff %+v, but got %+v", test.name, test.expected, d)
+		}
+		if impact := d.Impact(&to); !reflect.DeepEqual(impact, test.impact) {
+			t.Errorf("%s: expected impact %+v, but got %+v", test.name, test.impact, impact)
+		}
+	}
+}
+
+// TestInstallPlaysMatchPlaybook fails when the plays of kubernetes.yaml, or
+// their hosts, change without installPlayOrder and playHostPatterns
+func TestInstallPlaysMatchPlaybook(t *testing.T) {
+	ansibleDir := filepath.Join("..", "..", "ansible")
+	readPlaybook := func(file string) []map[string]interface{} {
+		b, err := ioutil.ReadFile(filepath.Join(ansibleDir, file))
+		if err != nil {
+			t.Fatalf("error reading playbook: %v", err)
+		}
+		plays := []map[string]interface{}{}
+		if err = yaml.Unmarshal(b, &plays); err != nil {
+			t.Fatalf("error unmarshaling playbook %q: %v", file, err)
+		}
+		return plays
+	}
+	order := []string{}
+	for _, include := range readPlaybook("kubernetes.yaml") {
+		file, ok := include["include"].(string)
+		if !ok {
+			t.Fatalf("expected kubernetes.yaml to only include playbooks, got %v", include)
+		}
+		order = append(order, file)
+	}
+	if !reflect.DeepEqual(order, installPlayOrder) {
+		t.Errorf("installPlayOrder does not match kubernetes.yaml:\nexpected %v\ngot      %v", order, installPlayOrder)
+	}
+	for _, file := range order {
+		for _, play := range readPlaybook(file) {
+			if hosts := play["hosts"]; hosts != playHostPatterns[file] {
+				t.Errorf("expected the host pattern of %q in playHostPatterns to be %q, got %q", file, hosts, playHostPatterns[file])
+			}
+		}
+	}
+}
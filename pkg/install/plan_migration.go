@@ -0,0 +1,195 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// planAPIVersionUnversioned is the version of plan files that were
+	// written before the apiVersion field was introduced.
+	planAPIVersionUnversioned = ""
+	planAPIVersionV1          = "v1"
+	// CurrentPlanAPIVersion is the version of the plan file schema that is
+	// supported by this version of kismatic.
+	CurrentPlanAPIVersion = planAPIVersionV1
+)
+
+// A planMigration converts a plan from one version of the plan file schema
+// to the next one.
+type planMigration struct {
+	// the version the migration produces
+	to string
+	// the function that performs the migration in place
+	migrate func(p *Plan)
+}
+
+// planMigrations is the chain of migrations, keyed by the version they migrate from.
+// When the plan file schema changes, add the new version and a migration
+// from the previous version, and bump CurrentPlanAPIVersion.
+var planMigrations = map[string]planMigration{
+	planAPIVersionUnversioned: {to: planAPIVersionV1, migrate: migrateUnversionedToV1},
+}
+
+// PlanMigration is the result of migrating a plan file to the current version
+// of the plan file schema.
+type PlanMigration struct {
+	// The version of the plan file before the migration
+	FromVersion string
+	// The version of the plan file after the migration
+	ToVersion string
+	// The migrated plan, with the defaults set
+	Plan *Plan
+	// The contents of the plan file before the migration
+	Original []byte
+	// The contents of the plan file after the migration
+	Migrated []byte
+}
+
+// Required returns true if the plan file is not at the current version
+func (m PlanMigration) Required() bool {
+	return m.FromVersion != m.ToVersion
+}
+
+// migratePlan applies the chain of migrations to the plan, starting at the
+// version declared in the plan, until it reaches the current version. A plan
+// that declares the current version must not set deprecated fields, as they
+// would be ignored.
+func migratePlan(p *Plan) error {
+	if p.APIVersion == CurrentPlanAPIVersion {
+		if fields := deprecatedPlanFields(p); len(fields) > 0 {
+			return fmt.Errorf("plan file version %q does not support the deprecated fields %s. Replace them with their current equivalent", p.APIVersion, strings.Join(fields, ", "))
+		}
+		return nil
+	}
+	for p.APIVersion != CurrentPlanAPIVersion {
+		m, ok := planMigrations[p.APIVersion]
+		if !ok {
+			return fmt.Errorf("plan file version %q is not supported by this version of kismatic. Supported version is %q", p.APIVersion, CurrentPlanAPIVersion)
+		}
+		m.migrate(p)
+		p.APIVersion = m.to
+	}
+	return nil
+}
+
+// migrateUnversionedToV1 moves the values of the fields that were deprecated
+// before the plan file was versioned to their replacements, and removes the
+// deprecated fields from the plan.
+func migrateUnversionedToV1(p *Plan) {
+	// set load_balancer from fqdn:6443
+	// set extra_sans from short_name
+	if p.Master.LoadBalancer == "" {
+		if p.Master.LoadBalancedShortName != nil && *p.Master.LoadBalancedShortName != "" {
+			if p.Cluster.Certificates.APIServerCertExtraSANs != "" {
+				p.Cluster.Certificates.APIServerCertExtraSANs = p.Cluster.Certificates.APIServerCertExtraSANs + ","
+			}
+			p.Cluster.Certificates.APIServerCertExtraSANs = p.Cluster.Certificates.APIServerCertExtraSANs + *p.Master.LoadBalancedShortName
+		}
+		if p.Master.LoadBalancedFQDN != nil && *p.Master.LoadBalancedFQDN != "" {
+			p.Master.LoadBalancer = *p.Master.LoadBalancedFQDN + ":6443"
+		}
+	}
+	p.Master.LoadBalancedFQDN = nil
+	p.Master.LoadBalancedShortName = nil
+
+	// package_manager moved from features: to add_ons: after KET v1.3.3
+	if p.Features != nil && p.Features.PackageManager != nil {
+		p.AddOns.PackageManager.Disable = !p.Features.PackageManager.Enabled
+		// KET v1.3.3 did not have a provider field
+		p.AddOns.PackageManager.Provider = ket133PackageManagerProvider
+	}
+	p.Features = nil
+
+	// allow_package_installation renamed to disable_package_installation after KET v1.4.0
+	if p.Cluster.AllowPackageInstallation != nil {
+		p.Cluster.DisablePackageInstallation = !*p.Cluster.AllowPackageInstallation
+	}
+	p.Cluster.AllowPackageInstallation = nil
+
+	// networking.type moved to the calico options after KET v1.5.0
+	if p.Cluster.Networking.Type != "" && p.AddOns.CNI == nil {
+		p.AddOns.CNI = &CNI{Provider: cniProviderCalico}
+		p.AddOns.CNI.Options.Calico.Mode = p.Cluster.Networking.Type
+	}
+	p.Cluster.Networking.Type = ""
+
+	// registry address and port were merged into server
+	if p.DockerRegistry.Server == "" && p.DockerRegistry.Address != "" && p.DockerRegistry.Port != 0 {
+		p.DockerRegistry.Server = fmt.Sprintf("%s:%d", p.DockerRegistry.Address, p.DockerRegistry.Port)
+	}
+	p.DockerRegistry.Address = ""
+	p.DockerRegistry.Port = 0
+
+	// direct_lvm was replaced by the storage driver options and direct_lvm_block_device
+	if p.Docker.Storage.DirectLVM != nil && p.Docker.Storage.DirectLVM.Enabled && len(p.Docker.Storage.Opts) == 0 {
+		p.Docker.Storage.Driver = "devicemapper"
+		p.Docker.Storage.Opts = map[string]string{
+			"dm.thinpooldev":           "/dev/mapper/docker-thinpool",
+			"dm.use_deferred_removal":  "true",
+			"dm.use_deferred_deletion": fmt.Sprintf("%t", p.Docker.Storage.DirectLVM.EnableDeferredDeletion),
+		}
+		p.Docker.Storage.DirectLVMBlockDevice.Path = p.Docker.Storage.DirectLVM.BlockDevice
+		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolPercent = "95"
+		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolMetaPercent = "1"
+		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendThreshold = "80"
+		p.Docker.Storage.DirectLVMBlockDevice.ThinpoolAutoextendPercent = "20"
+		p.Docker.Storage.DirectLVM = nil
+	}
+	// direct_lvm cannot be migrated when storage options were also set, as it
+	// would override them. Keep it so that it is not silently lost.
+	if p.Docker.Storage.DirectLVM != nil && !p.Docker.Storage.DirectLVM.Enabled {
+		p.Docker.Storage.DirectLVM = nil
+	}
+
+	// heapster options were moved under heapster: and influxdb: after KET v1.5.0
+	if p.AddOns.HeapsterMonitoring != nil {
+		if p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas != 0 {
+			p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas = p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas
+		}
+		if p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName != "" {
+			p.AddOns.HeapsterMonitoring.Options.InfluxDB.PVCName = p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName
+		}
+		p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas = 0
+		p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName = ""
+	}
+}
+
+// deprecatedPlanFields returns the fields of the plan that are replaced by
+// the migration to v1
+func deprecatedPlanFields(p *Plan) []string {
+	fields := []string{}
+	if p.Master.LoadBalancedFQDN != nil {
+		fields = append(fields, "master.load_balanced_fqdn")
+	}
+	if p.Master.LoadBalancedShortName != nil {
+		fields = append(fields, "master.load_balanced_short_name")
+	}
+	if p.Features != nil {
+		fields = append(fields, "features")
+	}
+	if p.Cluster.AllowPackageInstallation != nil {
+		fields = append(fields, "cluster.allow_package_installation")
+	}
+	if p.Cluster.Networking.Type != "" {
+		fields = append(fields, "cluster.networking.type")
+	}
+	if p.DockerRegistry.Address != "" {
+		fields = append(fields, "docker_registry.address")
+	}
+	if p.DockerRegistry.Port != 0 {
+		fields = append(fields, "docker_registry.port")
+	}
+	if p.Docker.Storage.DirectLVM != nil {
+		fields = append(fields, "docker.storage.direct_lvm")
+	}
+	if p.AddOns.HeapsterMonitoring != nil {
+		if p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas != 0 {
+			fields = append(fields, "add_ons.heapster.options.heapster_replicas")
+		}
+		if p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName != "" {
+			fields = append(fields, "add_ons.heapster.options.influxdb_pvc_name")
+		}
+	}
+	return fields
+}
@@ -0,0 +1,146 @@
+package install
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigratePlanUnversioned(t *testing.T) {
+	p := &Plan{}
+	lb := "lb.example.com"
+	p.Master.LoadBalancedFQDN = &lb
+	p.Cluster.Networking.Type = "routed"
+	p.DockerRegistry.Address = "10.0.0.1"
+	p.DockerRegistry.Port = 8443
+	p.AddOns.HeapsterMonitoring = &HeapsterMonitoring{}
+	p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas = 3
+	p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName = "influx"
+	p.Docker.Storage.DirectLVM = &DockerStorageDirectLVMDeprecated{
+		Enabled:     true,
+		BlockDevice: "/dev/sdb",
+	}
+
+	if err := migratePlan(p); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+
+	if p.APIVersion != CurrentPlanAPIVersion {
+		t.Errorf("expected plan version to be %q, but got %q", CurrentPlanAPIVersion, p.APIVersion)
+	}
+	if p.Master.LoadBalancer != "lb.example.com:6443" || p.Master.LoadBalancedFQDN != nil {
+		t.Errorf("expected master.load_balanced_fqdn to be migrated to master.load_balancer, got %q", p.Master.LoadBalancer)
+	}
+	if p.AddOns.CNI == nil || p.AddOns.CNI.Options.Calico.Mode != "routed" || p.Cluster.Networking.Type != "" {
+		t.Errorf("expected cluster.networking.type to be migrated to add_ons.cni.options.calico.mode")
+	}
+	if p.DockerRegistry.Server != "10.0.0.1:8443" || p.DockerRegistry.Address != "" || p.DockerRegistry.Port != 0 {
+		t.Errorf("expected docker_registry.address and port to be migrated to docker_registry.server, got %q", p.DockerRegistry.Server)
+	}
+	if p.AddOns.HeapsterMonitoring.Options.Heapster.Replicas != 3 || p.AddOns.HeapsterMonitoring.Options.HeapsterReplicas != 0 {
+		t.Errorf("expected heapster_replicas to be migrated to heapster.replicas")
+	}
+	if p.AddOns.HeapsterMonitoring.Options.InfluxDB.PVCName != "influx" || p.AddOns.HeapsterMonitoring.Options.InfluxDBPVCName != "" {
+		t.Errorf("expected influxdb_pvc_name to be migrated to influxdb.pvc_name")
+	}
+	if p.Docker.Storage.DirectLVM != nil || p.Docker.Storage.DirectLVMBlockDevice.Path != "/dev/sdb" {
+		t.Errorf("expected docker.storage.direct_lvm to be migrated to docker.storage.direct_lvm_block_device")
+	}
+}
+
+func TestMigratePlanCurrentVersion(t *testing.T) {
+	p := &Plan{APIVersion: CurrentPlanAPIVersion}
+	p.Cluster.Name = "test"
+	if err := migratePlan(p); err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if p.AddOns.CNI != nil {
+		t.Errorf("expected plan at the current version to not be modified")
+	}
+
+	// deprecated fields would be ignored at the current version
+	p.Cluster.Networking.Type = "routed"
+	err := migratePlan(p)
+	if err == nil || !strings.Contains(err.Error(), "cluster.networking.type") {
+		t.Errorf("expected the deprecated field to be rejected, got: %v", err)
+	}
+}
+
+func TestMigratePlanUnsupportedVersion(t *testing.T) {
+	p := &Plan{APIVersion: "v1000"}
+	if err := migratePlan(p); err == nil {
+		t.Errorf("expected an error migrating a plan with an unsupported version, but didn't get one")
+	}
+}
+
+func TestFilePlannerMigrate(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-migrate")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	file := filepath.Join(tmp, "kismatic-cluster.yaml")
+	original := []byte("cluster:\n  name: test\n  allow_package_installation: false\n")
+	if err = ioutil.WriteFile(file, original, 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+
+	fp := &FilePlanner{File: file}
+	m, err := fp.Migrate()
+	if err != nil {
+		t.Fatalf("unexpected error migrating plan: %v", err)
+	}
+	if !m.Required() {
+		t.Errorf("expected migration to be required for unversioned plan")
+	}
+	if m.FromVersion != "" || m.ToVersion != CurrentPlanAPIVersion {
+		t.Errorf("expected migration from %q to %q, got from %q to %q", "", CurrentPlanAPIVersion, m.FromVersion, m.ToVersion)
+	}
+	if !bytes.Equal(m.Original, original) {
+		t.Errorf("expected original contents to be the contents of the plan file")
+	}
+	if bytes.Contains(m.Migrated, []byte("allow_package_installation")) {
+		t.Errorf("expected migrated plan to not contain deprecated fields:\n%s", m.Migrated)
+	}
+	if !m.Plan.Cluster.DisablePackageInstallation {
+		t.Errorf("expected cluster.allow_package_installation to be migrated to cluster.disable_package_installation")
+	}
+	// the defaults are set on the plan, but are not written to the plan file
+	if m.Plan.Cluster.Certificates.CAExpiry != defaultCAExpiry {
+		t.Errorf("expected the defaults to be set on the migrated plan")
+	}
+	if bytes.Contains(m.Migrated, []byte(defaultCAExpiry)) {
+		t.Errorf("expected migrated plan to not contain the defaults:\n%s", m.Migrated)
+	}
+	// the plan file itself must not be modified
+	onDisk, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error reading plan file: %v", err)
+	}
+	if !bytes.Equal(onDisk, original) {
+		t.Errorf("expected plan file to not be modified")
+	}
+}
+
+func TestFilePlannerMigrateConflictingDeprecatedField(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-migrate")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	file := filepath.Join(tmp, "kismatic-cluster.yaml")
+	original := []byte("docker:\n  storage:\n    opts:\n      dm.basesize: 20G\n    direct_lvm:\n      enabled: true\n      block_device: /dev/sdb\n")
+	if err = ioutil.WriteFile(file, original, 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+
+	fp := &FilePlanner{File: file}
+	// the plan would be rejected once written at the current version
+	_, err = fp.Migrate()
+	if err == nil || !strings.Contains(err.Error(), "docker.storage.direct_lvm") {
+		t.Errorf("expected the deprecated field that cannot be migrated to be reported, got: %v", err)
+	}
+}
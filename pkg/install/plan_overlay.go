@@ -0,0 +1,127 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// overlayPatchKey is set to overlayPatchDelete on a node of an overlay to
+// remove the node from the node group
+const (
+	overlayPatchKey    = "$patch"
+	overlayPatchDelete = "delete"
+)
+
+// overlayListMergeKeys are the lists of the plan file that are merged by the
+// value of a key of their items, instead of being replaced by the overlay.
+var overlayListMergeKeys = map[string]string{
+	"etcd.nodes":    "host",
+	"master.nodes":  "host",
+	"worker.nodes":  "host",
+	"ingress.nodes": "host",
+	"storage.nodes": "host",
+}
+
+// mergePlanOverlays merges the overlay files on top of the base plan file, in
+// order, and returns the resulting plan file.
+// Maps, such as option overrides and labels, are merged key by key, and a key
+// that is set to null in an overlay is removed. Nodes are merged by host.
+// All other values, including lists, are replaced by the value in the overlay.
+func mergePlanOverlays(base []byte, overlays []string) ([]byte, error) {
+	merged := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(base, &merged); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plan: %v", err)
+	}
+	for _, file := range overlays {
+		d, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read overlay file: %v", err)
+		}
+		overlay := map[interface{}]interface{}{}
+		if err = yaml.Unmarshal(d, &overlay); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal overlay %q: %v", file, err)
+		}
+		m, err := mergeOverlayValue("", merged, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("error merging overlay %q: %v", file, err)
+		}
+		merged = m.(map[interface{}]interface{})
+	}
+	return yaml.Marshal(merged)
+}
+
+func mergeOverlayValue(path string, base, overlay interface{}) (interface{}, error) {
+	switch o := overlay.(type) {
+	case map[interface{}]interface{}:
+		b, ok := base.(map[interface{}]interface{})
+		if !ok {
+			return o, nil
+		}
+		for k, v := range o {
+			if v == nil {
+				delete(b, k)
+				continue
+			}
+			m, err := mergeOverlayValue(overlayPath(path, k), b[k], v)
+			if err != nil {
+				return nil, err
+			}
+			b[k] = m
+		}
+		return b, nil
+	case []interface{}:
+		if key, ok := overlayListMergeKeys[path]; ok {
+			return mergeOverlayList(path, key, base, o)
+		}
+		return o, nil
+	default:
+		return o, nil
+	}
+}
+
+// mergeOverlayList merges the items of the lists that have the same value for
+// the key. Items that are only in the overlay are added at the end of the list.
+func mergeOverlayList(path string, key string, base interface{}, overlay []interface{}) (interface{}, error) {
+	b, _ := base.([]interface{})
+	for _, item := range overlay {
+		o, ok := item.(map[interface{}]interface{})
+		if !ok || o[key] == nil {
+			return nil, fmt.Errorf("items of %q must have a %q", path, key)
+		}
+		i := indexOfItem(b, key, o[key])
+		if o[overlayPatchKey] == overlayPatchDelete {
+			if i != -1 {
+				b = append(b[:i], b[i+1:]...)
+			}
+			continue
+		}
+		if i == -1 {
+			b = append(b, o)
+			continue
+		}
+		m, err := mergeOverlayValue(path, b[i], o)
+		if err != nil {
+			return nil, err
+		}
+		b[i] = m
+	}
+	return b, nil
+}
+
+func indexOfItem(items []interface{}, key string, value interface{}) int {
+	for i, item := range items {
+		if m, ok := item.(map[interface{}]interface{}); ok && m[key] == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func overlayPath(parent string, key interface{}) string {
+	if parent == "" {
+		return fmt.Sprint(key)
+	}
+	return fmt.Sprintf("%s.%v", parent, key)
+}
@@ -0,0 +1,128 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const overlayTestBasePlan = `cluster:
+  name: base
+  version: v1.10.5
+  kube_apiserver:
+    option_overrides:
+      v: "2"
+      runtime-config: batch/v2alpha1=true
+additional_files:
+- source: /tmp/a
+  destination: /tmp/a
+  hosts: [all]
+master:
+  expected_count: 1
+  nodes:
+  - host: master01
+    ip: 10.0.0.1
+worker:
+  expected_count: 2
+  nodes:
+  - host: worker01
+    ip: 10.0.0.2
+    labels:
+      env: base
+      tier: web
+  - host: worker02
+    ip: 10.0.0.3
+`
+
+func TestFilePlannerOverlays(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-overlays")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	files := map[string]string{
+		"base.yaml": overlayTestBasePlan,
+		"prod.yaml": `cluster:
+  name: prod
+  kube_apiserver:
+    option_overrides:
+      v: "4"
+      runtime-config: null
+additional_files: []
+worker:
+  expected_count: 2
+  nodes:
+  - host: worker01
+    internalip: 192.168.0.2
+    labels:
+      env: prod
+  - host: worker02
+    $patch: delete
+  - host: worker03
+    ip: 10.0.0.4
+`,
+		"extra.yaml": `cluster:
+  kube_apiserver:
+    option_overrides:
+      audit-log-maxage: "30"
+`,
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(tmp, name), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+	}
+	fp := &FilePlanner{
+		File:     filepath.Join(tmp, "base.yaml"),
+		Overlays: []string{filepath.Join(tmp, "prod.yaml"), filepath.Join(tmp, "extra.yaml")},
+	}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	if p.Cluster.Name != "prod" {
+		t.Errorf("expected cluster name to be overridden, got %q", p.Cluster.Name)
+	}
+	if p.Cluster.Version != "v1.10.5" {
+		t.Errorf("expected cluster version from the base plan, got %q", p.Cluster.Version)
+	}
+	expectedOverrides := map[string]string{"v": "4", "audit-log-maxage": "30"}
+	if !reflect.DeepEqual(p.Cluster.APIServerOptions.Overrides, expectedOverrides) {
+		t.Errorf("expected option overrides %v, got %v", expectedOverrides, p.Cluster.APIServerOptions.Overrides)
+	}
+	if len(p.AdditionalFiles) != 0 {
+		t.Errorf("expected additional files to be replaced, got %v", p.AdditionalFiles)
+	}
+	expectedWorkers := []Node{
+		{Host: "worker01", IP: "10.0.0.2", InternalIP: "192.168.0.2", Labels: map[string]string{"env": "prod", "tier": "web"}},
+		{Host: "worker03", IP: "10.0.0.4"},
+	}
+	if !reflect.DeepEqual(p.Worker.Nodes, expectedWorkers) {
+		t.Errorf("expected workers\n%+v\ngot\n%+v", expectedWorkers, p.Worker.Nodes)
+	}
+	if len(p.Master.Nodes) != 1 || p.Master.Nodes[0].Host != "master01" {
+		t.Errorf("expected master nodes from the base plan, got %+v", p.Master.Nodes)
+	}
+
+	if err = fp.Write(p); err == nil {
+		t.Errorf("expected an error writing a plan that has overlays")
+	}
+}
+
+func TestFilePlannerOverlayNodeWithoutHost(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-plan-overlays")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	base := filepath.Join(tmp, "base.yaml")
+	overlay := filepath.Join(tmp, "overlay.yaml")
+	ioutil.WriteFile(base, []byte(overlayTestBasePlan), 0644)
+	ioutil.WriteFile(overlay, []byte("worker:\n  nodes:\n  - ip: 10.0.0.5\n"), 0644)
+	fp := &FilePlanner{File: base, Overlays: []string{overlay}}
+	if _, err = fp.Read(); err == nil {
+		t.Errorf("expected an error when a node of an overlay does not have a host")
+	}
+}
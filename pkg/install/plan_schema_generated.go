@@ -0,0 +1,1443 @@
+// Code generated by gen-kismatic-ref-docs. DO NOT EDIT.
+
+package install
+
+// PlanJSONSchema is the JSON Schema of the Plan type
+const PlanJSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Plan",
+  "type": "object",
+  "required": [
+    "cluster",
+    "etcd",
+    "master",
+    "worker"
+  ],
+  "properties": {
+    "add_ons": {
+      "description": "Add on configuration",
+      "type": "object",
+      "properties": {
+        "cni": {
+          "description": "The Container Networking Interface (CNI) add-on configuration.",
+          "type": "object",
+          "properties": {
+            "disable": {
+              "description": "Whether the CNI add-on is disabled. When set to true, CNI will not be installed on the cluster. Furthermore, the smoke test and any validation that depends on a functional pod network will be skipped.",
+              "type": "boolean",
+              "default": false
+            },
+            "options": {
+              "description": "The CNI options that can be configured for each CNI provider.",
+              "type": "object",
+              "properties": {
+                "calico": {
+                  "description": "The options that can be configured for the Calico CNI provider.",
+                  "type": "object",
+                  "properties": {
+                    "felix_input_mtu": {
+                      "description": "MTU for the tunnel device used if IPIP is enabled.",
+                      "type": "integer",
+                      "default": 1440
+                    },
+                    "ip6_autodetection_method": {
+                      "description": "IP6AutodetectionMethod is used to detect the IPv6 address of the host when the pod network has an IPv6 CIDR block. The value gets set in IP6_AUTODETECTION_METHOD variable in the pod.",
+                      "type": "string",
+                      "default": "first-found"
+                    },
+                    "ip_autodetection_method": {
+                      "description": "IPAutodetectionMethod is used to detect the IPv4 address of the host. The value gets set in IP_AUTODETECTION_METHOD variable in the pod.",
+                      "type": "string",
+                      "default": "first-found"
+                    },
+                    "log_level": {
+                      "description": "The logging level for the CNI plugin",
+                      "type": "string",
+                      "default": "info",
+                      "enum": [
+                        "warning",
+                        "info",
+                        "debug"
+                      ]
+                    },
+                    "mode": {
+                      "description": "The datapath technique that should be configured in Calico.",
+                      "type": "string",
+                      "default": "overlay",
+                      "enum": [
+                        "overlay",
+                        "routed"
+                      ]
+                    },
+                    "workload_mtu": {
+                      "description": "MTU for the workload interface, configures the CNI config.",
+                      "type": "integer",
+                      "default": 1500
+                    }
+                  }
+                },
+                "portmap": {
+                  "description": "The options that can be configured for the Portmap CNI provider.",
+                  "type": "object",
+                  "properties": {
+                    "disable": {
+                      "description": "Disable the portmap CNI plugin",
+                      "type": "boolean",
+                      "default": false
+                    }
+                  }
+                },
+                "weave": {
+                  "description": "The options that can be configured for the Weave CNI provider.",
+                  "type": "object",
+                  "properties": {
+                    "password": {
+                      "description": "The password to use for network traffic encryption. Can be a reference to a secret that is stored outside of the plan file: ` + "`" + `${env:NAME}` + "`" + `, ` + "`" + `file:///path/to/file` + "`" + ` or ` + "`" + `exec:command` + "`" + `.",
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            },
+            "provider": {
+              "description": "The CNI provider that should be installed on the cluster.",
+              "type": "string",
+              "default": "calico",
+              "enum": [
+                "calico",
+                "weave",
+                "contiv",
+                "custom"
+              ]
+            }
+          }
+        },
+        "dashboard": {
+          "description": "The Dashboard add-on configuration.",
+          "type": "object",
+          "properties": {
+            "disable": {
+              "description": "Whether the dashboard add-on should be disabled. When set to true, the Kubernetes Dashboard will not be installed on the cluster.",
+              "type": "boolean",
+              "default": false
+            },
+            "options": {
+              "description": "The options that can be configured for the Dashboard add-on",
+              "type": "object",
+              "properties": {
+                "node_port": {
+                  "description": "When using NodePort set the port to use. When left empty Kubernetes will allocate a random port.",
+                  "type": "string"
+                },
+                "service_type": {
+                  "description": "Kubernetes service type of the Dashboard service.",
+                  "type": "string",
+                  "default": "ClusterIP",
+                  "enum": [
+                    "ClusterIP",
+                    "NodePort",
+                    "LoadBalancer",
+                    "ExternalName"
+                  ]
+                }
+              }
+            }
+          }
+        },
+        "dns": {
+          "description": "The DNS add-on configuration.",
+          "type": "object",
+          "required": [
+            "provider"
+          ],
+          "properties": {
+            "disable": {
+              "description": "Whether the DNS add-on should be disabled. When set to true, no DNS solution will be deployed on the cluster.",
+              "type": "boolean"
+            },
+            "options": {
+              "description": "The options that can be configured for the cluster DNS add-on",
+              "type": "object",
+              "properties": {
+                "replicas": {
+                  "description": "Number of cluster DNS replicas that should be scheduled on the cluster.",
+                  "type": "integer",
+                  "default": 2
+                }
+              }
+            },
+            "provider": {
+              "description": "This property indicates the in-cluster DNS provider.",
+              "type": "string",
+              "default": "kubedns",
+              "enum": [
+                "kubedns",
+                "coredns"
+              ]
+            }
+          }
+        },
+        "heapster": {
+          "description": "The Heapster Monitoring add-on configuration.",
+          "type": "object",
+          "properties": {
+            "disable": {
+              "description": "Whether the Heapster add-on should be disabled. When set to true, Heapster and InfluxDB will not be deployed on the cluster.",
+              "type": "boolean",
+              "default": false
+            },
+            "options": {
+              "description": "The options that can be configured for the Heapster add-on",
+              "type": "object",
+              "properties": {
+                "heapster": {
+                  "description": "The Heapster configuration options.",
+                  "type": "object",
+                  "properties": {
+                    "replicas": {
+                      "description": "Number of Heapster replicas that should be scheduled on the cluster.",
+                      "type": "integer",
+                      "default": 2
+                    },
+                    "service_type": {
+                      "description": "Kubernetes service type of the Heapster service.",
+                      "type": "string",
+                      "default": "ClusterIP",
+                      "enum": [
+                        "ClusterIP",
+                        "NodePort",
+                        "LoadBalancer",
+                        "ExternalName"
+                      ]
+                    },
+                    "sink": {
+                      "description": "URL of the backend store that will be used as the Heapster sink.",
+                      "type": "string",
+                      "default": "influxdb:http://heapster-influxdb.kube-system.svc:8086"
+                    }
+                  }
+                },
+                "heapster_replicas": {
+                  "description": "Number of Heapster replicas that should be scheduled on the cluster.",
+                  "type": "integer",
+                  "deprecated": true
+                },
+                "influxdb": {
+                  "description": "The InfluxDB configuration options.",
+                  "type": "object",
+                  "properties": {
+                    "pvc_name": {
+                      "description": "Name of the Persistent Volume Claim that will be used by InfluxDB. This PVC must be created after the installation. If not set, InfluxDB will be configured with ephemeral storage.",
+                      "type": "string"
+                    }
+                  }
+                },
+                "influxdb_pvc_name": {
+                  "description": "Name of the Persistent Volume Claim that will be used by InfluxDB. When set, this PVC must be created after the installation. If not set, InfluxDB will be configured with ephemeral storage.",
+                  "type": "string",
+                  "deprecated": true
+                }
+              }
+            }
+          }
+        },
+        "metrics_server": {
+          "description": "Metrics Server add-on configuration. A cluster-wide aggregator of resource usage data. Required for Horizontal Pod Autoscaler to function properly.",
+          "type": "object",
+          "properties": {
+            "disable": {
+              "description": "Whether the metrics-server add-on should be disabled. When set to true, metrics-server will not be deployed on the cluster.",
+              "type": "boolean",
+              "default": false
+            }
+          }
+        },
+        "package_manager": {
+          "description": "The PackageManager add-on configuration.",
+          "type": "object",
+          "required": [
+            "provider"
+          ],
+          "properties": {
+            "disable": {
+              "description": "Whether the package manager add-on should be disabled. When set to true, the package manager will not be installed on the cluster.",
+              "type": "boolean",
+              "default": false
+            },
+            "options": {
+              "description": "The PackageManager options.",
+              "type": "object",
+              "properties": {
+                "helm": {
+                  "description": "Helm PackageManager options",
+                  "type": "object",
+                  "properties": {
+                    "namespace": {
+                      "description": "Namespace to deploy tiller",
+                      "type": "string",
+                      "default": "kube-system"
+                    }
+                  }
+                }
+              }
+            },
+            "provider": {
+              "description": "This property indicates the package manager provider.",
+              "type": "string",
+              "enum": [
+                "helm"
+              ]
+            }
+          }
+        },
+        "rescheduler": {
+          "description": "The Rescheduler add-on configuration. Because the Rescheduler does not have leader election and therefore can only run as a single instance in a cluster, it will be deployed as a static pod on the first master. More information about the Rescheduler can be found here: https://kubernetes.io/docs/tasks/administer-cluster/guaranteed-scheduling-critical-addon-pods/",
+          "type": "object",
+          "properties": {
+            "disable": {
+              "description": "Whether the pod rescheduler add-on should be disabled. When set to true, the rescheduler will not be installed on the cluster.",
+              "type": "boolean",
+              "default": false
+            }
+          }
+        }
+      }
+    },
+    "additional_files": {
+      "description": "A set of files or directories to copy from the local machine to any of the nodes in the cluster.",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": [
+          "hosts",
+          "source",
+          "destination"
+        ],
+        "properties": {
+          "destination": {
+            "description": "Path to the file or directory on remote machine, where file will be copied. Must be an absolute path.",
+            "type": "string"
+          },
+          "hosts": {
+            "description": "Hostname or role where additional files or directories will be copied.",
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "skip_validation": {
+            "description": "Set to true if validation will be run before the file exists on the local machine. Useful for files generated at install time, ie. assets in generated/ directory.",
+            "type": "boolean"
+          },
+          "source": {
+            "description": "Path to the file or directory on local machine. Must be an absolute path.",
+            "type": "string"
+          }
+        }
+      }
+    },
+    "apiVersion": {
+      "description": "The version of the plan file schema. Plan files without a version are treated as written by a release of KET that predates the versioning of the plan file, and are migrated when read. Use ` + "`" + `kismatic install plan migrate` + "`" + ` to update the plan file to the current version.",
+      "type": "string",
+      "default": "v1"
+    },
+    "cluster": {
+      "description": "Kubernetes cluster configuration",
+      "type": "object",
+      "required": [
+        "name"
+      ],
+      "properties": {
+        "admin_password": {
+          "description": "The password for the admin user. If provided, ABAC will be enabled in the cluster. Can be a reference to a secret that is stored outside of the plan file: ` + "`" + `${env:NAME}` + "`" + `, ` + "`" + `file:///path/to/file` + "`" + ` or ` + "`" + `exec:command` + "`" + `. This field will be removed completely in a future release.",
+          "type": "string",
+          "deprecated": true
+        },
+        "allow_package_installation": {
+          "description": "Whether KET should install the packages on the cluster nodes. Use DisablePackageInstallation instead.",
+          "type": "boolean",
+          "deprecated": true
+        },
+        "certificates": {
+          "description": "The Certificates configuration for the cluster.",
+          "type": "object",
+          "required": [
+            "expiry",
+            "ca_expiry"
+          ],
+          "properties": {
+            "apiserver_cert_extra_sans": {
+              "description": "Comma-separated list of Subject Alternative Names (SANs) to use for the API Server serving certificate. Can be both IP addresses and DNS names.",
+              "type": "string"
+            },
+            "ca_expiry": {
+              "description": "The length of time that the generated Certificate Authority should be valid for. For example: \"17520h\" for 2 years.",
+              "type": "string"
+            },
+            "expiry": {
+              "description": "The length of time that the generated certificates should be valid for. For example: \"17520h\" for 2 years.",
+              "type": "string"
+            }
+          }
+        },
+        "cloud_provider": {
+          "description": "The CloudProvider configuration for the cluster.",
+          "type": "object",
+          "properties": {
+            "config": {
+              "description": "Path to the cloud provider config file. This will be copied to all the machines in the cluster",
+              "type": "string"
+            },
+            "provider": {
+              "description": "The cloud provider that should be set in the Kubernetes components",
+              "type": "string",
+              "enum": [
+                "aws",
+                "azure",
+                "cloudstack",
+                "fake",
+                "gce",
+                "mesos",
+                "openstack",
+                "ovirt",
+                "photon",
+                "rackspace",
+                "vsphere"
+              ]
+            }
+          }
+        },
+        "disable_package_installation": {
+          "description": "Whether KET should install the packages on the cluster nodes. When true, KET will not install the required packages. Instead, it will verify that the packages have been installed by the operator.",
+          "type": "boolean"
+        },
+        "disconnected_installation": {
+          "description": "Whether the cluster nodes are disconnected from the internet. When set to ` + "`" + `true` + "`" + `, internal package repositories and a container image registry are required for installation.",
+          "type": "boolean",
+          "default": false
+        },
+        "kube_apiserver": {
+          "description": "Kubernetes API Server configuration.",
+          "type": "object",
+          "properties": {
+            "option_overrides": {
+              "description": "Listing of option overrides that are to be applied to the Kubernetes API server configuration. This is an advanced feature that can prevent the API server from starting up if invalid configuration is provided.",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "kube_controller_manager": {
+          "description": "Kubernetes Controller Manager configuration.",
+          "type": "object",
+          "properties": {
+            "option_overrides": {
+              "description": "Listing of option overrides that are to be applied to the Kubernetes Controller Manager configuration. This is an advanced feature that can prevent the Controller Manager from starting up if invalid configuration is provided.",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "kube_proxy": {
+          "description": "Kubernetes Proxy configuration.",
+          "type": "object",
+          "properties": {
+            "option_overrides": {
+              "description": "Listing of option overrides that are to be applied to the Kubernetes Proxy configuration. This is an advanced feature that can prevent the Proxy from starting up if invalid configuration is provided.",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "kube_scheduler": {
+          "description": "Kubernetes Scheduler configuration.",
+          "type": "object",
+          "properties": {
+            "option_overrides": {
+              "description": "Listing of option overrides that are to be applied to the Kubernetes Scheduler configuration. This is an advanced feature that can prevent the Scheduler from starting up if invalid configuration is provided.",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "kubelet": {
+          "description": "Kubelet configuration applied to all nodes.",
+          "type": "object",
+          "properties": {
+            "option_overrides": {
+              "description": "Listing of option overrides that are to be applied to the Kubelet configurations. This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "name": {
+          "description": "Name of the cluster to be used when generating assets that require a cluster name, such as kubeconfig files and certificates.",
+          "type": "string"
+        },
+        "networking": {
+          "description": "The Networking configuration for the cluster.",
+          "type": "object",
+          "required": [
+            "pod_cidr_block",
+            "service_cidr_block"
+          ],
+          "properties": {
+            "http_proxy": {
+              "description": "The URL of the proxy that should be used for HTTP connections.",
+              "type": "string"
+            },
+            "https_proxy": {
+              "description": "The URL of the proxy that should be used for HTTPS connections.",
+              "type": "string"
+            },
+            "no_proxy": {
+              "description": "Comma-separated list of host names and/or IPs for which connections should not go through a proxy. All nodes' 'host' and 'IPs' are always set.",
+              "type": "string"
+            },
+            "pod_cidr_block": {
+              "description": "The pod network's CIDR block. For example: ` + "`" + `172.16.0.0/16` + "`" + ` An IPv6 CIDR block, such as ` + "`" + `fd00:10:244::/64` + "`" + `, can be used for IPv6-only clusters. For dual-stack clusters, set a comma-separated IPv4 and IPv6 CIDR block. The first CIDR block must be of the same IP family as the service network, and is the one that is used by Kubernetes. Dual-stack pod networking is only supported with the Calico CNI provider.",
+              "type": "string"
+            },
+            "service_cidr_block": {
+              "description": "The Kubernetes service network's CIDR block. For example: ` + "`" + `172.20.0.0/16` + "`" + ` Can be an IPv6 CIDR block, such as ` + "`" + `fd00:10:96::/112` + "`" + `. Kubernetes allocates service IPs from a single IP family, so only one CIDR block is supported.",
+              "type": "string"
+            },
+            "type": {
+              "description": "The datapath technique that should be configured in Calico.",
+              "type": "string",
+              "default": "overlay",
+              "enum": [
+                "overlay",
+                "routed"
+              ],
+              "deprecated": true
+            },
+            "update_hosts_files": {
+              "description": "Whether the /etc/hosts file should be updated on the cluster nodes. When set to true, KET will update the hosts file on all nodes to include entries for all other nodes in the cluster.",
+              "type": "boolean",
+              "default": false
+            }
+          }
+        },
+        "ssh": {
+          "description": "The SSH configuration for the cluster nodes.",
+          "type": "object",
+          "required": [
+            "user",
+            "ssh_port"
+          ],
+          "properties": {
+            "bastion": {
+              "description": "The bastion host that the SSH connections to the cluster nodes go through, when the nodes are not directly reachable.",
+              "type": "object",
+              "required": [
+                "host"
+              ],
+              "properties": {
+                "host": {
+                  "description": "The hostname or IP address of the bastion host.",
+                  "type": "string"
+                },
+                "ssh_key": {
+                  "description": "The absolute path of the SSH key that should be used for accessing the bastion host via SSH. Defaults to the key of the cluster nodes.",
+                  "type": "string"
+                },
+                "ssh_port": {
+                  "description": "The port number on which the bastion host is listening for SSH connections.",
+                  "type": "integer",
+                  "default": 22
+                },
+                "user": {
+                  "description": "The user for accessing the bastion host via SSH. Defaults to the user of the cluster nodes.",
+                  "type": "string"
+                }
+              }
+            },
+            "become_password": {
+              "description": "The password for sudo on the cluster nodes, when the user can't sudo without a password. Must be a reference to a secret that is stored outside of the plan file: ${env:NAME}, file:///path or exec:command. The password can also be entered with --ask-become-pass instead.",
+              "type": "string"
+            },
+            "ssh_key": {
+              "description": "The absolute path of the SSH key that should be used for accessing the cluster nodes via SSH. The passphrase of a key that is protected by a passphrase is prompted once. Required, unless an ssh-agent is running and SSH_AUTH_SOCK is set.",
+              "type": "string"
+            },
+            "ssh_port": {
+              "description": "The port number on which cluster nodes are listening for SSH connections.",
+              "type": "integer"
+            },
+            "user": {
+              "description": "The user for accessing the cluster nodes via SSH. This user requires sudo elevation privileges on the cluster nodes.",
+              "type": "string"
+            }
+          }
+        },
+        "version": {
+          "description": "The Kubernetes version to install. If left blank will be set to the latest tested version. Only a single Minor version is supported with.",
+          "type": "string",
+          "default": "v1.10.5"
+        }
+      }
+    },
+    "docker": {
+      "description": "Configuration for the docker engine installed by KET",
+      "type": "object",
+      "properties": {
+        "disable": {
+          "description": "Set to true to disable the installation of docker container runtime on the nodes. The installer will validate that docker is installed and running prior to proceeding. Use this option if a different version of docker from the included one is required.",
+          "type": "boolean"
+        },
+        "logs": {
+          "description": "Log configuration for the docker engine.",
+          "type": "object",
+          "properties": {
+            "driver": {
+              "description": "Docker logging driver, more details https://docs.docker.com/engine/admin/logging/overview/.",
+              "type": "string",
+              "default": "json-file"
+            },
+            "opts": {
+              "description": "Driver specific options.",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "storage": {
+          "description": "Storage configuration for the docker engine.",
+          "type": "object",
+          "properties": {
+            "direct_lvm": {
+              "description": "DirectLVM is the configuration required for setting up device mapper in direct-lvm mode.",
+              "type": "object",
+              "deprecated": true,
+              "properties": {
+                "block_device": {
+                  "description": "The path to the block storage device that will be used by the devicemapper storage driver.",
+                  "type": "string"
+                },
+                "enable_deferred_deletion": {
+                  "description": "Whether deferred deletion should be enabled when using devicemapper in direct_lvm mode.",
+                  "type": "boolean",
+                  "default": false
+                },
+                "enabled": {
+                  "description": "Whether the direct_lvm mode of the devicemapper storage driver should be enabled. When set to true, a dedicated block storage device must be available on each cluster node.",
+                  "type": "boolean",
+                  "default": false
+                }
+              }
+            },
+            "direct_lvm_block_device": {
+              "description": "DirectLVMBlockDevice is the configuration required for setting up Device Mapper storage driver in direct-lvm mode. Refer to https://docs.docker.com/v17.03/engine/userguide/storagedriver/device-mapper-driver/#manage-devicemapper docs.",
+              "type": "object",
+              "properties": {
+                "path": {
+                  "description": "The path to the block device.",
+                  "type": "string"
+                },
+                "thinpool_autoextend_percent": {
+                  "description": "The percentage to increase the thin pool by when an autoextend is triggered.",
+                  "type": "string",
+                  "default": "20"
+                },
+                "thinpool_autoextend_threshold": {
+                  "description": "The threshold for when lvm should automatically extend the thin pool as a percentage of the total storage space.",
+                  "type": "string",
+                  "default": "80"
+                },
+                "thinpool_metapercent": {
+                  "description": "The percentage of space to for metadata storage from the passed in block device.",
+                  "type": "string",
+                  "default": "1"
+                },
+                "thinpool_percent": {
+                  "description": "The percentage of space to use for storage from the passed in block device.",
+                  "type": "string",
+                  "default": "95"
+                }
+              }
+            },
+            "driver": {
+              "description": "Docker storage driver, more details https://docs.docker.com/engine/userguide/storagedriver/. Leave empty to have docker automatically select the driver.",
+              "type": "string"
+            },
+            "opts": {
+              "description": "Driver specific options",
+              "type": "object",
+              "additionalProperties": {
+                "type": "string"
+              }
+            }
+          }
+        }
+      }
+    },
+    "docker_registry": {
+      "description": "Docker registry configuration",
+      "type": "object",
+      "properties": {
+        "CA": {
+          "description": "The absolute path of the Certificate Authority that should be installed on all cluster nodes that have a docker daemon. This is required to establish trust between the daemons and the private registry when the registry is using a self-signed certificate.",
+          "type": "string"
+        },
+        "address": {
+          "description": "The hostname or IP address of a private container image registry. When performing a disconnected installation, this registry will be used to fetch all the required container images.",
+          "type": "string",
+          "deprecated": true
+        },
+        "password": {
+          "description": "The password that should be used when connecting to a registry that has authentication enabled. Otherwise leave blank for unauthenticated access. Can be a reference to a secret that is stored outside of the plan file: ` + "`" + `${env:NAME}` + "`" + `, ` + "`" + `file:///path/to/file` + "`" + ` or ` + "`" + `exec:command` + "`" + `.",
+          "type": "string"
+        },
+        "port": {
+          "description": "The port on which the private container image registry is listening on.",
+          "type": "integer",
+          "deprecated": true
+        },
+        "server": {
+          "description": "The hostname or IP address and port of a private container image registry. Do not include http or https. When performing a disconnected installation, this registry will be used to fetch all the required container images.",
+          "type": "string"
+        },
+        "username": {
+          "description": "The username that should be used when connecting to a registry that has authentication enabled. Otherwise leave blank for unauthenticated access.",
+          "type": "string"
+        }
+      }
+    },
+    "etcd": {
+      "description": "Etcd nodes of the cluster",
+      "type": "object",
+      "required": [
+        "expected_count"
+      ],
+      "properties": {
+        "expected_count": {
+          "description": "Number of nodes.",
+          "type": "integer"
+        },
+        "inventory_source": {
+          "description": "The source the list of nodes is read from, instead of listing them in the plan file.",
+          "type": "object",
+          "required": [
+            "type",
+            "path"
+          ],
+          "properties": {
+            "args": {
+              "description": "Arguments passed to the executable.",
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            },
+            "path": {
+              "description": "Path to the file or to the executable.",
+              "type": "string"
+            },
+            "type": {
+              "description": "The type of the inventory source. A csv file has a header row with the host, ip, internalip and labels columns, where labels are formatted as key=value pairs separated by semicolons. A json file contains a list of objects with the host, ip, internalip and labels fields. An exec source is an executable that prints the nodes in the json format. The name of the node group is passed to the executable in the KISMATIC_NODE_GROUP environment variable.",
+              "type": "string",
+              "enum": [
+                "csv",
+                "json",
+                "exec"
+              ]
+            }
+          }
+        },
+        "nodes": {
+          "description": "List of nodes. Required, unless the nodes are read from an inventory source.",
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": [
+              "host",
+              "ip"
+            ],
+            "properties": {
+              "host": {
+                "description": "The hostname of the node. The hostname is verified in the validation phase of the installation.",
+                "type": "string"
+              },
+              "internalip": {
+                "description": "The internal (or private) IP address of the node. If set, this IP will be used when configuring cluster components.",
+                "type": "string"
+              },
+              "ip": {
+                "description": "The IP address of the node. This is the IP address that will be used to connect to the node over SSH.",
+                "type": "string"
+              },
+              "kubelet": {
+                "description": "Kubelet configuration applied to this node. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "option_overrides": {
+                    "description": "Listing of option overrides that are to be applied to the Kubelet configurations. This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.",
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  }
+                }
+              },
+              "labels": {
+                "description": "Labels to add when installing the node in the cluster. If a node is defined under multiple roles, the labels for that node will be merged. If a label is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence. It is recommended to use reverse-DNS notation to avoid collision with other labels.",
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              },
+              "ssh": {
+                "description": "SSH configuration of this node, overriding the SSH configuration of the cluster. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "ssh_key": {
+                    "description": "The absolute path of the SSH key that should be used for accessing the node via SSH.",
+                    "type": "string"
+                  },
+                  "ssh_port": {
+                    "description": "The port number on which the node is listening for SSH connections.",
+                    "type": "integer"
+                  },
+                  "user": {
+                    "description": "The user for accessing the node via SSH. This user requires sudo elevation privileges on the node.",
+                    "type": "string"
+                  }
+                }
+              },
+              "taints": {
+                "description": "Taints to add when installing the node in the cluster. If a node is defined under multiple roles, the taints for that node will be merged. If a taint is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence.",
+                "type": "array",
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "effect": {
+                      "description": "Effect for the taint",
+                      "type": "string",
+                      "enum": [
+                        "NoSchedule",
+                        "PreferNoSchedule",
+                        "NoExecute"
+                      ]
+                    },
+                    "key": {
+                      "description": "Key for the taint",
+                      "type": "string"
+                    },
+                    "value": {
+                      "description": "Value for the taint",
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "features": {
+      "description": "Feature configuration",
+      "type": "object",
+      "deprecated": true,
+      "properties": {
+        "package_manager": {
+          "description": "The PackageManager feature configuration.",
+          "type": "object",
+          "deprecated": true,
+          "properties": {
+            "enabled": {
+              "description": "Whether the package manager add-on should be enabled.",
+              "type": "boolean",
+              "deprecated": true
+            }
+          }
+        }
+      }
+    },
+    "ingress": {
+      "description": "Ingress nodes of the cluster",
+      "type": "object",
+      "required": [
+        "expected_count"
+      ],
+      "properties": {
+        "expected_count": {
+          "description": "Number of nodes.",
+          "type": "integer"
+        },
+        "inventory_source": {
+          "description": "The source the list of nodes is read from, instead of listing them in the plan file.",
+          "type": "object",
+          "required": [
+            "type",
+            "path"
+          ],
+          "properties": {
+            "args": {
+              "description": "Arguments passed to the executable.",
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            },
+            "path": {
+              "description": "Path to the file or to the executable.",
+              "type": "string"
+            },
+            "type": {
+              "description": "The type of the inventory source. A csv file has a header row with the host, ip, internalip and labels columns, where labels are formatted as key=value pairs separated by semicolons. A json file contains a list of objects with the host, ip, internalip and labels fields. An exec source is an executable that prints the nodes in the json format. The name of the node group is passed to the executable in the KISMATIC_NODE_GROUP environment variable.",
+              "type": "string",
+              "enum": [
+                "csv",
+                "json",
+                "exec"
+              ]
+            }
+          }
+        },
+        "nodes": {
+          "description": "List of nodes. Required, unless the nodes are read from an inventory source.",
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": [
+              "host",
+              "ip"
+            ],
+            "properties": {
+              "host": {
+                "description": "The hostname of the node. The hostname is verified in the validation phase of the installation.",
+                "type": "string"
+              },
+              "internalip": {
+                "description": "The internal (or private) IP address of the node. If set, this IP will be used when configuring cluster components.",
+                "type": "string"
+              },
+              "ip": {
+                "description": "The IP address of the node. This is the IP address that will be used to connect to the node over SSH.",
+                "type": "string"
+              },
+              "kubelet": {
+                "description": "Kubelet configuration applied to this node. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "option_overrides": {
+                    "description": "Listing of option overrides that are to be applied to the Kubelet configurations. This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.",
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  }
+                }
+              },
+              "labels": {
+                "description": "Labels to add when installing the node in the cluster. If a node is defined under multiple roles, the labels for that node will be merged. If a label is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence. It is recommended to use reverse-DNS notation to avoid collision with other labels.",
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              },
+              "ssh": {
+                "description": "SSH configuration of this node, overriding the SSH configuration of the cluster. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "ssh_key": {
+                    "description": "The absolute path of the SSH key that should be used for accessing the node via SSH.",
+                    "type": "string"
+                  },
+                  "ssh_port": {
+                    "description": "The port number on which the node is listening for SSH connections.",
+                    "type": "integer"
+                  },
+                  "user": {
+                    "description": "The user for accessing the node via SSH. This user requires sudo elevation privileges on the node.",
+                    "type": "string"
+                  }
+                }
+              },
+              "taints": {
+                "description": "Taints to add when installing the node in the cluster. If a node is defined under multiple roles, the taints for that node will be merged. If a taint is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence.",
+                "type": "array",
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "effect": {
+                      "description": "Effect for the taint",
+                      "type": "string",
+                      "enum": [
+                        "NoSchedule",
+                        "PreferNoSchedule",
+                        "NoExecute"
+                      ]
+                    },
+                    "key": {
+                      "description": "Key for the taint",
+                      "type": "string"
+                    },
+                    "value": {
+                      "description": "Value for the taint",
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "master": {
+      "description": "Master nodes of the cluster",
+      "type": "object",
+      "required": [
+        "load_balancer",
+        "expected_count"
+      ],
+      "properties": {
+        "expected_count": {
+          "description": "Number of master nodes that are part of the cluster.",
+          "type": "integer"
+        },
+        "inventory_source": {
+          "description": "The source the list of master nodes is read from, instead of listing them in the plan file.",
+          "type": "object",
+          "required": [
+            "type",
+            "path"
+          ],
+          "properties": {
+            "args": {
+              "description": "Arguments passed to the executable.",
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            },
+            "path": {
+              "description": "Path to the file or to the executable.",
+              "type": "string"
+            },
+            "type": {
+              "description": "The type of the inventory source. A csv file has a header row with the host, ip, internalip and labels columns, where labels are formatted as key=value pairs separated by semicolons. A json file contains a list of objects with the host, ip, internalip and labels fields. An exec source is an executable that prints the nodes in the json format. The name of the node group is passed to the executable in the KISMATIC_NODE_GROUP environment variable.",
+              "type": "string",
+              "enum": [
+                "csv",
+                "json",
+                "exec"
+              ]
+            }
+          }
+        },
+        "load_balanced_fqdn": {
+          "description": "The FQDN of the load balancer that is fronting multiple master nodes. In the case where there is only one master node, this can be set to the IP address of the master node.",
+          "type": "string",
+          "deprecated": true
+        },
+        "load_balanced_short_name": {
+          "description": "The short name of the load balancer that is fronting multiple master nodes. In the case where there is only one master node, this can be set to the IP address of the master nodes.",
+          "type": "string",
+          "deprecated": true
+        },
+        "load_balancer": {
+          "description": "The IP or DNS and Port of the load balancer that is fronting multiple master nodes. In the case where there no load balancer this can be set to the IP address of the master node with port '6443'.",
+          "type": "string"
+        },
+        "nodes": {
+          "description": "List of master nodes that are part of the cluster. Required, unless the nodes are read from an inventory source.",
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": [
+              "host",
+              "ip"
+            ],
+            "properties": {
+              "host": {
+                "description": "The hostname of the node. The hostname is verified in the validation phase of the installation.",
+                "type": "string"
+              },
+              "internalip": {
+                "description": "The internal (or private) IP address of the node. If set, this IP will be used when configuring cluster components.",
+                "type": "string"
+              },
+              "ip": {
+                "description": "The IP address of the node. This is the IP address that will be used to connect to the node over SSH.",
+                "type": "string"
+              },
+              "kubelet": {
+                "description": "Kubelet configuration applied to this node. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "option_overrides": {
+                    "description": "Listing of option overrides that are to be applied to the Kubelet configurations. This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.",
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  }
+                }
+              },
+              "labels": {
+                "description": "Labels to add when installing the node in the cluster. If a node is defined under multiple roles, the labels for that node will be merged. If a label is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence. It is recommended to use reverse-DNS notation to avoid collision with other labels.",
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              },
+              "ssh": {
+                "description": "SSH configuration of this node, overriding the SSH configuration of the cluster. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "ssh_key": {
+                    "description": "The absolute path of the SSH key that should be used for accessing the node via SSH.",
+                    "type": "string"
+                  },
+                  "ssh_port": {
+                    "description": "The port number on which the node is listening for SSH connections.",
+                    "type": "integer"
+                  },
+                  "user": {
+                    "description": "The user for accessing the node via SSH. This user requires sudo elevation privileges on the node.",
+                    "type": "string"
+                  }
+                }
+              },
+              "taints": {
+                "description": "Taints to add when installing the node in the cluster. If a node is defined under multiple roles, the taints for that node will be merged. If a taint is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence.",
+                "type": "array",
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "effect": {
+                      "description": "Effect for the taint",
+                      "type": "string",
+                      "enum": [
+                        "NoSchedule",
+                        "PreferNoSchedule",
+                        "NoExecute"
+                      ]
+                    },
+                    "key": {
+                      "description": "Key for the taint",
+                      "type": "string"
+                    },
+                    "value": {
+                      "description": "Value for the taint",
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "nfs": {
+      "description": "NFS volumes of the cluster.",
+      "type": "object",
+      "properties": {
+        "nfs_volume": {
+          "description": "List of NFS volumes that should be attached to the cluster during the installation.",
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": [
+              "nfs_host",
+              "mount_path"
+            ],
+            "properties": {
+              "mount_path": {
+                "description": "The path where the NFS volume should be mounted.",
+                "type": "string"
+              },
+              "nfs_host": {
+                "description": "The hostname or IP of the NFS volume.",
+                "type": "string"
+              }
+            }
+          }
+        }
+      }
+    },
+    "storage": {
+      "description": "Storage nodes of the cluster.",
+      "type": "object",
+      "required": [
+        "expected_count"
+      ],
+      "properties": {
+        "expected_count": {
+          "description": "Number of nodes.",
+          "type": "integer"
+        },
+        "inventory_source": {
+          "description": "The source the list of nodes is read from, instead of listing them in the plan file.",
+          "type": "object",
+          "required": [
+            "type",
+            "path"
+          ],
+          "properties": {
+            "args": {
+              "description": "Arguments passed to the executable.",
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            },
+            "path": {
+              "description": "Path to the file or to the executable.",
+              "type": "string"
+            },
+            "type": {
+              "description": "The type of the inventory source. A csv file has a header row with the host, ip, internalip and labels columns, where labels are formatted as key=value pairs separated by semicolons. A json file contains a list of objects with the host, ip, internalip and labels fields. An exec source is an executable that prints the nodes in the json format. The name of the node group is passed to the executable in the KISMATIC_NODE_GROUP environment variable.",
+              "type": "string",
+              "enum": [
+                "csv",
+                "json",
+                "exec"
+              ]
+            }
+          }
+        },
+        "nodes": {
+          "description": "List of nodes. Required, unless the nodes are read from an inventory source.",
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": [
+              "host",
+              "ip"
+            ],
+            "properties": {
+              "host": {
+                "description": "The hostname of the node. The hostname is verified in the validation phase of the installation.",
+                "type": "string"
+              },
+              "internalip": {
+                "description": "The internal (or private) IP address of the node. If set, this IP will be used when configuring cluster components.",
+                "type": "string"
+              },
+              "ip": {
+                "description": "The IP address of the node. This is the IP address that will be used to connect to the node over SSH.",
+                "type": "string"
+              },
+              "kubelet": {
+                "description": "Kubelet configuration applied to this node. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "option_overrides": {
+                    "description": "Listing of option overrides that are to be applied to the Kubelet configurations. This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.",
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  }
+                }
+              },
+              "labels": {
+                "description": "Labels to add when installing the node in the cluster. If a node is defined under multiple roles, the labels for that node will be merged. If a label is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence. It is recommended to use reverse-DNS notation to avoid collision with other labels.",
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              },
+              "ssh": {
+                "description": "SSH configuration of this node, overriding the SSH configuration of the cluster. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "ssh_key": {
+                    "description": "The absolute path of the SSH key that should be used for accessing the node via SSH.",
+                    "type": "string"
+                  },
+                  "ssh_port": {
+                    "description": "The port number on which the node is listening for SSH connections.",
+                    "type": "integer"
+                  },
+                  "user": {
+                    "description": "The user for accessing the node via SSH. This user requires sudo elevation privileges on the node.",
+                    "type": "string"
+                  }
+                }
+              },
+              "taints": {
+                "description": "Taints to add when installing the node in the cluster. If a node is defined under multiple roles, the taints for that node will be merged. If a taint is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence.",
+                "type": "array",
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "effect": {
+                      "description": "Effect for the taint",
+                      "type": "string",
+                      "enum": [
+                        "NoSchedule",
+                        "PreferNoSchedule",
+                        "NoExecute"
+                      ]
+                    },
+                    "key": {
+                      "description": "Key for the taint",
+                      "type": "string"
+                    },
+                    "value": {
+                      "description": "Value for the taint",
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "worker": {
+      "description": "Worker nodes of the cluster",
+      "type": "object",
+      "required": [
+        "expected_count"
+      ],
+      "properties": {
+        "expected_count": {
+          "description": "Number of nodes.",
+          "type": "integer"
+        },
+        "inventory_source": {
+          "description": "The source the list of nodes is read from, instead of listing them in the plan file.",
+          "type": "object",
+          "required": [
+            "type",
+            "path"
+          ],
+          "properties": {
+            "args": {
+              "description": "Arguments passed to the executable.",
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            },
+            "path": {
+              "description": "Path to the file or to the executable.",
+              "type": "string"
+            },
+            "type": {
+              "description": "The type of the inventory source. A csv file has a header row with the host, ip, internalip and labels columns, where labels are formatted as key=value pairs separated by semicolons. A json file contains a list of objects with the host, ip, internalip and labels fields. An exec source is an executable that prints the nodes in the json format. The name of the node group is passed to the executable in the KISMATIC_NODE_GROUP environment variable.",
+              "type": "string",
+              "enum": [
+                "csv",
+                "json",
+                "exec"
+              ]
+            }
+          }
+        },
+        "nodes": {
+          "description": "List of nodes. Required, unless the nodes are read from an inventory source.",
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": [
+              "host",
+              "ip"
+            ],
+            "properties": {
+              "host": {
+                "description": "The hostname of the node. The hostname is verified in the validation phase of the installation.",
+                "type": "string"
+              },
+              "internalip": {
+                "description": "The internal (or private) IP address of the node. If set, this IP will be used when configuring cluster components.",
+                "type": "string"
+              },
+              "ip": {
+                "description": "The IP address of the node. This is the IP address that will be used to connect to the node over SSH.",
+                "type": "string"
+              },
+              "kubelet": {
+                "description": "Kubelet configuration applied to this node. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "option_overrides": {
+                    "description": "Listing of option overrides that are to be applied to the Kubelet configurations. This is an advanced feature that can prevent the Kubelet from starting up if invalid configuration is provided.",
+                    "type": "object",
+                    "additionalProperties": {
+                      "type": "string"
+                    }
+                  }
+                }
+              },
+              "labels": {
+                "description": "Labels to add when installing the node in the cluster. If a node is defined under multiple roles, the labels for that node will be merged. If a label is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence. It is recommended to use reverse-DNS notation to avoid collision with other labels.",
+                "type": "object",
+                "additionalProperties": {
+                  "type": "string"
+                }
+              },
+              "ssh": {
+                "description": "SSH configuration of this node, overriding the SSH configuration of the cluster. If a node is repeated for multiple roles, the overrides cannot be different.",
+                "type": "object",
+                "properties": {
+                  "ssh_key": {
+                    "description": "The absolute path of the SSH key that should be used for accessing the node via SSH.",
+                    "type": "string"
+                  },
+                  "ssh_port": {
+                    "description": "The port number on which the node is listening for SSH connections.",
+                    "type": "integer"
+                  },
+                  "user": {
+                    "description": "The user for accessing the node via SSH. This user requires sudo elevation privileges on the node.",
+                    "type": "string"
+                  }
+                }
+              },
+              "taints": {
+                "description": "Taints to add when installing the node in the cluster. If a node is defined under multiple roles, the taints for that node will be merged. If a taint is repeated for the same node, only one will be used in this order: etcd,master,worker,ingress,storage roles where 'storage' has the highest precedence.",
+                "type": "array",
+                "items": {
+                  "type": "object",
+                  "properties": {
+                    "effect": {
+                      "description": "Effect for the taint",
+                      "type": "string",
+                      "enum": [
+                        "NoSchedule",
+                        "PreferNoSchedule",
+                        "NoExecute"
+                      ]
+                    },
+                    "key": {
+                      "description": "Key for the taint",
+                      "type": "string"
+                    },
+                    "value": {
+                      "description": "Value for the taint",
+                      "type": "string"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
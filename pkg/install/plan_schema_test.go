@@ -0,0 +1,61 @@
+package install
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testSchema struct {
+	Type       string                 `json:"type"`
+	Properties map[string]*testSchema `json:"properties"`
+	Items      *testSchema            `json:"items"`
+}
+
+// The schema is generated from plan_types.go. This test fails when the plan
+// types change without regenerating the schema with 'make pkg/install/update-plan-schema'
+func TestPlanJSONSchemaIsUpToDate(t *testing.T) {
+	s := &testSchema{}
+	if err := json.Unmarshal([]byte(PlanJSONSchema), s); err != nil {
+		t.Fatalf("plan JSON schema is not valid JSON: %v", err)
+	}
+	assertSchemaHasFields(t, "", reflect.TypeOf(Plan{}), s)
+}
+
+func assertSchemaHasFields(t *testing.T, path string, typ reflect.Type, s *testSchema) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		// unexported fields are not part of the plan file
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if name == "-" {
+			continue
+		}
+		fieldPath := strings.TrimPrefix(path+"."+name, ".")
+		prop, ok := s.Properties[name]
+		if !ok {
+			t.Errorf("property %q is missing from the plan JSON schema", fieldPath)
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct {
+			if prop.Items == nil {
+				t.Errorf("property %q does not have an items schema", fieldPath)
+				continue
+			}
+			assertSchemaHasFields(t, fieldPath, ft.Elem(), prop.Items)
+		}
+		if ft.Kind() == reflect.Struct {
+			assertSchemaHasFields(t, fieldPath, ft, prop)
+		}
+	}
+}
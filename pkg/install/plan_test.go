@@ -48,7 +48,7 @@ func TestWritePlanTemplate(t *testing.T) {
 			t.Fatalf("error creating temp dir: %v", err)
 		}
 		file := filepath.Join(tmp, "kismatic-cluster.yaml")
-		fp := &FilePlanner{file}
+		fp := &FilePlanner{File: file}
 		if err = WritePlanTemplate(test.template, fp); err != nil {
 			t.Fatalf("error writing plan template: %v", err)
 		}
@@ -81,7 +81,7 @@ func TestReadWithDeprecated(t *testing.T) {
 	lb := "lb"
 	p.Master.LoadBalancedFQDN = &lb
 	p.Master.LoadBalancedShortName = &lb
-	readDeprecatedFields(p)
+	migrateUnversionedToV1(p)
 
 	if p.Cluster.Certificates.APIServerCertExtraSANs != "lb" {
 		t.Errorf("Expected master.load_balanced_short_name to be added to apiserver_cert_extra_sans")
@@ -130,3 +130,41 @@ func TestReadWithNil(t *testing.T) {
 		t.Errorf("expected cluster version to be %s, but got %s", kubernetesVersionString, p.Cluster.Version)
 	}
 }
+
+func TestServiceIPs(t *testing.T) {
+	tests := []struct {
+		serviceCIDR       string
+		podCIDR           string
+		expectedKubeIP    string
+		expectedDNSIP     string
+		expectedPodCIDRv4 string
+		expectedPodCIDRv6 string
+	}{
+		{"172.20.0.0/16", "172.16.0.0/16", "172.20.0.1", "172.20.0.2", "172.16.0.0/16", ""},
+		{"fd00:10:96::/112", "fd00:10:244::/64", "fd00:10:96::1", "fd00:10:96::2", "", "fd00:10:244::/64"},
+		{"172.20.0.0/16", "172.16.0.0/16, fd00:10:244::/64", "172.20.0.1", "172.20.0.2", "172.16.0.0/16", "fd00:10:244::/64"},
+	}
+	for _, test := range tests {
+		p := &Plan{}
+		p.Cluster.Networking.ServiceCIDRBlock = test.serviceCIDR
+		p.Cluster.Networking.PodCIDRBlock = test.podCIDR
+		kubeIP, err := getKubernetesServiceIP(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if kubeIP != test.expectedKubeIP {
+			t.Errorf("expected kubernetes service IP %q, but got %q", test.expectedKubeIP, kubeIP)
+		}
+		dnsIP, err := getDNSServiceIP(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dnsIP != test.expectedDNSIP {
+			t.Errorf("expected DNS service IP %q, but got %q", test.expectedDNSIP, dnsIP)
+		}
+		v4, v6 := p.Cluster.Networking.podCIDRs()
+		if v4 != test.expectedPodCIDRv4 || v6 != test.expectedPodCIDRv6 {
+			t.Errorf("expected pod CIDR blocks %q and %q, but got %q and %q", test.expectedPodCIDRv4, test.expectedPodCIDRv6, v4, v6)
+		}
+	}
+}
@@ -59,6 +59,12 @@ func taintEffects() []string {
 
 // Plan is the installation plan that the user intends to execute
 type Plan struct {
+	// The version of the plan file schema.
+	// Plan files without a version are treated as written by a release of KET that
+	// predates the versioning of the plan file, and are migrated when read.
+	// Use `kismatic install plan migrate` to update the plan file to the current version.
+	// +default=v1
+	APIVersion string `yaml:"apiVersion"`
 	// Kubernetes cluster configuration
 	// +required
 	Cluster Cluster
@@ -88,6 +94,10 @@ type Plan struct {
 	Storage OptionalNodeGroup
 	// NFS volumes of the cluster.
 	NFS *NFS `yaml:"nfs,omitempty"`
+
+	// secretReferences are the references of the secrets that were resolved
+	// when reading the plan, keyed by the path of the field in the plan file.
+	secretReferences map[string]string
 }
 
 // Cluster describes a Kubernetes cluster
@@ -103,6 +113,8 @@ type Cluster struct {
 	Version string
 	// The password for the admin user.
 	// If provided, ABAC will be enabled in the cluster.
+	// Can be a reference to a secret that is stored outside of the plan file:
+	// `${env:NAME}`, `file:///path/to/file` or `exec:command`.
 	// This field will be removed completely in a future release.
 	// +deprecated
 	AdminPassword string `yaml:"admin_password,omitempty"`
@@ -181,9 +193,17 @@ type NetworkConfig struct {
 	// +deprecated
 	Type string `yaml:"type,omitempty"`
 	// The pod network's CIDR block. For example: `172.16.0.0/16`
+	// An IPv6 CIDR block, such as `fd00:10:244::/64`, can be used for IPv6-only
+	// clusters. For dual-stack clusters, set a comma-separated IPv4 and IPv6
+	// CIDR block. The first CIDR block must be of the same IP family as the
+	// service network, and is the one that is used by Kubernetes.
+	// Dual-stack pod networking is only supported with the Calico CNI provider.
 	// +required
 	PodCIDRBlock string `yaml:"pod_cidr_block"`
 	// The Kubernetes service network's CIDR block. For example: `172.20.0.0/16`
+	// Can be an IPv6 CIDR block, such as `fd00:10:96::/112`. Kubernetes
+	// allocates service IPs from a single IP family, so only one CIDR block is
+	// supported.
 	// +required
 	ServiceCIDRBlock string `yaml:"service_cidr_block"`
 	// Whether the /etc/hosts file should be updated on the cluster nodes.
@@ -223,12 +243,85 @@ type SSHConfig struct {
 	// +required
 	User string
 	// The absolute path of the SSH key that should be used for accessing the
-	// cluster nodes via SSH.
-	// +required
+	// cluster nodes via SSH. The passphrase of a key that is protected by a
+	// passphrase is prompted once.
+	// Required, unless an ssh-agent is running and SSH_AUTH_SOCK is set.
 	Key string `yaml:"ssh_key"`
 	// The port number on which cluster nodes are listening for SSH connections.
 	// +required
 	Port int `yaml:"ssh_port"`
+	// The bastion host that the SSH connections to the cluster nodes go through,
+	// when the nodes are not directly reachable.
+	Bastion *BastionConfig `yaml:"bastion,omitempty"`
+	// The password for sudo on the cluster nodes, when the user can't sudo
+	// without a password. Must be a reference to a secret that is stored
+	// outside of the plan file: ${env:NAME}, file:///path or exec:command.
+	// The password can also be entered with --ask-become-pass instead.
+	BecomePassword string `yaml:"become_password,omitempty"`
+}
+
+// BastionConfig describes the jump host used for accessing the cluster nodes via SSH
+type BastionConfig struct {
+	// The hostname or IP address of the bastion host.
+	// +required
+	Host string
+	// The port number on which the bastion host is listening for SSH connections.
+	// +default=22
+	Port int `yaml:"ssh_port,omitempty"`
+	// The user for accessing the bastion host via SSH.
+	// Defaults to the user of the cluster nodes.
+	User string `yaml:"user,omitempty"`
+	// The absolute path of the SSH key that should be used for accessing the
+	// bastion host via SSH. Defaults to the key of the cluster nodes.
+	Key string `yaml:"ssh_key,omitempty"`
+}
+
+// ForNode returns the SSH configuration for accessing the node, with the
+// overrides of the node applied
+func (s SSHConfig) ForNode(n Node) SSHConfig {
+	if s.Bastion != nil {
+		// the bastion defaults to the user and key of the cluster, not the
+		// ones of the node
+		b := s.BastionHost()
+		s.Bastion = &BastionConfig{Host: b.Host, Port: b.Port, User: b.User, Key: b.Key}
+	}
+	if n.SSH == nil {
+		return s
+	}
+	if n.SSH.User != "" {
+		s.User = n.SSH.User
+	}
+	if n.SSH.Key != "" {
+		s.Key = n.SSH.Key
+	}
+	if n.SSH.Port != 0 {
+		s.Port = n.SSH.Port
+	}
+	return s
+}
+
+// BastionHost returns the bastion host that the SSH connections go through,
+// with the defaults set, or nil if there is no bastion host.
+func (s SSHConfig) BastionHost() *ssh.Bastion {
+	if s.Bastion == nil {
+		return nil
+	}
+	b := &ssh.Bastion{
+		Host: s.Bastion.Host,
+		Port: s.Bastion.Port,
+		User: s.Bastion.User,
+		Key:  s.Bastion.Key,
+	}
+	if b.Port == 0 {
+		b.Port = 22
+	}
+	if b.User == "" {
+		b.User = s.User
+	}
+	if b.Key == "" {
+		b.Key = s.Key
+	}
+	return b
 }
 
 // CloudProvider controls the Kubernetes cloud providers feature
@@ -351,6 +444,8 @@ type DockerRegistry struct {
 	Username string
 	// The password that should be used when connecting to a registry that has authentication enabled.
 	// Otherwise leave blank for unauthenticated access.
+	// Can be a reference to a secret that is stored outside of the plan file:
+	// `${env:NAME}`, `file:///path/to/file` or `exec:command`.
 	Password string
 }
 
@@ -437,11 +532,18 @@ type CalicoOptions struct {
 	// The value gets set in IP_AUTODETECTION_METHOD variable in the pod.
 	// +default=first-found
 	IPAutodetectionMethod string `yaml:"ip_autodetection_method"`
+	// IP6AutodetectionMethod is used to detect the IPv6 address of the host
+	// when the pod network has an IPv6 CIDR block.
+	// The value gets set in IP6_AUTODETECTION_METHOD variable in the pod.
+	// +default=first-found
+	IP6AutodetectionMethod string `yaml:"ip6_autodetection_method"`
 }
 
 // The WeaveOptions that can be configured for the Weave CNI provider.
 type WeaveOptions struct {
 	// The password to use for network traffic encryption.
+	// Can be a reference to a secret that is stored outside of the plan file:
+	// `${env:NAME}`, `file:///path/to/file` or `exec:command`.
 	Password string
 }
 
@@ -602,8 +704,10 @@ type MasterNodeGroup struct {
 	// +deprecated
 	LoadBalancedShortName *string `yaml:"load_balanced_short_name,omitempty"`
 	// List of master nodes that are part of the cluster.
-	// +required
+	// Required, unless the nodes are read from an inventory source.
 	Nodes []Node
+	// The source the list of master nodes is read from, instead of listing them in the plan file.
+	InventorySource *InventorySource `yaml:"inventory_source,omitempty"`
 }
 
 // A NodeGroup is a collection of nodes
@@ -612,13 +716,33 @@ type NodeGroup struct {
 	// +required
 	ExpectedCount int `yaml:"expected_count"`
 	// List of nodes.
-	// +required
+	// Required, unless the nodes are read from an inventory source.
 	Nodes []Node
+	// The source the list of nodes is read from, instead of listing them in the plan file.
+	InventorySource *InventorySource `yaml:"inventory_source,omitempty"`
 }
 
 // An OptionalNodeGroup is a collection of nodes that can be empty
 type OptionalNodeGroup NodeGroup
 
+// An InventorySource provides the nodes of a node group
+type InventorySource struct {
+	// The type of the inventory source.
+	// A csv file has a header row with the host, ip, internalip and labels columns,
+	// where labels are formatted as key=value pairs separated by semicolons.
+	// A json file contains a list of objects with the host, ip, internalip and labels fields.
+	// An exec source is an executable that prints the nodes in the json format.
+	// The name of the node group is passed to the executable in the KISMATIC_NODE_GROUP environment variable.
+	// +required
+	// +options=csv,json,exec
+	Type string
+	// Path to the file or to the executable.
+	// +required
+	Path string
+	// Arguments passed to the executable.
+	Args []string `yaml:"args,omitempty"`
+}
+
 // A Node is a compute unit, virtual or physical, that is part of the cluster
 type Node struct {
 	// The hostname of the node. The hostname is verified
@@ -646,6 +770,21 @@ type Node struct {
 	// Kubelet configuration applied to this node.
 	// If a node is repeated for multiple roles, the overrides cannot be different.
 	KubeletOptions KubeletOptions `yaml:"kubelet,omitempty"`
+	// SSH configuration of this node, overriding the SSH configuration of the cluster.
+	// If a node is repeated for multiple roles, the overrides cannot be different.
+	SSH *NodeSSHConfig `yaml:"ssh,omitempty"`
+}
+
+// NodeSSHConfig overrides the cluster's SSH configuration for accessing a node
+type NodeSSHConfig struct {
+	// The user for accessing the node via SSH.
+	// This user requires sudo elevation privileges on the node.
+	User string `yaml:"user,omitempty"`
+	// The absolute path of the SSH key that should be used for accessing the
+	// node via SSH.
+	Key string `yaml:"ssh_key,omitempty"`
+	// The port number on which the node is listening for SSH connections.
+	Port int `yaml:"ssh_port,omitempty"`
 }
 
 // Taint for nodes
@@ -852,7 +991,8 @@ func (p *Plan) GetSSHConnection(host string) (*SSHConnection, error) {
 		return nil, notFoundErr
 	}
 
-	return &SSHConnection{&p.Cluster.SSH, foundNode}, nil
+	sshConfig := p.Cluster.SSH.ForNode(*foundNode)
+	return &SSHConnection{&sshConfig, foundNode}, nil
 }
 
 // GetSSHClient is a convience method that calls GetSSHConnection and returns an SSH client with the result
@@ -861,10 +1001,11 @@ func (p *Plan) GetSSHClient(host string) (ssh.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key)
+	client, err := ssh.NewClient(con.Node.IP, con.SSHConfig.Port, con.SSHConfig.User, con.SSHConfig.Key, con.SSHConfig.BastionHost())
 	if err != nil {
 		return nil, fmt.Errorf("error creating SSH client for host %s: %v", host, err)
 	}
+	client.SudoPassword = con.SSHConfig.BecomePassword
 
 	return client, nil
 }
@@ -948,6 +1089,9 @@ func (node Node) certSpecs(plan Plan, ca *tls.CA) ([]certificateSpec, error) {
 		if node.InternalIP != "" {
 			san = append(san, node.InternalIP)
 		}
+		if isIPv6Address(node.IP) || isIPv6Address(node.InternalIP) {
+			san = append(san, "::1")
+		}
 		m = append(m, certificateSpec{
 			description:           fmt.Sprintf("%s etcd server", node.Host),
 			filename:              fmt.Sprintf("%s-etcd", node.Host),
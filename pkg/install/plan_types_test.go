@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/apprenda/kismatic/pkg/ssh"
 	"gopkg.in/yaml.v2"
 )
 
@@ -73,3 +74,56 @@ func TestClusterAddress(t *testing.T) {
 	}
 
 }
+
+func TestSSHConfigBastionHost(t *testing.T) {
+	s := SSHConfig{User: "alice", Key: "/keys/id_rsa", Port: 2222}
+	if b := s.BastionHost(); b != nil {
+		t.Errorf("expected no bastion host, got %+v", b)
+	}
+
+	s.Bastion = &BastionConfig{Host: "bastion.example.com"}
+	expected := ssh.Bastion{Host: "bastion.example.com", Port: 22, User: "alice", Key: "/keys/id_rsa"}
+	if b := s.BastionHost(); b == nil || *b != expected {
+		t.Errorf("expected bastion host %+v, got %+v", expected, b)
+	}
+
+	s.Bastion = &BastionConfig{Host: "bastion.example.com", Port: 2022, User: "bob", Key: "/keys/bastion.pem"}
+	expected = ssh.Bastion{Host: "bastion.example.com", Port: 2022, User: "bob", Key: "/keys/bastion.pem"}
+	if b := s.BastionHost(); b == nil || *b != expected {
+		t.Errorf("expected bastion host %+v, got %+v", expected, b)
+	}
+}
+
+func TestSSHConfigForNode(t *testing.T) {
+	p := &Plan{}
+	p.Cluster.SSH = SSHConfig{User: "alice", Key: "/keys/id_rsa", Port: 22, Bastion: &BastionConfig{Host: "bastion"}}
+	p.Worker.Nodes = []Node{
+		{Host: "worker01", IP: "10.0.0.1"},
+		{Host: "worker02", IP: "10.0.0.2", SSH: &NodeSSHConfig{User: "bob", Port: 2222}},
+	}
+
+	con, err := p.GetSSHConnection("worker01")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if con.SSHConfig.User != "alice" || con.SSHConfig.Key != "/keys/id_rsa" || con.SSHConfig.Port != 22 {
+		t.Errorf("expected the SSH configuration of the cluster, got %+v", con.SSHConfig)
+	}
+
+	con, err = p.GetSSHConnection("worker02")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if con.SSHConfig.User != "bob" || con.SSHConfig.Key != "/keys/id_rsa" || con.SSHConfig.Port != 2222 {
+		t.Errorf("expected the SSH overrides of the node, got %+v", con.SSHConfig)
+	}
+	// the bastion keeps the user of the cluster
+	if b := con.SSHConfig.BastionHost(); b.User != "alice" {
+		t.Errorf("expected the bastion to use the user of the cluster, got %q", b.User)
+	}
+
+	n := installNodeToAnsibleNode(&p.Worker.Nodes[1], &p.Cluster.SSH)
+	if n.SSHUser != "bob" || n.SSHPrivateKey != "/keys/id_rsa" || n.SSHPort != 2222 || n.Bastion.SSHUser != "alice" {
+		t.Errorf("expected the SSH overrides of the node in the inventory, got %+v", n)
+	}
+}
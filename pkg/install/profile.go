@@ -0,0 +1,248 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+const runProfileFile = "profile.json"
+
+// The number of the slowest tasks, plays and hosts that are printed
+const profileReportTop = 10
+
+// ProfileReport is the time spent on each task, play and host of a run
+type ProfileReport struct {
+	Seconds float64 `json:"seconds"`
+	// Plays, Tasks and Hosts are sorted from the slowest to the fastest
+	Plays []PlayTiming `json:"plays"`
+	Tasks []TaskTiming `json:"tasks"`
+	Hosts []HostTiming `json:"hosts"`
+}
+
+// PlayTiming is the time spent on a play
+type PlayTiming struct {
+	Play    string  `json:"play"`
+	Seconds float64 `json:"seconds"`
+}
+
+// TaskTiming is the time spent on a task, and the time each host took to
+// return its result
+type TaskTiming struct {
+	Play    string             `json:"play"`
+	Task    string             `json:"task"`
+	Seconds float64            `json:"seconds"`
+	Hosts   map[string]float64 `json:"hosts,omitempty"`
+}
+
+// HostTiming is the time a host spent on the tasks of the run
+type HostTiming struct {
+	Host    string  `json:"host"`
+	Seconds float64 `json:"seconds"`
+	Tasks   int     `json:"tasks"`
+}
+
+// profileExplainer records when each play and task started, and when each
+// host returned its result, before passing the events on to the explainer.
+// The events do not carry a time, so they are timed when they are received.
+type profileExplainer struct {
+	explainer explain.AnsibleEventExplainer
+	// now is a hook for testing
+	now func() time.Time
+
+	mu        sync.Mutex
+	start     time.Time
+	plays     []PlayTiming
+	tasks     []TaskTiming
+	playStart time.Time
+	taskStart time.Time
+	// hostStart is when the host started working on the running task, which
+	// is when the task started, or when the host returned a loop item
+	hostStart map[string]time.Time
+	running   bool
+}
+
+func newProfileExplainer(explainer explain.AnsibleEventExplainer) *profileExplainer {
+	return &profileExplainer{
+		explainer: explainer,
+		now:       time.Now,
+		hostStart: map[string]time.Time{},
+	}
+}
+
+// ExplainEvent records the time of the event and explains it
+func (p *profileExplainer) ExplainEvent(e ansible.Event) {
+	p.record(e)
+	p.explainer.ExplainEvent(e)
+}
+
+func (p *profileExplainer) record(e ansible.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	switch event := e.(type) {
+	case *ansible.PlayStartEvent:
+		p.endTask(now)
+		p.endPlay(now)
+		p.plays = append(p.plays, PlayTiming{Play: event.Name})
+		p.playStart = now
+	case *ansible.TaskStartEvent:
+		p.startTask(now, event.Name)
+	case *ansible.HandlerTaskStartEvent:
+		p.startTask(now, event.Name)
+	case *ansible.PlaybookEndEvent:
+		p.endTask(now)
+		p.endPlay(now)
+	case ansible.ResultEvent:
+		host, _, _ := event.HostResult()
+		if !p.running || host == "" {
+			return
+		}
+		t := &p.tasks[len(p.tasks)-1]
+		start, ok := p.hostStart[host]
+		if !ok {
+			start = p.taskStart
+		}
+		t.Hosts[host] += now.Sub(start).Seconds()
+		p.hostStart[host] = now
+	}
+}
+
+func (p *profileExplainer) startTask(now time.Time, name string) {
+	p.endTask(now)
+	play := ""
+	if len(p.plays) > 0 {
+		play = p.plays[len(p.plays)-1].Play
+	}
+	p.tasks = append(p.tasks, TaskTiming{Play: play, Task: name, Hosts: map[string]float64{}})
+	p.taskStart = now
+	p.hostStart = map[string]time.Time{}
+	p.running = true
+}
+
+func (p *profileExplainer) endTask(now time.Time) {
+	if !p.running {
+		return
+	}
+	p.tasks[len(p.tasks)-1].Seconds = now.Sub(p.taskStart).Seconds()
+	p.running = false
+}
+
+func (p *profileExplainer) endPlay(now time.Time) {
+	if len(p.plays) == 0 || p.playStart.IsZero() {
+		return
+	}
+	p.plays[len(p.plays)-1].Seconds = now.Sub(p.playStart).Seconds()
+	p.playStart = time.Time{}
+}
+
+// finish ends the task and play that were running when ansible exited, and
+// returns the report
+func (p *profileExplainer) finish() *ProfileReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	p.endTask(now)
+	p.endPlay(now)
+
+	r := &ProfileReport{
+		Plays: append([]PlayTiming{}, p.plays...),
+		Tasks: append([]TaskTiming{}, p.tasks...),
+		Hosts: []HostTiming{},
+	}
+	if !p.start.IsZero() {
+		r.Seconds = now.Sub(p.start).Seconds()
+	}
+	hosts := map[string]*HostTiming{}
+	for _, t := range p.tasks {
+		for h, s := range t.Hosts {
+			if _, ok := hosts[h]; !ok {
+				hosts[h] = &HostTiming{Host: h}
+			}
+			hosts[h].Seconds += s
+			hosts[h].Tasks++
+		}
+	}
+	for _, h := range hosts {
+		r.Hosts = append(r.Hosts, *h)
+	}
+	sort.SliceStable(r.Plays, func(i, j int) bool { return r.Plays[i].Seconds > r.Plays[j].Seconds })
+	sort.SliceStable(r.Tasks, func(i, j int) bool { return r.Tasks[i].Seconds > r.Tasks[j].Seconds })
+	sort.Slice(r.Hosts, func(i, j int) bool {
+		if r.Hosts[i].Seconds == r.Hosts[j].Seconds {
+			return r.Hosts[i].Host < r.Hosts[j].Host
+		}
+		return r.Hosts[i].Seconds > r.Hosts[j].Seconds
+	})
+	return r
+}
+
+func writeProfileReport(runDirectory string, r *ProfileReport) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling profile report: %v", err)
+	}
+	file := filepath.Join(runDirectory, runProfileFile)
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("error writing profile report to %q: %v", file, err)
+	}
+	return nil
+}
+
+// PrintProfileReport prints the slowest plays, tasks and hosts of the report
+func PrintProfileReport(out io.Writer, r *ProfileReport) {
+	util.PrintHeader(out, "Profile", '=')
+	fmt.Fprintf(out, "Total: %s\n\n", secondsToDuration(r.Seconds))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SLOWEST PLAYS\tDURATION")
+	for i, p := range r.Plays {
+		if i == profileReportTop {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%s\n", p.Play, secondsToDuration(p.Seconds))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "SLOWEST TASKS\tPLAY\tDURATION\tSLOWEST HOST")
+	for i, t := range r.Tasks {
+		if i == profileReportTop {
+			break
+		}
+		slowest := ""
+		var max float64
+		for h, s := range t.Hosts {
+			if s > max || (s == max && h < slowest) {
+				slowest, max = h, s
+			}
+		}
+		if slowest != "" {
+			slowest = fmt.Sprintf("%s (%s)", slowest, secondsToDuration(max))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Task, t.Play, secondsToDuration(t.Seconds), slowest)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "SLOWEST HOSTS\tDURATION\tTASKS")
+	for i, h := range r.Hosts {
+		if i == profileReportTop {
+			break
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n", h.Host, secondsToDuration(h.Seconds), h.Tasks)
+	}
+	w.Flush()
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return (time.Duration(s * float64(time.Second))).Round(100 * time.Millisecond)
+}
@@ -0,0 +1,97 @@
+package install
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestProfileExplainer(t *testing.T) {
+	clock := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	p := newProfileExplainer(noopExplainer{})
+	p.now = func() time.Time { return clock }
+
+	// each event is received the given number of seconds after the previous one
+	events := []struct {
+		after int
+		event ansible.Event
+	}{
+		{0, playStart("_docker.yaml", "Install Docker")},
+		{0, taskStart("install docker")},
+		{30, runnerOK("worker01")},
+		{60, runnerOK("worker02")},
+		{0, taskStart("start docker")},
+		{5, runnerOK("worker01")},
+		{5, runnerOK("worker02")},
+		{0, playStart("_etcd-k8s.yaml", "Start etcd")},
+		{0, taskStart("start etcd")},
+		{20, runnerFailed("etcd01", false)},
+	}
+	for _, e := range events {
+		clock = clock.Add(time.Duration(e.after) * time.Second)
+		p.ExplainEvent(e.event)
+	}
+	clock = clock.Add(10 * time.Second)
+	r := p.finish()
+
+	expected := &ProfileReport{
+		Seconds: 130,
+		Plays: []PlayTiming{
+			{Play: "Install Docker", Seconds: 100},
+			{Play: "Start etcd", Seconds: 30},
+		},
+		Tasks: []TaskTiming{
+			{Play: "Install Docker", Task: "install docker", Seconds: 90, Hosts: map[string]float64{"worker01": 30, "worker02": 90}},
+			{Play: "Start etcd", Task: "start etcd", Seconds: 30, Hosts: map[string]float64{"etcd01": 20}},
+			{Play: "Install Docker", Task: "start docker", Seconds: 10, Hosts: map[string]float64{"worker01": 5, "worker02": 10}},
+		},
+		Hosts: []HostTiming{
+			{Host: "worker02", Seconds: 100, Tasks: 2},
+			{Host: "worker01", Seconds: 35, Tasks: 2},
+			{Host: "etcd01", Seconds: 20, Tasks: 1},
+		},
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected report:\n%+v\ngot:\n%+v", expected, r)
+	}
+
+	out := &bytes.Buffer{}
+	PrintProfileReport(out, r)
+	for _, s := range []string{"Total: 2m10s", "install docker", "worker02 (1m30s)", "SLOWEST HOSTS"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected the report to contain %q, got:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestWriteProfileReport(t *testing.T) {
+	runDir, err := ioutil.TempDir("", "ket-test-profile")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+
+	r := &ProfileReport{Seconds: 1, Plays: []PlayTiming{{Play: "Gather Facts", Seconds: 1}}}
+	if err := writeProfileReport(runDir, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(runDir, runProfileFile))
+	if err != nil {
+		t.Fatalf("error reading profile report: %v", err)
+	}
+	got := &ProfileReport{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatalf("error unmarshaling profile report: %v", err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("expected %+v, got %+v", r, got)
+	}
+}
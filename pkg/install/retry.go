@@ -0,0 +1,97 @@
+package install
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+// RetryPolicy re-runs a playbook that failed because hosts failed or were
+// unreachable, limited to those hosts, before the run is declared failed. The
+// plays that were completed are skipped. As the plays stop on all hosts when
+// a host fails, the playbook is re-run only when no other host ran it:
+// re-running it on the failed hosts would leave the other hosts behind.
+type RetryPolicy struct {
+	// Attempts is the number of times the playbook is re-run. The playbook
+	// is not re-run when zero.
+	Attempts int
+	// Backoff is how long to wait before the first re-run. The wait doubles
+	// before each of the following re-runs.
+	Backoff time.Duration
+}
+
+// wait returns how long to wait before the attempt, starting from 1
+func (r RetryPolicy) wait(attempt int) time.Duration {
+	return r.Backoff * time.Duration(1<<uint(attempt-1))
+}
+
+// failedHostsExplainer collects the hosts that ran, and those that failed or
+// were unreachable, before passing the events on to the explainer
+type failedHostsExplainer struct {
+	explainer explain.AnsibleEventExplainer
+	mu        sync.Mutex
+	// hosts is true for the hosts that failed, false for the others
+	hosts map[string]bool
+}
+
+// ExplainEvent collects the host of the result and explains the event
+func (f *failedHostsExplainer) ExplainEvent(e ansible.Event) {
+	if event, ok := e.(ansible.ResultEvent); ok {
+		host, _, ignoreErrors := event.HostResult()
+		failed := false
+		switch e.(type) {
+		case *ansible.RunnerFailedEvent:
+			failed = !ignoreErrors
+		case *ansible.RunnerUnreachableEvent:
+			failed = true
+		}
+		f.mu.Lock()
+		if f.hosts == nil {
+			f.hosts = map[string]bool{}
+		}
+		f.hosts[host] = f.hosts[host] || failed
+		f.mu.Unlock()
+	}
+	f.explainer.ExplainEvent(e)
+}
+
+// reset returns the hosts that failed since the last reset, sorted, and
+// whether other hosts ran since, and were stopped with the failed hosts
+func (f *failedHostsExplainer) reset() ([]string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hosts := []string{}
+	stopped := false
+	for h, failed := range f.hosts {
+		if failed {
+			hosts = append(hosts, h)
+		} else {
+			stopped = true
+		}
+	}
+	sort.Strings(hosts)
+	f.hosts = nil
+	return hosts, stopped && len(hosts) > 0
+}
+
+// mergeCompletedPlays returns the completed plays, followed by the plays that
+// were completed since, without duplicates
+func mergeCompletedPlays(completed []string, since []string) []string {
+	merged := append([]string{}, completed...)
+	for _, p := range since {
+		found := false
+		for _, c := range merged {
+			if c == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
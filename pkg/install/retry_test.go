@@ -0,0 +1,253 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+// scriptedRunner explains the events of each attempt when waiting for the
+// playbook, and fails if a host failed. When set, interrupt is called before
+// the playbook fails.
+type scriptedRunner struct {
+	explainer explain.AnsibleEventExplainer
+	attempts  [][]ansible.Event
+	limits    [][]string
+	completed [][]string
+	interrupt func()
+}
+
+func (r *scriptedRunner) StartPlaybook(ctx context.Context, playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog) (<-chan ansible.Event, error) {
+	return r.StartPlaybookOnNode(ctx, playbookFile, inventory, cc)
+}
+
+func (r *scriptedRunner) StartPlaybookOnNode(ctx context.Context, playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog, node ...string) (<-chan ansible.Event, error) {
+	r.limits = append(r.limits, node)
+	r.completed = append(r.completed, cc.CompletedPlays)
+	events := make(chan ansible.Event)
+	close(events)
+	return events, nil
+}
+
+func (r *scriptedRunner) WaitPlaybook() error {
+	var err error
+	for _, e := range r.attempts[len(r.limits)-1] {
+		r.explainer.ExplainEvent(e)
+		switch e.(type) {
+		case *ansible.RunnerFailedEvent, *ansible.RunnerUnreachableEvent:
+			err = errors.New("exit status 2")
+		}
+	}
+	if err != nil && r.interrupt != nil {
+		r.interrupt()
+		return ansible.ErrInterrupted
+	}
+	return err
+}
+
+func TestExecuteRetriesFailedHosts(t *testing.T) {
+	tests := []struct {
+		retries           int
+		limit             []string
+		attempts          [][]ansible.Event
+		expectedLimits    [][]string
+		expectedCompleted [][]string
+		expectedPlays     []string
+		shouldError       bool
+	}{
+		{
+			// the retries run on the hosts that failed, from the play that
+			// failed
+			retries: 2,
+			attempts: [][]ansible.Event{
+				{
+					playStart("_docker.yaml", "docker"), runnerOK("worker1"), runnerOK("worker2"),
+					playStart("_kubelet.yaml", "kubelet"), runnerFailed("worker2", false), runnerUnreachable("worker1"),
+				},
+				{
+					playStart("_docker.yaml", "docker"),
+					playStart("_kubelet.yaml", "kubelet"), runnerOK("worker1"), runnerUnreachable("worker2"), runnerFailed("worker1", false),
+				},
+				{
+					playStart("_docker.yaml", "docker"),
+					playStart("_kubelet.yaml", "kubelet"), runnerOK("worker1"), runnerOK("worker2"),
+					playStart("_kube-proxy.yaml", "kube-proxy"), runnerOK("worker1"), runnerOK("worker2"),
+				},
+			},
+			expectedLimits:    [][]string{nil, {"worker1", "worker2"}, {"worker1", "worker2"}},
+			expectedCompleted: [][]string{nil, {"_docker.yaml"}, {"_docker.yaml"}},
+			expectedPlays:     []string{"_docker.yaml", "_kubelet.yaml", "_kube-proxy.yaml"},
+		},
+		{
+			// the failure stopped a host that did not fail, which would be
+			// left behind by a retry on the failed host
+			retries: 2,
+			attempts: [][]ansible.Event{
+				{
+					playStart("_docker.yaml", "docker"), runnerOK("master1"), runnerOK("worker1"),
+					playStart("_kubelet.yaml", "kubelet"), runnerOK("master1"), runnerFailed("worker1", false),
+				},
+			},
+			expectedLimits:    [][]string{nil},
+			expectedCompleted: [][]string{nil},
+			shouldError:       true,
+		},
+		{
+			// a host whose errors were ignored went on, and was stopped by
+			// the host that was unreachable
+			retries: 1,
+			attempts: [][]ansible.Event{
+				{runnerFailed("worker1", true), runnerUnreachable("worker2")},
+			},
+			expectedLimits:    [][]string{nil},
+			expectedCompleted: [][]string{nil},
+			shouldError:       true,
+		},
+		{
+			retries: 1,
+			limit:   []string{"worker1"},
+			attempts: [][]ansible.Event{
+				{runnerOK("worker1"), runnerFailed("worker1", false)},
+				{runnerFailed("worker1", false)},
+			},
+			expectedLimits:    [][]string{{"worker1"}, {"worker1"}},
+			expectedCompleted: [][]string{nil, {}},
+			shouldError:       true,
+		},
+		{
+			retries: 0,
+			attempts: [][]ansible.Event{
+				{runnerFailed("worker1", false)},
+			},
+			expectedLimits:    [][]string{nil},
+			expectedCompleted: [][]string{nil},
+			shouldError:       true,
+		},
+	}
+	for i, test := range tests {
+		runsDir, err := ioutil.TempDir("", "ket-test-retry")
+		if err != nil {
+			t.Fatalf("error creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(runsDir)
+		runner := &scriptedRunner{attempts: test.attempts}
+		ae := &ansibleExecutor{
+			options: ExecutorOptions{
+				RunsDirectory: runsDir,
+				Retry:         RetryPolicy{Attempts: test.retries, Backoff: time.Millisecond},
+			},
+			stdout:              ioutil.Discard,
+			consoleOutputFormat: ansible.RawFormat,
+			runnerExplainerFactory: func(explainer explain.AnsibleEventExplainer, _ io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+				runner.explainer = explainer
+				return runner, &explain.AnsibleEventStreamExplainer{EventExplainer: explainer}, nil
+			},
+		}
+		err = ae.execute(task{name: "apply", playbook: "kubernetes.yaml", explainer: noopExplainer{}, limit: test.limit})
+		if err != nil && !test.shouldError {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if err == nil && test.shouldError {
+			t.Errorf("test %d: expected an error, but didn't get one", i)
+		}
+		if !reflect.DeepEqual(runner.limits, test.expectedLimits) {
+			t.Errorf("test %d: expected the attempts to run on %v, but they ran on %v", i, test.expectedLimits, runner.limits)
+		}
+		if !reflect.DeepEqual(runner.completed, test.expectedCompleted) {
+			t.Errorf("test %d: expected the attempts to skip %v, but they skipped %v", i, test.expectedCompleted, runner.completed)
+		}
+		runs, err := listRuns(runsDir, "apply")
+		if err != nil || len(runs) != 1 {
+			t.Fatalf("test %d: expected a single run, got %v: %v", i, runs, err)
+		}
+		if runs[0].Retries != len(test.expectedCompleted)-1 {
+			t.Errorf("test %d: expected %d retries to be recorded, got %d", i, len(test.expectedCompleted)-1, runs[0].Retries)
+		}
+		// the checkpoints are the plays of the last attempt
+		if test.expectedPlays != nil {
+			c, err := runs[0].Checkpoints()
+			if err != nil || c == nil {
+				t.Fatalf("test %d: expected checkpoints, got %v", i, err)
+			}
+			plays := []string{}
+			for _, p := range c.Plays {
+				if p.Status != PlayStatusSucceeded {
+					t.Errorf("test %d: expected play %q to succeed, got %q", i, p.File, p.Status)
+				}
+				plays = append(plays, p.File)
+			}
+			if !reflect.DeepEqual(plays, test.expectedPlays) {
+				t.Errorf("test %d: expected the plays %v to be recorded, got %v", i, test.expectedPlays, plays)
+			}
+		}
+	}
+}
+
+func TestExecuteInterrupted(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-interrupt")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := &scriptedRunner{
+		attempts:  [][]ansible.Event{{runnerOK("master1"), runnerFailed("worker1", false)}},
+		interrupt: cancel,
+	}
+	ae := &ansibleExecutor{
+		options: ExecutorOptions{
+			RunsDirectory: runsDir,
+			Retry:         RetryPolicy{Attempts: 2, Backoff: time.Millisecond},
+			Context:       ctx,
+		},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explainer explain.AnsibleEventExplainer, _ io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			runner.explainer = explainer
+			return runner, &explain.AnsibleEventStreamExplainer{EventExplainer: explainer}, nil
+		},
+	}
+	if err = ae.execute(task{name: "apply", playbook: "kubernetes.yaml", explainer: noopExplainer{}}); err == nil {
+		t.Fatalf("expected an error, but didn't get one")
+	}
+	// an interrupted playbook is not retried
+	if !reflect.DeepEqual(runner.limits, [][]string{nil}) {
+		t.Errorf("expected the playbook to run once, but it ran on %v", runner.limits)
+	}
+	runs, err := listRuns(runsDir, "apply")
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected a single run, got %v: %v", runs, err)
+	}
+	if runs[0].Status != RunStatusInterrupted || runs[0].End == "" {
+		t.Errorf("expected the run to be recorded as interrupted, got %+v", runs[0].RunRecord)
+	}
+
+	// no other playbook is started once interrupted
+	if err = ae.execute(task{name: "smoketest", playbook: "smoketest.yaml", explainer: noopExplainer{}}); err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+	if len(runner.limits) != 1 {
+		t.Errorf("expected no other playbook to be started, but it ran %d times", len(runner.limits))
+	}
+	if runs, err = listRuns(runsDir, "smoketest"); err != nil || len(runs) != 0 {
+		t.Errorf("expected no run of the task that was not started, got %v: %v", runs, err)
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	r := RetryPolicy{Attempts: 3, Backoff: 10 * time.Second}
+	for attempt, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		if w := r.wait(attempt + 1); w != expected {
+			t.Errorf("attempt %d: expected to wait %v, got %v", attempt+1, expected, w)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// RunSummary is the summary of a run, rebuilt from the ansible events that
+// were recorded in the run directory
+type RunSummary struct {
+	Playbook string
+	// Plays in the order they were started
+	Plays []PlaySummary
+	// Hosts are the results of the tasks on each host
+	Hosts map[string]*HostSummary
+	// Failures are the tasks that failed, in the order they failed
+	Failures []TaskFailure
+}
+
+// PlaySummary is the summary of a play of a run
+type PlaySummary struct {
+	Name  string
+	Tasks int
+}
+
+// HostSummary is the number of task results of each kind on a host
+type HostSummary struct {
+	OK          int
+	Skipped     int
+	Failed      int
+	Unreachable int
+}
+
+// TaskFailure is a task that failed, or could not reach a host
+type TaskFailure struct {
+	Play        string
+	Task        string
+	Host        string
+	Message     string
+	Unreachable bool
+}
+
+// Summary rebuilds the summary of the run from the ansible events that were
+// recorded in the run directory. Returns nil if the run did not record events.
+func (r Run) Summary() (*RunSummary, error) {
+	f, err := os.Open(filepath.Join(r.Directory, ansible.EventsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return summarizeEvents(ansible.EventStream(f)), nil
+}
+
+func summarizeEvents(events <-chan ansible.Event) *RunSummary {
+	s := &RunSummary{Hosts: map[string]*HostSummary{}}
+	var play, task string
+	host := func(name string) *HostSummary {
+		if _, ok := s.Hosts[name]; !ok {
+			s.Hosts[name] = &HostSummary{}
+		}
+		return s.Hosts[name]
+	}
+	for e := range events {
+		switch event := e.(type) {
+		case *ansible.PlaybookStartEvent:
+			s.Playbook = event.Name
+		case *ansible.PlayStartEvent:
+			play = event.Name
+			s.Plays = append(s.Plays, PlaySummary{Name: event.Name})
+		case *ansible.TaskStartEvent:
+			task = event.Name
+			if len(s.Plays) > 0 {
+				s.Plays[len(s.Plays)-1].Tasks++
+			}
+		case *ansible.HandlerTaskStartEvent:
+			task = event.Name
+		case *ansible.RunnerOKEvent:
+			host(event.Host).OK++
+		case *ansible.RunnerSkippedEvent:
+			host(event.Host).Skipped++
+		case *ansible.RunnerFailedEvent:
+			if event.IgnoreErrors {
+				host(event.Host).OK++
+				continue
+			}
+			host(event.Host).Failed++
+			msg := event.Result.Message
+			if msg == "" {
+				msg = strings.TrimSpace(event.Result.Stderr)
+			}
+			s.Failures = append(s.Failures, TaskFailure{Play: play, Task: task, Host: event.Host, Message: msg})
+		case *ansible.RunnerUnreachableEvent:
+			host(event.Host).Unreachable++
+			s.Failures = append(s.Failures, TaskFailure{Play: play, Task: task, Host: event.Host, Message: event.Result.Message, Unreachable: true})
+		}
+	}
+	return s
+}
@@ -0,0 +1,270 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultRunsDirectory is the directory where information about
+	// installation runs is kept when no other directory is specified.
+	DefaultRunsDirectory = "./runs"
+
+	runRecordFile = "run.yaml"
+	runPlanFile   = "kismatic-cluster.yaml"
+	// runDirectoryTimeFormat is the format of the name of the run directories
+	runDirectoryTimeFormat = "2006-01-02-15-04-05"
+
+	// StaleRunAge is how long after it started a run that is still recorded
+	// as running is considered abandoned, for example because kismatic was
+	// killed before it could record how the run ended.
+	StaleRunAge = 24 * time.Hour
+)
+
+// The possible states of a run
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+	// RunStatusInterrupted is used for runs that were stopped by an
+	// interrupt, such as Ctrl-C, before they completed
+	RunStatusInterrupted = "interrupted"
+	// RunStatusUnknown is used for runs that were recorded by a version
+	// of kismatic that did not keep track of the run status.
+	RunStatusUnknown = "unknown"
+)
+
+// RunRecord is the information about an execution that is
+// stored in the run directory.
+type RunRecord struct {
+	Task     string   `yaml:"task"`
+	Playbook string   `yaml:"playbook"`
+	Limit    []string `yaml:"limit,omitempty"`
+	Status   string   `yaml:"status"`
+	Start    string   `yaml:"start"`
+	End      string   `yaml:"end,omitempty"`
+	Error    string   `yaml:"error,omitempty"`
+	Retries  int      `yaml:"retries,omitempty"`
+	// Owner is the user and the host the run was started by
+	Owner string `yaml:"owner,omitempty"`
+	// PID is the process ID of the kismatic command that started the run
+	PID int `yaml:"pid,omitempty"`
+}
+
+// A Run is an execution of a task that is recorded in the runs directory
+type Run struct {
+	// Directory of the run
+	Directory string
+	// Time the run was started
+	Start time.Time
+	RunRecord
+}
+
+// PlanFile returns the path to the plan file that was used for the run
+func (r Run) PlanFile() string {
+	return filepath.Join(r.Directory, runPlanFile)
+}
+
+// ID returns the identifier of the run, formatted as task/start-time
+func (r Run) ID() string {
+	return r.Task + "/" + filepath.Base(r.Directory)
+}
+
+// Duration returns how long the run took. Returns zero if the run has not
+// ended, or if the end time was not recorded.
+func (r Run) Duration() time.Duration {
+	end, err := time.Parse(time.RFC3339, r.End)
+	if err != nil {
+		return 0
+	}
+	start, err := time.Parse(time.RFC3339, r.RunRecord.Start)
+	if err != nil {
+		start = r.Start
+	}
+	return end.Sub(start)
+}
+
+// Stale returns true if the run is recorded as running, but was abandoned:
+// it started more than StaleRunAge ago, or the command that started it is
+// not running anymore on this host.
+func (r Run) Stale(now time.Time) bool {
+	if r.Status != RunStatusRunning {
+		return false
+	}
+	if now.Sub(r.Start) > StaleRunAge {
+		return true
+	}
+	return r.PID > 0 && onThisHost(r.Owner) && !processRunning(r.PID)
+}
+
+// Checkpoints returns the plays that were recorded by the run. Returns nil if
+// the run did not record checkpoints.
+func (r Run) Checkpoints() (*Checkpoints, error) {
+	c, err := readCheckpoints(r.Directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func writeRunRecord(runDirectory string, r RunRecord) error {
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error marshaling run record: %v", err)
+	}
+	file := filepath.Join(runDirectory, runRecordFile)
+	if err := ioutil.WriteFile(file, b, 0644); err != nil {
+		return fmt.Errorf("error writing run record to %q: %v", file, err)
+	}
+	return nil
+}
+
+func readRun(runDirectory string) (*Run, error) {
+	start, err := time.ParseInLocation(runDirectoryTimeFormat, filepath.Base(runDirectory), time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a run directory", runDirectory)
+	}
+	r := &Run{
+		Directory: runDirectory,
+		Start:     start,
+		RunRecord: RunRecord{
+			Task:   filepath.Base(filepath.Dir(runDirectory)),
+			Status: RunStatusUnknown,
+		},
+	}
+	b, err := ioutil.ReadFile(filepath.Join(runDirectory, runRecordFile))
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading run record: %v", err)
+	}
+	if err := yaml.Unmarshal(b, &r.RunRecord); err != nil {
+		return nil, fmt.Errorf("error unmarshaling run record in %q: %v", runDirectory, err)
+	}
+	return r, nil
+}
+
+// listRuns returns the runs of the given task, most recent first
+func listRuns(runsDirectory string, task string) ([]Run, error) {
+	dirs, err := ioutil.ReadDir(filepath.Join(runsDirectory, task))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing runs of %q: %v", task, err)
+	}
+	runs := []Run{}
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		r, err := readRun(filepath.Join(runsDirectory, task, d.Name()))
+		if err != nil {
+			// ignore directories that were not created by kismatic
+			continue
+		}
+		runs = append(runs, *r)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start.After(runs[j].Start) })
+	return runs, nil
+}
+
+// ListRuns returns the runs of all tasks, most recent first
+func ListRuns(runsDirectory string) ([]Run, error) {
+	tasks, err := ioutil.ReadDir(runsDirectory)
+	if os.IsNotExist(err) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing runs: %v", err)
+	}
+	runs := []Run{}
+	for _, t := range tasks {
+		if !t.IsDir() {
+			continue
+		}
+		taskRuns, err := listRuns(runsDirectory, t.Name())
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, taskRuns...)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Start.After(runs[j].Start) })
+	return runs, nil
+}
+
+// GetRun returns the run with the given ID
+func GetRun(runsDirectory string, id string) (*Run, error) {
+	parts := strings.Split(filepath.ToSlash(id), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || parts[0] == ".." {
+		return nil, fmt.Errorf("%q is not a run ID. Run IDs are formatted as task/start-time, such as apply/2018-01-02-15-04-05", id)
+	}
+	dir := filepath.Join(runsDirectory, parts[0], parts[1])
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %q was not found in %q", id, runsDirectory)
+		}
+		return nil, fmt.Errorf("error reading run %q: %v", id, err)
+	}
+	return readRun(dir)
+}
+
+// PruneRuns removes all but the keep most recent runs of each task. The
+// last successful run of each task is always kept, as it is used to compare
+// the plan file against, and so are the runs that are still running. Stale
+// runs that are recorded as running are pruned as any other run.
+// Returns the runs that were removed.
+func PruneRuns(runsDirectory string, keep int) ([]Run, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("the number of runs to keep must be greater than or equal to zero")
+	}
+	runs, err := ListRuns(runsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	kept := map[string]int{}
+	lastSuccessful := map[string]bool{}
+	pruned := []Run{}
+	now := time.Now()
+	for _, r := range runs {
+		if kept[r.Task] < keep || (r.Status == RunStatusRunning && !r.Stale(now)) {
+			kept[r.Task]++
+			if r.Status == RunStatusSucceeded {
+				lastSuccessful[r.Task] = true
+			}
+			continue
+		}
+		if r.Status == RunStatusSucceeded && !lastSuccessful[r.Task] {
+			lastSuccessful[r.Task] = true
+			continue
+		}
+		if err := os.RemoveAll(r.Directory); err != nil {
+			return pruned, fmt.Errorf("error removing run %q: %v", r.ID(), err)
+		}
+		pruned = append(pruned, r)
+	}
+	return pruned, nil
+}
+
+// LastSuccessfulRun returns the most recent run of the task that succeeded.
+// Returns nil if the task has never succeeded.
+func LastSuccessfulRun(runsDirectory string, task string) (*Run, error) {
+	runs, err := listRuns(runsDirectory, task)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		if r.Status == RunStatusSucceeded {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
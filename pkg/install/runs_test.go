@@ -0,0 +1,269 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLastSuccessfulRun(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-runs")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+
+	runs := map[string]string{
+		"2018-01-01-10-00-00": RunStatusSucceeded,
+		"2018-01-02-10-00-00": RunStatusSucceeded,
+		"2018-01-03-10-00-00": RunStatusFailed,
+		// recorded by an older version of kismatic
+		"2018-01-04-10-00-00": "",
+	}
+	for dir, status := range runs {
+		runDir := filepath.Join(runsDir, "apply", dir)
+		if err := os.MkdirAll(runDir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		if status == "" {
+			continue
+		}
+		if err := writeRunRecord(runDir, RunRecord{Task: "apply", Status: status}); err != nil {
+			t.Fatalf("error writing run record: %v", err)
+		}
+	}
+	// directories that were not created by kismatic are ignored
+	if err := os.MkdirAll(filepath.Join(runsDir, "apply", "foo"), 0777); err != nil {
+		t.Fatalf("error creating dir: %v", err)
+	}
+
+	r, err := LastSuccessfulRun(runsDir, "apply")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r == nil {
+		t.Fatalf("expected a run, but got nil")
+	}
+	expected := filepath.Join(runsDir, "apply", "2018-01-02-10-00-00")
+	if r.Directory != expected {
+		t.Errorf("expected run %q, but got %q", expected, r.Directory)
+	}
+
+	r, err = LastSuccessfulRun(runsDir, "reset")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r != nil {
+		t.Errorf("expected no run, but got %q", r.Directory)
+	}
+}
+
+func writeTestRuns(t *testing.T, runsDir string, runs map[string]string) {
+	for id, status := range runs {
+		runDir := filepath.Join(runsDir, id)
+		if err := os.MkdirAll(runDir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		if err := writeRunRecord(runDir, RunRecord{Task: filepath.Dir(id), Status: status}); err != nil {
+			t.Fatalf("error writing run record: %v", err)
+		}
+	}
+}
+
+func TestListRunsAndGetRun(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-runs")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+	writeTestRuns(t, runsDir, map[string]string{
+		"apply/2018-01-01-10-00-00": RunStatusSucceeded,
+		"step/2018-01-02-10-00-00":  RunStatusFailed,
+		"apply/2018-01-03-10-00-00": RunStatusFailed,
+	})
+
+	runs, err := ListRuns(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, r := range runs {
+		ids = append(ids, r.ID())
+	}
+	expected := []string{"apply/2018-01-03-10-00-00", "step/2018-01-02-10-00-00", "apply/2018-01-01-10-00-00"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected runs %v, got %v", expected, ids)
+	}
+
+	r, err := GetRun(runsDir, "step/2018-01-02-10-00-00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Status != RunStatusFailed {
+		t.Errorf("expected run status %q, got %q", RunStatusFailed, r.Status)
+	}
+	for _, id := range []string{"step/2018-01-05-10-00-00", "step", "../step/2018-01-02-10-00-00"} {
+		if _, err := GetRun(runsDir, id); err == nil {
+			t.Errorf("expected an error getting run %q", id)
+		}
+	}
+
+	runs, err = ListRuns(filepath.Join(runsDir, "missing"))
+	if err != nil || len(runs) != 0 {
+		t.Errorf("expected no runs in a missing directory, got %v, %v", runs, err)
+	}
+}
+
+func TestPruneRuns(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-runs")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+	writeTestRuns(t, runsDir, map[string]string{
+		"apply/2018-01-01-10-00-00":                          RunStatusSucceeded,
+		"apply/2018-01-02-10-00-00":                          RunStatusSucceeded,
+		"apply/2018-01-03-10-00-00":                          RunStatusFailed,
+		"apply/2018-01-04-10-00-00":                          RunStatusFailed,
+		"apply/" + time.Now().Format(runDirectoryTimeFormat): RunStatusRunning,
+		"step/2018-01-01-10-00-00":                           RunStatusFailed,
+		"step/2018-01-02-10-00-00":                           RunStatusFailed,
+	})
+
+	pruned, err := PruneRuns(runsDir, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, r := range pruned {
+		ids = append(ids, r.ID())
+	}
+	sort.Strings(ids)
+	// the running run is the most recent apply, and the last successful
+	// apply is kept as well
+	expected := []string{"apply/2018-01-01-10-00-00", "apply/2018-01-03-10-00-00", "apply/2018-01-04-10-00-00", "step/2018-01-01-10-00-00"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected pruned runs %v, got %v", expected, ids)
+	}
+	runs, err := ListRuns(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 3 {
+		t.Errorf("expected 3 runs to be kept, got %d", len(runs))
+	}
+
+	if _, err := PruneRuns(runsDir, -1); err == nil {
+		t.Errorf("expected an error pruning with a negative number of runs to keep")
+	}
+}
+
+func TestPruneStaleRuns(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-runs")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+	now := time.Now()
+	runs := []struct {
+		start       time.Time
+		owner       string
+		pid         int
+		expectPrune bool
+	}{
+		// started too long ago, by an older version that did not record its owner
+		{start: now.Add(-StaleRunAge - time.Hour), expectPrune: true},
+		// started too long ago, by a command that is still running
+		{start: now.Add(-StaleRunAge - 2*time.Hour), owner: lockOwner(), pid: os.Getpid(), expectPrune: true},
+		// started by a command that exited on this host
+		{start: now.Add(-time.Minute), owner: lockOwner(), pid: exitedPID(t), expectPrune: true},
+		// started by a command that is still running on this host
+		{start: now.Add(-2 * time.Minute), owner: lockOwner(), pid: os.Getpid()},
+		// started by a command on another host, which cannot be checked
+		{start: now.Add(-3 * time.Minute), owner: "ops@elsewhere", pid: exitedPID(t)},
+		// started recently, by an older version that did not record its owner
+		{start: now.Add(-4 * time.Minute)},
+	}
+	expected := []string{}
+	for _, r := range runs {
+		runDir := filepath.Join(runsDir, "apply", r.start.Format(runDirectoryTimeFormat))
+		if err := os.MkdirAll(runDir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		record := RunRecord{Task: "apply", Status: RunStatusRunning, Owner: r.owner, PID: r.pid}
+		if err := writeRunRecord(runDir, record); err != nil {
+			t.Fatalf("error writing run record: %v", err)
+		}
+		if r.expectPrune {
+			expected = append(expected, "apply/"+filepath.Base(runDir))
+		}
+	}
+
+	pruned, err := PruneRuns(runsDir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, r := range pruned {
+		ids = append(ids, r.ID())
+	}
+	sort.Strings(ids)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected pruned runs %v, got %v", expected, ids)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	runsDir, err := ioutil.TempDir("", "ket-test-runs")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+	writeTestRuns(t, runsDir, map[string]string{"apply/2018-01-01-10-00-00": RunStatusFailed})
+	r, err := GetRun(runsDir, "apply/2018-01-01-10-00-00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, err := r.Summary()
+	if err != nil || s != nil {
+		t.Errorf("expected no summary when no events were recorded, got %v, %v", s, err)
+	}
+
+	events := `{"eventType":"PLAYBOOK_START","eventData":{"name":"kubernetes.yaml","count":2}}
+{"eventType":"PLAY_START","eventData":{"name":"Gather Facts","file":"_all.yaml"}}
+{"eventType":"TASK_START","eventData":{"name":"setup"}}
+{"eventType":"RUNNER_OK","eventData":{"host":"etcd01","result":{}}}
+{"eventType":"RUNNER_OK","eventData":{"host":"worker01","result":{}}}
+{"eventType":"PLAY_START","eventData":{"name":"Start etcd","file":"_etcd-k8s.yaml"}}
+{"eventType":"TASK_START","eventData":{"name":"start etcd"}}
+{"eventType":"RUNNER_SKIPPED","eventData":{"host":"worker01","result":{}}}
+{"eventType":"RUNNER_FAILED","eventData":{"host":"etcd01","result":{"msg":"etcd did not start"},"ignoreErrors":false}}
+{"eventType":"RUNNER_UNREACHABLE","eventData":{"host":"etcd02","result":{"msg":"ssh timed out"}}}
+{"eventType":"PLAYBOOK_END","eventData":{}}
+`
+	if err := ioutil.WriteFile(filepath.Join(r.Directory, "events.jsonl"), []byte(events), 0644); err != nil {
+		t.Fatalf("error writing events: %v", err)
+	}
+	s, err = r.Summary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Playbook != "kubernetes.yaml" || len(s.Plays) != 2 || s.Plays[1].Tasks != 1 {
+		t.Errorf("unexpected plays in summary: %+v", s)
+	}
+	if *s.Hosts["etcd01"] != (HostSummary{OK: 1, Failed: 1}) || *s.Hosts["worker01"] != (HostSummary{OK: 1, Skipped: 1}) {
+		t.Errorf("unexpected host results: etcd01 %+v, worker01 %+v", s.Hosts["etcd01"], s.Hosts["worker01"])
+	}
+	expected := []TaskFailure{
+		{Play: "Start etcd", Task: "start etcd", Host: "etcd01", Message: "etcd did not start"},
+		{Play: "Start etcd", Task: "start etcd", Host: "etcd02", Message: "ssh timed out", Unreachable: true},
+	}
+	if !reflect.DeepEqual(s.Failures, expected) {
+		t.Errorf("expected failures %+v, got %+v", expected, s.Failures)
+	}
+}
@@ -0,0 +1,140 @@
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+const (
+	secretFilePrefix = "file://"
+	secretExecPrefix = "exec:"
+	// becomePasswordPath is the path of the become password in the plan file
+	becomePasswordPath = "cluster.ssh.become_password"
+)
+
+// becomePassword is the password for sudo on the nodes that was entered by
+// the user
+var becomePassword string
+
+// SetBecomePassword sets the password for sudo on the nodes of the plans
+// that are read, unless the plan refers to its own password. The password is
+// never written to a plan file.
+func SetBecomePassword(password string) {
+	becomePassword = password
+}
+
+var secretEnvRegexp = regexp.MustCompile(`^\$\{env:([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// IsSecretReference returns true if the value refers to a secret that is
+// stored outside of the plan file. The supported references are:
+// ${env:NAME} for environment variables, file:///path for files, and
+// exec:command for the output of a command.
+func IsSecretReference(s string) bool {
+	return secretEnvRegexp.MatchString(s) || strings.HasPrefix(s, secretFilePrefix) || strings.HasPrefix(s, secretExecPrefix)
+}
+
+// resolveSecretReference returns the secret the reference refers to
+func resolveSecretReference(ref string) (string, error) {
+	switch {
+	case secretEnvRegexp.MatchString(ref):
+		name := secretEnvRegexp.FindStringSubmatch(ref)[1]
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(ref, secretFilePrefix):
+		file := strings.TrimPrefix(ref, secretFilePrefix)
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("error reading file: %v", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(ref, secretExecPrefix):
+		command := strings.TrimSpace(strings.TrimPrefix(ref, secretExecPrefix))
+		if command == "" {
+			return "", fmt.Errorf("command is empty")
+		}
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("error running command %q: %v: %s", command, err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	}
+	return "", fmt.Errorf("%q is not a secret reference", ref)
+}
+
+// secretFields returns the fields of the plan that can contain secret
+// references, keyed by their path in the plan file.
+func secretFields(p *Plan) map[string]*string {
+	fields := map[string]*string{
+		"cluster.admin_password":   &p.Cluster.AdminPassword,
+		"docker_registry.password": &p.DockerRegistry.Password,
+		becomePasswordPath:         &p.Cluster.SSH.BecomePassword,
+	}
+	if p.AddOns.CNI != nil {
+		fields["add_ons.cni.options.weave.password"] = &p.AddOns.CNI.Options.Weave.Password
+	}
+	return fields
+}
+
+// resolveSecrets replaces the secret references in the plan with the secrets
+// they refer to. The references are kept in the plan, so that they are
+// written back instead of the secrets. The become password must be a
+// reference, and the password set with SetBecomePassword is never written.
+func resolveSecrets(p *Plan) error {
+	if p.Cluster.SSH.BecomePassword != "" && !IsSecretReference(p.Cluster.SSH.BecomePassword) {
+		return fmt.Errorf("%q must be a secret reference, such as ${env:NAME}, the password cannot be stored in the plan file", becomePasswordPath)
+	}
+	for path, field := range secretFields(p) {
+		ref := *field
+		if !IsSecretReference(ref) {
+			continue
+		}
+		secret, err := resolveSecretReference(ref)
+		if err != nil {
+			return fmt.Errorf("error resolving secret reference of %q: %v", path, err)
+		}
+		if p.secretReferences == nil {
+			p.secretReferences = map[string]string{}
+		}
+		p.secretReferences[path] = ref
+		*field = secret
+	}
+	if p.Cluster.SSH.BecomePassword == "" && becomePassword != "" {
+		if p.secretReferences == nil {
+			p.secretReferences = map[string]string{}
+		}
+		// the empty reference is written back instead of the password
+		p.secretReferences[becomePasswordPath] = ""
+		p.Cluster.SSH.BecomePassword = becomePassword
+	}
+	return nil
+}
+
+// withSecretReferences returns a copy of the plan where the secrets that were
+// resolved from a reference are replaced by the reference.
+func withSecretReferences(p *Plan) *Plan {
+	if len(p.secretReferences) == 0 {
+		return p
+	}
+	c := *p
+	if p.AddOns.CNI != nil {
+		cni := *p.AddOns.CNI
+		c.AddOns.CNI = &cni
+	}
+	for path, field := range secretFields(&c) {
+		if ref, ok := p.secretReferences[path]; ok {
+			*field = ref
+		}
+	}
+	return &c
+}
@@ -0,0 +1,267 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestResolveSecretReference(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-secrets")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	secretFile := filepath.Join(tmp, "secret")
+	if err = ioutil.WriteFile(secretFile, []byte("fromfile\n"), 0600); err != nil {
+		t.Fatalf("error writing secret file: %v", err)
+	}
+	os.Setenv("KET_TEST_SECRET", "fromenv")
+	defer os.Unsetenv("KET_TEST_SECRET")
+
+	tests := []struct {
+		ref         string
+		expected    string
+		shouldError bool
+	}{
+		{ref: "${env:KET_TEST_SECRET}", expected: "fromenv"},
+		{ref: "${env:KET_TEST_SECRET_NOT_SET}", shouldError: true},
+		{ref: "file://" + secretFile, expected: "fromfile"},
+		{ref: "file://" + filepath.Join(tmp, "missing"), shouldError: true},
+		{ref: "exec:echo fromexec", expected: "fromexec"},
+		{ref: "exec:exit 1", shouldError: true},
+		{ref: "exec:", shouldError: true},
+		{ref: "password", shouldError: true},
+	}
+	for _, test := range tests {
+		secret, err := resolveSecretReference(test.ref)
+		if err != nil && !test.shouldError {
+			t.Errorf("%s: unexpected error: %v", test.ref, err)
+		}
+		if err == nil && test.shouldError {
+			t.Errorf("%s: expected an error, but didn't get one", test.ref)
+		}
+		if secret != test.expected {
+			t.Errorf("%s: expected %q, but got %q", test.ref, test.expected, secret)
+		}
+	}
+}
+
+func TestFilePlannerSecretReferences(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-secrets")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	os.Setenv("KET_TEST_REGISTRY_PASSWORD", "registrysecret")
+	defer os.Unsetenv("KET_TEST_REGISTRY_PASSWORD")
+
+	file := filepath.Join(tmp, "kismatic-cluster.yaml")
+	plan := `apiVersion: v1
+cluster:
+  admin_password: plaintext
+docker_registry:
+  password: ${env:KET_TEST_REGISTRY_PASSWORD}
+add_ons:
+  cni:
+    provider: weave
+    options:
+      weave:
+        password: exec:echo weavesecret
+`
+	if err = ioutil.WriteFile(file, []byte(plan), 0644); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	fp := &FilePlanner{File: file}
+	p, err := fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	if p.DockerRegistry.Password != "registrysecret" {
+		t.Errorf("expected docker registry password to be resolved, got %q", p.DockerRegistry.Password)
+	}
+	if p.AddOns.CNI.Options.Weave.Password != "weavesecret" {
+		t.Errorf("expected weave password to be resolved, got %q", p.AddOns.CNI.Options.Weave.Password)
+	}
+	if p.Cluster.AdminPassword != "plaintext" {
+		t.Errorf("expected admin password to be unchanged, got %q", p.Cluster.AdminPassword)
+	}
+
+	// the references are written back, and the plan is not modified
+	out := &FilePlanner{File: filepath.Join(tmp, "written.yaml")}
+	if err = out.Write(p); err != nil {
+		t.Fatalf("unexpected error writing plan: %v", err)
+	}
+	written, err := ioutil.ReadFile(out.File)
+	if err != nil {
+		t.Fatalf("error reading written plan: %v", err)
+	}
+	for _, secret := range []string{"registrysecret", "weavesecret"} {
+		if strings.Contains(string(written), "password: "+secret) {
+			t.Errorf("expected secret %q to not be written to the plan file", secret)
+		}
+	}
+	for _, ref := range []string{"${env:KET_TEST_REGISTRY_PASSWORD}", "exec:echo weavesecret"} {
+		if !strings.Contains(string(written), ref) {
+			t.Errorf("expected reference %q to be written to the plan file", ref)
+		}
+	}
+	if p.DockerRegistry.Password != "registrysecret" || p.AddOns.CNI.Options.Weave.Password != "weavesecret" {
+		t.Errorf("expected writing the plan to not modify it")
+	}
+
+	// references are not resolved when they should be kept
+	os.Unsetenv("KET_TEST_REGISTRY_PASSWORD")
+	fp.KeepSecretReferences = true
+	p, err = fp.Read()
+	if err != nil {
+		t.Fatalf("unexpected error reading plan: %v", err)
+	}
+	if p.DockerRegistry.Password != "${env:KET_TEST_REGISTRY_PASSWORD}" {
+		t.Errorf("expected docker registry password to be a reference, got %q", p.DockerRegistry.Password)
+	}
+}
+
+func TestFilePlannerBecomePassword(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-secrets")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	os.Setenv("KET_TEST_BECOME_PASSWORD", "becomesecret")
+	defer os.Unsetenv("KET_TEST_BECOME_PASSWORD")
+
+	tests := []struct {
+		becomePassword  string
+		entered         string
+		expected        string
+		expectedWritten string
+		shouldError     bool
+	}{
+		{
+			becomePassword:  "${env:KET_TEST_BECOME_PASSWORD}",
+			expected:        "becomesecret",
+			expectedWritten: "become_password: ${env:KET_TEST_BECOME_PASSWORD}",
+		},
+		{
+			becomePassword:  "${env:KET_TEST_BECOME_PASSWORD}",
+			entered:         "enteredsecret",
+			expected:        "becomesecret",
+			expectedWritten: "become_password: ${env:KET_TEST_BECOME_PASSWORD}",
+		},
+		{
+			entered:  "enteredsecret",
+			expected: "enteredsecret",
+		},
+		{
+			becomePassword: "plaintext",
+			shouldError:    true,
+		},
+	}
+	for i, test := range tests {
+		file := filepath.Join(tmp, "kismatic-cluster.yaml")
+		plan := "apiVersion: v1\ncluster:\n  ssh:\n    user: kismaticuser\n"
+		if test.becomePassword != "" {
+			plan += "    become_password: " + test.becomePassword + "\n"
+		}
+		if err = ioutil.WriteFile(file, []byte(plan), 0644); err != nil {
+			t.Fatalf("error writing plan file: %v", err)
+		}
+		SetBecomePassword(test.entered)
+		p, err := (&FilePlanner{File: file}).Read()
+		SetBecomePassword("")
+		if err != nil && !test.shouldError {
+			t.Errorf("test %d: unexpected error reading plan: %v", i, err)
+		}
+		if err == nil && test.shouldError {
+			t.Errorf("test %d: expected an error, but didn't get one", i)
+		}
+		if err != nil {
+			continue
+		}
+		if p.Cluster.SSH.BecomePassword != test.expected {
+			t.Errorf("test %d: expected become password %q, got %q", i, test.expected, p.Cluster.SSH.BecomePassword)
+		}
+
+		out := &FilePlanner{File: filepath.Join(tmp, "written.yaml")}
+		if err = out.Write(p); err != nil {
+			t.Fatalf("unexpected error writing plan: %v", err)
+		}
+		written, err := ioutil.ReadFile(out.File)
+		if err != nil {
+			t.Fatalf("error reading written plan: %v", err)
+		}
+		if strings.Contains(string(written), test.expected) {
+			t.Errorf("test %d: expected the become password to not be written to the plan file", i)
+		}
+		if !strings.Contains(string(written), test.expectedWritten) {
+			t.Errorf("test %d: expected %q to be written to the plan file", i, test.expectedWritten)
+		}
+	}
+}
+
+func TestExecuteDoesNotRecordSecrets(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "ket-test-secrets")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+	ansibleDir := filepath.Join(tmp, "ansible")
+	for _, d := range []string{"bin", "playbooks"} {
+		if err = os.MkdirAll(filepath.Join(ansibleDir, d), 0700); err != nil {
+			t.Fatalf("error creating directory: %v", err)
+		}
+	}
+	if err = ioutil.WriteFile(filepath.Join(ansibleDir, "bin", "ansible-playbook"), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatalf("error writing fake ansible-playbook: %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(ansibleDir, "playbooks", "kubernetes.yaml"), []byte{}, 0600); err != nil {
+		t.Fatalf("error writing playbook: %v", err)
+	}
+	os.Setenv("KET_TEST_ADMIN_PASSWORD", "adminsecret")
+	defer os.Unsetenv("KET_TEST_ADMIN_PASSWORD")
+	secretFile := filepath.Join(tmp, "registry-password")
+	if err = ioutil.WriteFile(secretFile, []byte("registrysecret\n"), 0600); err != nil {
+		t.Fatalf("error writing secret file: %v", err)
+	}
+	plan := Plan{}
+	plan.Cluster.AdminPassword = "${env:KET_TEST_ADMIN_PASSWORD}"
+	plan.DockerRegistry.Password = "file://" + secretFile
+	if err = resolveSecrets(&plan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cc := ansible.ClusterCatalog{AdminPassword: plan.Cluster.AdminPassword, DockerRegistryPassword: plan.DockerRegistry.Password}
+	ae := &ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: filepath.Join(tmp, "runs")},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.JSONLinesFormat,
+		ansibleDir:          ansibleDir,
+	}
+	if err = ae.execute(task{name: "apply", playbook: "kubernetes.yaml", plan: plan, clusterCatalog: cc, explainer: noopExplainer{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the plan is recorded with the references of the secrets, and the
+	// cluster catalog is redacted
+	err = filepath.Walk(ae.options.RunsDirectory, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, secret := range []string{"adminsecret", "registrysecret"} {
+			if strings.Contains(string(b), secret) {
+				t.Errorf("secret %q was recorded in %s", secret, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
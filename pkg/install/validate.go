@@ -153,10 +153,40 @@ func (p *Plan) validate() (bool, []error) {
 	v.validateWithErrPrefix("Ingress nodes", &p.Ingress)
 	v.validate(p.NFS)
 	v.validateWithErrPrefix("Storage nodes", &p.Storage)
+	v.addError(p.validateIPFamilies()...)
+	v.validate(networkTopology{Plan: p})
 
 	return v.valid()
 }
 
+// validateIPFamilies verifies that the nodes and the CNI provider support the
+// IP family of the cluster networks
+func (p *Plan) validateIPFamilies() []error {
+	errs := []error{}
+	n := p.Cluster.Networking
+	if !n.IPv6Enabled() {
+		return errs
+	}
+	if p.AddOns.CNI != nil && !p.AddOns.CNI.Disable && p.AddOns.CNI.Provider != cniProviderCalico {
+		errs = append(errs, fmt.Errorf("The %q CNI provider does not support IPv6 pod networks, use %q instead", p.AddOns.CNI.Provider, cniProviderCalico))
+	}
+	if _, _, err := net.ParseCIDR(n.ServiceCIDRBlock); err != nil {
+		return errs
+	}
+	// the nodes must be reachable on the IP family of the service network
+	serviceIPv6 := isIPv6CIDR(n.ServiceCIDRBlock)
+	for _, node := range p.GetUniqueNodes() {
+		ip := node.IP
+		if node.InternalIP != "" {
+			ip = node.InternalIP
+		}
+		if net.ParseIP(ip) != nil && isIPv6Address(ip) != serviceIPv6 {
+			errs = append(errs, fmt.Errorf("Node %q: IP %q must be of the same IP family as the Service CIDR block %q", node.Host, ip, n.ServiceCIDRBlock))
+		}
+	}
+	return errs
+}
+
 func (c *Cluster) validate() (bool, []error) {
 	v := newValidator()
 	if c.Name == "" {
@@ -201,8 +231,14 @@ func (n *NetworkConfig) validate() (bool, []error) {
 	if n.PodCIDRBlock == "" {
 		v.addError(errors.New("Pod CIDR block cannot be empty"))
 	}
-	if _, _, err := net.ParseCIDR(n.PodCIDRBlock); n.PodCIDRBlock != "" && err != nil {
-		v.addError(fmt.Errorf("Invalid Pod CIDR block provided: %v", err))
+	podBlocks := cidrBlocks(n.PodCIDRBlock)
+	for _, b := range podBlocks {
+		if _, _, err := net.ParseCIDR(b); err != nil {
+			v.addError(fmt.Errorf("Invalid Pod CIDR block provided: %v", err))
+		}
+	}
+	if len(podBlocks) > 2 || (len(podBlocks) == 2 && isIPv6CIDR(podBlocks[0]) == isIPv6CIDR(podBlocks[1])) {
+		v.addError(fmt.Errorf("Invalid Pod CIDR block provided: %q must be a single CIDR block, or an IPv4 and an IPv6 CIDR block separated by a comma", n.PodCIDRBlock))
 	}
 
 	if n.ServiceCIDRBlock == "" {
@@ -211,6 +247,9 @@ func (n *NetworkConfig) validate() (bool, []error) {
 	if _, _, err := net.ParseCIDR(n.ServiceCIDRBlock); n.ServiceCIDRBlock != "" && err != nil {
 		v.addError(fmt.Errorf("Invalid Service CIDR block provided: %v", err))
 	}
+	if n.ServiceCIDRBlock != "" && len(podBlocks) > 0 && isIPv6CIDR(n.ServiceCIDRBlock) != isIPv6CIDR(podBlocks[0]) {
+		v.addError(fmt.Errorf("The first Pod CIDR block %q and the Service CIDR block %q must be of the same IP family", podBlocks[0], n.ServiceCIDRBlock))
+	}
 	return v.valid()
 }
 
@@ -230,18 +269,58 @@ func (s *SSHConfig) validate() (bool, []error) {
 	if s.User == "" {
 		v.addError(errors.New("SSH user field is required"))
 	}
-	if s.Key == "" {
-		v.addError(errors.New("SSH key field is required"))
+	if s.Key == "" && !ssh.AgentAvailable() {
+		v.addError(errors.New("SSH key field is required, unless an ssh-agent is running (SSH_AUTH_SOCK is set)"))
 	}
-	if _, err := os.Stat(s.Key); os.IsNotExist(err) {
-		v.addError(fmt.Errorf("SSH Key file was not found at %q", s.Key))
-	}
-	if !filepath.IsAbs(s.Key) {
-		v.addError(errors.New("SSH Key field must be an absolute path"))
+	if s.Key != "" {
+		if _, err := os.Stat(s.Key); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("SSH Key file was not found at %q", s.Key))
+		}
+		if !filepath.IsAbs(s.Key) {
+			v.addError(errors.New("SSH Key field must be an absolute path"))
+		}
 	}
 	if s.Port < 1 || s.Port > 65535 {
 		v.addError(fmt.Errorf("SSH port %d is invalid. Port must be in the range 1-65535", s.Port))
 	}
+	if s.Bastion != nil {
+		v.validate(s.Bastion)
+	}
+	return v.valid()
+}
+
+func (s *NodeSSHConfig) validate() (bool, []error) {
+	v := newValidator()
+	if s.Key != "" {
+		if _, err := os.Stat(s.Key); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("Node SSH Key file was not found at %q", s.Key))
+		}
+		if !filepath.IsAbs(s.Key) {
+			v.addError(errors.New("Node SSH Key field must be an absolute path"))
+		}
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		v.addError(fmt.Errorf("Node SSH port %d is invalid. Port must be in the range 1-65535", s.Port))
+	}
+	return v.valid()
+}
+
+func (b *BastionConfig) validate() (bool, []error) {
+	v := newValidator()
+	if b.Host == "" {
+		v.addError(errors.New("Bastion host field is required"))
+	}
+	if b.Key != "" {
+		if _, err := os.Stat(b.Key); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("Bastion SSH Key file was not found at %q", b.Key))
+		}
+		if !filepath.IsAbs(b.Key) {
+			v.addError(errors.New("Bastion SSH Key field must be an absolute path"))
+		}
+	}
+	if b.Port < 0 || b.Port > 65535 {
+		v.addError(fmt.Errorf("Bastion SSH port %d is invalid. Port must be in the range 1-65535", b.Port))
+	}
 	return v.valid()
 }
 
@@ -333,7 +412,7 @@ func (h *HeapsterMonitoring) validate() (bool, []error) {
 	v := newValidator()
 	if h != nil && !h.Disable {
 		if h.Options.Heapster.Replicas <= 0 {
-			v.addError(fmt.Errorf("Heapster replicas %d is not valid, must be greater than 0", h.Options.HeapsterReplicas))
+			v.addError(fmt.Errorf("Heapster replicas %d is not valid, must be greater than 0", h.Options.Heapster.Replicas))
 		}
 		if !util.Contains(h.Options.Heapster.ServiceType, serviceTypes()) {
 			v.addError(fmt.Errorf("Heapster Service Type %q is not a valid option %v", h.Options.Heapster.ServiceType, serviceTypes()))
@@ -369,25 +448,47 @@ func (p *PackageManager) validate() (bool, []error) {
 func (s sshConnectionSet) validate() (bool, []error) {
 	v := newValidator()
 
-	err := ssh.ValidUnencryptedPrivateKey(s.SSHConfig.Key)
-	if err != nil {
-		v.addError(fmt.Errorf("SSH key validation error: %v", err))
-	} else {
+	bastion := s.SSHConfig.BastionHost()
+	if err := validBastionKey(bastion); err != nil {
+		v.addError(fmt.Errorf("Bastion SSH key validation error: %v", err))
+		return v.valid()
+	}
+	// The nodes can override the SSH configuration of the cluster, each key
+	// is validated once
+	keyErrs := map[string]error{}
+	connections := []SSHConfig{}
+	nodes := []Node{}
+	for _, node := range s.Nodes {
+		config := s.SSHConfig.ForNode(node)
+		err, seen := keyErrs[config.Key]
+		if !seen {
+			err = validKey(config.Key)
+			keyErrs[config.Key] = err
+			if err != nil {
+				v.addError(fmt.Errorf("SSH key validation error: %v", err))
+			}
+		}
+		if err == nil {
+			connections = append(connections, config)
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) > 0 {
 		var wg sync.WaitGroup
-		errQueue := make(chan error, len(s.Nodes))
+		errQueue := make(chan error, len(nodes))
 		// number of nodes
-		wg.Add(len(s.Nodes))
-		for _, node := range s.Nodes {
-			go func(ip string) {
+		wg.Add(len(nodes))
+		for i, node := range nodes {
+			go func(ip string, config SSHConfig) {
 				defer wg.Done()
-				sshErr := ssh.TestConnection(ip, s.SSHConfig.Port, s.SSHConfig.User, s.SSHConfig.Key)
+				sshErr := ssh.TestConnection(ip, config.Port, config.User, config.Key, bastion)
 				// Need to send something the buffered channel
 				if sshErr != nil {
 					errQueue <- fmt.Errorf("SSH connectivity validation failed for %q: %v", ip, sshErr)
 				} else {
 					errQueue <- nil
 				}
-			}(node.IP)
+			}(node.IP, connections[i])
 		}
 
 		// Wait for all nodes to complete, then close channel
@@ -407,6 +508,23 @@ func (s sshConnectionSet) validate() (bool, []error) {
 	return v.valid()
 }
 
+// validKey validates the key, unless it is empty and the keys of the
+// ssh-agent are used
+func validKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	return ssh.ValidPrivateKey(key)
+}
+
+// validBastionKey validates the key of the bastion host, if there is one
+func validBastionKey(b *ssh.Bastion) error {
+	if b == nil {
+		return nil
+	}
+	return validKey(b.Key)
+}
+
 type nodeList struct {
 	Nodes []Node
 }
@@ -415,6 +533,7 @@ func (nl nodeList) validate() (bool, []error) {
 	v := newValidator()
 	v.addError(validateNoDuplicateNodeInfo(nl.Nodes)...)
 	v.addError(validateKubeletOptionsDefinedOnce(nl.Nodes)...)
+	v.addError(validateSSHOptionsDefinedOnce(nl.Nodes)...)
 	return v.valid()
 }
 
@@ -459,6 +578,19 @@ func validateKubeletOptionsDefinedOnce(nodes []Node) []error {
 	return errs
 }
 
+func validateSSHOptionsDefinedOnce(nodes []Node) []error {
+	errs := []error{}
+	seenNodes := map[string]*NodeSSHConfig{}
+	for _, n := range nodes {
+		if val, ok := seenNodes[n.HashCode()]; ok && !reflect.DeepEqual(val, n.SSH) {
+			errs = append(errs, fmt.Errorf("Cannot redefine SSH options for node %q", n.Host))
+		} else {
+			seenNodes[n.HashCode()] = n.SSH
+		}
+	}
+	return errs
+}
+
 func (ng *NodeGroup) validate() (bool, []error) {
 	v := newValidator()
 	if ng == nil || len(ng.Nodes) <= 0 {
@@ -473,10 +605,24 @@ func (ng *NodeGroup) validate() (bool, []error) {
 	for i, n := range ng.Nodes {
 		v.validateWithErrPrefix(fmt.Sprintf("Node #%d", i+1), &n)
 	}
+	if ng.InventorySource != nil {
+		v.validateWithErrPrefix("Inventory Source", ng.InventorySource)
+	}
 
 	return v.valid()
 }
 
+func (s *InventorySource) validate() (bool, []error) {
+	v := newValidator()
+	if !util.Contains(s.Type, inventorySourceTypes()) {
+		v.addError(fmt.Errorf("%q is not a valid inventory source type. Options are %v", s.Type, inventorySourceTypes()))
+	}
+	if s.Path == "" {
+		v.addError(fmt.Errorf("Path cannot be empty"))
+	}
+	return v.valid()
+}
+
 // In order to make this node group optional, we consider it to be valid if:
 // - it's nil
 // - the number of nodes is zero, and the expected count is zero
@@ -511,6 +657,9 @@ func (mng *MasterNodeGroup) validate() (bool, []error) {
 	for i, n := range mng.Nodes {
 		v.validateWithErrPrefix(fmt.Sprintf("Node #%d", i+1), &n)
 	}
+	if mng.InventorySource != nil {
+		v.validateWithErrPrefix("Inventory Source", mng.InventorySource)
+	}
 
 	if mng.LoadBalancer == "" {
 		v.addError(fmt.Errorf("Load Balancer IP or DNS is required"))
@@ -551,6 +700,9 @@ func (n *Node) validate() (bool, []error) {
 			v.addError(fmt.Errorf("Node label %q is not valid %s", val, err))
 		}
 	}
+	if n.SSH != nil {
+		v.validate(n.SSH)
+	}
 	// Validate node taints don't start with 'kismatic/' as that is reserved
 	// Don't validate effects as those will likely change
 	for _, taint := range n.Taints {
@@ -693,6 +845,9 @@ func (sv StorageVolume) validate() (bool, []error) {
 }
 
 func validateAllowedAddress(address string) bool {
+	if strings.Contains(address, ":") {
+		return validateAllowedIPv6Address(address)
+	}
 	// First, validate that there are four octets with 1, 2 or 3 chars, separated by dots
 	r := regexp.MustCompile(`^[0-9*]{1,3}\.[0-9*]{1,3}\.[0-9*]{1,3}\.[0-9*]{1,3}$`)
 	if !r.MatchString(address) {
@@ -710,3 +865,21 @@ func validateAllowedAddress(address string) bool {
 	}
 	return true
 }
+
+// validateAllowedIPv6Address validates an IPv6 address, where groups can be
+// replaced by a wildcard
+func validateAllowedIPv6Address(address string) bool {
+	r := regexp.MustCompile(`^[0-9a-fA-F:*]+$`)
+	if !r.MatchString(address) {
+		return false
+	}
+	groups := strings.Split(address, ":")
+	for i, g := range groups {
+		if g == "*" {
+			groups[i] = "0"
+		} else if strings.Contains(g, "*") {
+			return false
+		}
+	}
+	return net.ParseIP(strings.Join(groups, ":")) != nil
+}
@@ -318,6 +318,60 @@ func TestValidatePlanInvalidServicesCIDR(t *testing.T) {
 	assertInvalidPlan(t, p)
 }
 
+func TestValidatePlanDualStackPodCIDR(t *testing.T) {
+	p := validPlan()
+	p.Cluster.Networking.PodCIDRBlock = "172.16.0.0/16,fd00:10:244::/64"
+	if valid, errs := ValidatePlan(&p); !valid {
+		t.Errorf("expected valid, but got invalid: %v", errs)
+	}
+}
+
+func TestValidatePlanInvalidDualStackPodCIDR(t *testing.T) {
+	tests := []string{
+		"172.16.0.0/16,172.17.0.0/16",
+		"fd00:10:244::/64,fd00:10:245::/64",
+		"172.16.0.0/16,fd00:10:244::/64,172.17.0.0/16",
+		"172.16.0.0/16,foo",
+		// the first CIDR block must be of the same family as the service network
+		"fd00:10:244::/64,172.16.0.0/16",
+	}
+	for _, cidr := range tests {
+		p := validPlan()
+		p.Cluster.Networking.PodCIDRBlock = cidr
+		if valid, _ := ValidatePlan(&p); valid {
+			t.Errorf("expected pod CIDR block %q to be invalid", cidr)
+		}
+	}
+}
+
+func TestValidatePlanIPv6(t *testing.T) {
+	p := validPlan()
+	p.Cluster.Networking.PodCIDRBlock = "fd00:10:244::/64"
+	p.Cluster.Networking.ServiceCIDRBlock = "fd00:10:96::/112"
+	p.Etcd.Nodes[0].IP = "fd00::10"
+	p.Master.Nodes[0].IP = "fd00::11"
+	p.Master.LoadBalancer = "[fd00::11]:6443"
+	p.Worker.Nodes[0].IP = "fd00::12"
+	p.Ingress.Nodes[0].IP = "fd00::10"
+	if valid, errs := ValidatePlan(&p); !valid {
+		t.Errorf("expected valid, but got invalid: %v", errs)
+	}
+}
+
+func TestValidatePlanIPv6NodeIPFamily(t *testing.T) {
+	p := validPlan()
+	p.Cluster.Networking.PodCIDRBlock = "fd00:10:244::/64"
+	p.Cluster.Networking.ServiceCIDRBlock = "fd00:10:96::/112"
+	assertInvalidPlan(t, p)
+}
+
+func TestValidatePlanIPv6UnsupportedCNIProvider(t *testing.T) {
+	p := validPlan()
+	p.Cluster.Networking.PodCIDRBlock = "172.16.0.0/16,fd00:10:244::/64"
+	p.AddOns.CNI.Provider = "weave"
+	assertInvalidPlan(t, p)
+}
+
 func TestValidatePlanEmptyCertificatesExpiry(t *testing.T) {
 	p := validPlan()
 	p.Cluster.Certificates.Expiry = ""
@@ -754,6 +808,14 @@ func TestValidateAllowAddress(t *testing.T) {
 		{"192...", false},
 		{"192.168..", false},
 		{"192.168.205.", false},
+		{"fd00::10", true},
+		{"fd00:0:0:0:0:0:0:10", true},
+		{"fd00::*", true},
+		{"fd00:*:*:*:*:*:*:*", true},
+		{"fd00::1*", false},
+		{"fd00:::10", false},
+		{"fd00::g", false},
+		{"10000::", false},
 	}
 	for _, test := range tests {
 		if validateAllowedAddress(test.address) != test.valid {
@@ -2219,3 +2281,67 @@ func TestValidateFile(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateSSHConfigBastion(t *testing.T) {
+	tests := []struct {
+		bastion *BastionConfig
+		valid   bool
+	}{
+		{bastion: nil, valid: true},
+		{bastion: &BastionConfig{Host: "bastion.example.com"}, valid: true},
+		{bastion: &BastionConfig{Host: "bastion.example.com", Port: 2022, User: "bob", Key: "/bin/sh"}, valid: true},
+		{bastion: &BastionConfig{}, valid: false},
+		{bastion: &BastionConfig{Host: "bastion.example.com", Key: "bastion.pem"}, valid: false},
+		{bastion: &BastionConfig{Host: "bastion.example.com", Key: "/nonexistent/bastion.pem"}, valid: false},
+		{bastion: &BastionConfig{Host: "bastion.example.com", Port: 70000}, valid: false},
+	}
+	for i, test := range tests {
+		s := SSHConfig{User: "root", Key: "/bin/sh", Port: 22, Bastion: test.bastion}
+		ok, errs := s.validate()
+		if ok != test.valid {
+			t.Errorf("test %d: expected valid to be %v, got %v: %v", i, test.valid, ok, errs)
+		}
+	}
+}
+
+func TestValidateNodeSSHOverrides(t *testing.T) {
+	tests := []struct {
+		nodes []Node
+		valid bool
+	}{
+		{
+			nodes: []Node{
+				{Host: "node01", IP: "10.0.0.1", SSH: &NodeSSHConfig{User: "bob", Key: "/bin/sh", Port: 2222}},
+				{Host: "node01", IP: "10.0.0.1", SSH: &NodeSSHConfig{User: "bob", Key: "/bin/sh", Port: 2222}},
+			},
+			valid: true,
+		},
+		{
+			nodes: []Node{
+				{Host: "node01", IP: "10.0.0.1", SSH: &NodeSSHConfig{User: "bob"}},
+				{Host: "node01", IP: "10.0.0.1", SSH: &NodeSSHConfig{User: "carol"}},
+			},
+			valid: false,
+		},
+		{
+			nodes: []Node{
+				{Host: "node01", IP: "10.0.0.1", SSH: &NodeSSHConfig{User: "bob"}},
+				{Host: "node01", IP: "10.0.0.1"},
+			},
+			valid: false,
+		},
+	}
+	for i, test := range tests {
+		ok, errs := ValidateNodes(test.nodes)
+		if ok != test.valid {
+			t.Errorf("test %d: expected valid to be %v, got %v: %v", i, test.valid, ok, errs)
+		}
+	}
+
+	for _, s := range []NodeSSHConfig{{Key: "id_rsa"}, {Key: "/nonexistent/id_rsa"}, {Port: 70000}} {
+		n := Node{Host: "node01", IP: "10.0.0.1", SSH: &s}
+		if ok, _ := ValidateNode(&n); ok {
+			t.Errorf("expected SSH overrides %+v to be invalid", s)
+		}
+	}
+}
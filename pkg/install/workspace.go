@@ -0,0 +1,145 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/validation"
+)
+
+const (
+	// DefaultWorkspaceDirectory is the directory where the clusters of the
+	// workspace are kept
+	DefaultWorkspaceDirectory = "clusters"
+	// WorkspaceEnvVar can be set to use a workspace directory other than the default
+	WorkspaceEnvVar = "KISMATIC_WORKSPACE"
+
+	workspaceCurrentFile   = ".current"
+	clusterPlanFile        = "kismatic-cluster.yaml"
+	clusterGeneratedAssets = "generated"
+	clusterRuns            = "runs"
+)
+
+// Workspace is a directory that keeps the plan file, the generated assets
+// and the runs of multiple named clusters. Each cluster is kept in a
+// sub-directory named after the cluster.
+type Workspace struct {
+	Directory string
+}
+
+// WorkspaceCluster is a named cluster of a workspace
+type WorkspaceCluster struct {
+	Name      string
+	Directory string
+}
+
+// PlanFile returns the path to the plan file of the cluster
+func (c WorkspaceCluster) PlanFile() string {
+	return filepath.Join(c.Directory, clusterPlanFile)
+}
+
+// GeneratedAssetsDirectory returns the path to the generated assets directory of the cluster
+func (c WorkspaceCluster) GeneratedAssetsDirectory() string {
+	return filepath.Join(c.Directory, clusterGeneratedAssets)
+}
+
+// RunsDirectory returns the path to the directory where information about
+// the installation runs of the cluster is kept
+func (c WorkspaceCluster) RunsDirectory() string {
+	return filepath.Join(c.Directory, clusterRuns)
+}
+
+// DefaultWorkspace returns the workspace set in the KISMATIC_WORKSPACE
+// environment variable, or the default workspace
+func DefaultWorkspace() Workspace {
+	if dir := os.Getenv(WorkspaceEnvVar); dir != "" {
+		return Workspace{Directory: dir}
+	}
+	return Workspace{Directory: DefaultWorkspaceDirectory}
+}
+
+// Clusters returns the clusters of the workspace, sorted by name
+func (w Workspace) Clusters() ([]WorkspaceCluster, error) {
+	files, err := ioutil.ReadDir(w.Directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []WorkspaceCluster{}, nil
+		}
+		return nil, fmt.Errorf("error reading workspace: %v", err)
+	}
+	clusters := []WorkspaceCluster{}
+	for _, f := range files {
+		if !f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		clusters = append(clusters, w.cluster(f.Name()))
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return clusters, nil
+}
+
+// Cluster returns the cluster with the given name
+func (w Workspace) Cluster(name string) (*WorkspaceCluster, error) {
+	c := w.cluster(name)
+	fi, err := os.Stat(c.Directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cluster %q does not exist. Use 'kismatic cluster create' to create it", name)
+		}
+		return nil, fmt.Errorf("error reading cluster %q: %v", name, err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("cluster %q is not a directory", name)
+	}
+	return &c, nil
+}
+
+// CreateCluster creates a new cluster in the workspace
+func (w Workspace) CreateCluster(name string) (*WorkspaceCluster, error) {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid cluster name %q: %s", name, strings.Join(errs, ", "))
+	}
+	c := w.cluster(name)
+	if _, err := os.Stat(c.Directory); err == nil {
+		return nil, fmt.Errorf("cluster %q already exists", name)
+	}
+	if err := os.MkdirAll(c.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("error creating cluster directory: %v", err)
+	}
+	return &c, nil
+}
+
+// Use sets the cluster that is used by commands that don't target a cluster
+func (w Workspace) Use(name string) error {
+	if _, err := w.Cluster(name); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(w.Directory, workspaceCurrentFile), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("error setting the current cluster: %v", err)
+	}
+	return nil
+}
+
+// Current returns the cluster that is in use, or nil if no cluster is in use
+func (w Workspace) Current() (*WorkspaceCluster, error) {
+	b, err := ioutil.ReadFile(filepath.Join(w.Directory, workspaceCurrentFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading the current cluster: %v", err)
+	}
+	name := strings.TrimSpace(string(b))
+	if name == "" {
+		return nil, nil
+	}
+	return w.Cluster(name)
+}
+
+func (w Workspace) cluster(name string) WorkspaceCluster {
+	return WorkspaceCluster{Name: name, Directory: filepath.Join(w.Directory, name)}
+}
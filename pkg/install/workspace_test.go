@@ -0,0 +1,72 @@
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kismatic-workspace")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ws := Workspace{Directory: filepath.Join(dir, "clusters")}
+
+	clusters, err := ws.Clusters()
+	if err != nil {
+		t.Fatalf("unexpected error listing clusters of an empty workspace: %v", err)
+	}
+	if len(clusters) != 0 {
+		t.Errorf("expected no clusters, but got %v", clusters)
+	}
+	current, err := ws.Current()
+	if err != nil || current != nil {
+		t.Errorf("expected no current cluster, but got %v (err: %v)", current, err)
+	}
+
+	for _, name := range []string{"prod", "dev"} {
+		if _, err = ws.CreateCluster(name); err != nil {
+			t.Fatalf("unexpected error creating cluster %q: %v", name, err)
+		}
+	}
+	if _, err = ws.CreateCluster("prod"); err == nil {
+		t.Errorf("expected an error creating a cluster that already exists")
+	}
+	if _, err = ws.CreateCluster("Not_Valid"); err == nil {
+		t.Errorf("expected an error creating a cluster with an invalid name")
+	}
+
+	clusters, err = ws.Clusters()
+	if err != nil {
+		t.Fatalf("unexpected error listing clusters: %v", err)
+	}
+	if len(clusters) != 2 || clusters[0].Name != "dev" || clusters[1].Name != "prod" {
+		t.Errorf("expected clusters dev and prod, but got %v", clusters)
+	}
+
+	if err = ws.Use("staging"); err == nil {
+		t.Errorf("expected an error using a cluster that does not exist")
+	}
+	if err = ws.Use("prod"); err != nil {
+		t.Fatalf("unexpected error using cluster: %v", err)
+	}
+	current, err = ws.Current()
+	if err != nil {
+		t.Fatalf("unexpected error getting the current cluster: %v", err)
+	}
+	if current == nil || current.Name != "prod" {
+		t.Fatalf("expected the current cluster to be prod, but got %v", current)
+	}
+	if current.PlanFile() != filepath.Join(ws.Directory, "prod", "kismatic-cluster.yaml") {
+		t.Errorf("unexpected plan file %q", current.PlanFile())
+	}
+	if current.GeneratedAssetsDirectory() != filepath.Join(ws.Directory, "prod", "generated") {
+		t.Errorf("unexpected generated assets directory %q", current.GeneratedAssetsDirectory())
+	}
+	if current.RunsDirectory() != filepath.Join(ws.Directory, "prod", "runs") {
+		t.Errorf("unexpected runs directory %q", current.RunsDirectory())
+	}
+}
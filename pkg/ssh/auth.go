@@ -0,0 +1,195 @@
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// PassphrasePrompt returns the passphrase of an encrypted key. It is called
+// once per key file, the first time the key is used.
+var PassphrasePrompt = func(file string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return nil, fmt.Errorf("SSH key %q is protected by a passphrase. Add the key to a running ssh-agent, or run kismatic from a terminal to enter the passphrase", file)
+	}
+	fmt.Fprintf(os.Stderr, "Enter passphrase for SSH key %q: ", file)
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("error reading passphrase: %v", err)
+	}
+	return passphrase, nil
+}
+
+// privateKey is a key that was read, or the error reading it
+type privateKey struct {
+	raw    interface{}
+	signer ssh.Signer
+	err    error
+}
+
+// keys are the private keys that were read, by file, so that the passphrase
+// of an encrypted key is prompted only once
+var keys = struct {
+	sync.Mutex
+	files map[string]*privateKey
+}{files: map[string]*privateKey{}}
+
+func loadKey(file string) (*privateKey, error) {
+	keys.Lock()
+	defer keys.Unlock()
+	if k, ok := keys.files[file]; ok {
+		return k, k.err
+	}
+	k := &privateKey{}
+	k.raw, k.err = readKey(file)
+	if k.err == nil {
+		if k.signer, k.err = ssh.NewSignerFromKey(k.raw); k.err != nil {
+			k.err = fmt.Errorf("Parse SSH key error: %v", k.err)
+		}
+	}
+	keys.files[file] = k
+	return k, k.err
+}
+
+func readKey(file string) (interface{}, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SSH key %q: %v", file, err)
+	}
+	raw, err := ssh.ParseRawPrivateKey(b)
+	if _, ok := err.(*ssh.PassphraseMissingError); !ok {
+		if err != nil {
+			return nil, fmt.Errorf("Parse SSH key error: %v", err)
+		}
+		return raw, nil
+	}
+	passphrase, err := PassphrasePrompt(file)
+	if err != nil {
+		return nil, err
+	}
+	raw, err = ssh.ParseRawPrivateKeyWithPassphrase(b, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting SSH key %q: %v", file, err)
+	}
+	return raw, nil
+}
+
+// userAgent is the ssh-agent running at SSH_AUTH_SOCK, connected to on first
+// use
+var userAgent struct {
+	once  sync.Once
+	agent agent.Agent
+}
+
+func sshAgent() agent.Agent {
+	userAgent.once.Do(func() {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return
+		}
+		userAgent.agent = agent.NewClient(conn)
+	})
+	return userAgent.agent
+}
+
+// AgentAvailable returns true if an ssh-agent is running at SSH_AUTH_SOCK
+func AgentAvailable() bool {
+	return sshAgent() != nil
+}
+
+// authMethods returns the methods that authenticate with the key, if not
+// empty, and with the keys of the running ssh-agent
+func authMethods(key string) ([]ssh.AuthMethod, error) {
+	var signer ssh.Signer
+	if key != "" {
+		k, err := loadKey(key)
+		if err != nil {
+			return nil, err
+		}
+		signer = k.signer
+	}
+	a := sshAgent()
+	if signer == nil && a == nil {
+		return nil, fmt.Errorf("no SSH key was provided, and no ssh-agent is running (SSH_AUTH_SOCK is not set)")
+	}
+	// All the keys are tried by the same method, as the client does not try
+	// a method twice
+	signers := func() ([]ssh.Signer, error) {
+		signers := []ssh.Signer{}
+		if signer != nil {
+			signers = append(signers, signer)
+		}
+		if a != nil {
+			if agentSigners, err := a.Signers(); err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		}
+		return signers, nil
+	}
+	return []ssh.AuthMethod{ssh.PublicKeysCallback(signers)}, nil
+}
+
+// Agent serves decrypted keys to the ssh processes started by ansible, which
+// cannot prompt for the passphrases of the keys
+type Agent struct {
+	// Socket is the path of the unix socket the agent listens on
+	Socket   string
+	dir      string
+	listener net.Listener
+}
+
+// StartAgent starts an agent that serves the keys, after decrypting them
+func StartAgent(keyFiles []string) (*Agent, error) {
+	keyring := agent.NewKeyring()
+	for _, f := range keyFiles {
+		k, err := loadKey(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := keyring.Add(agent.AddedKey{PrivateKey: k.raw, Comment: f}); err != nil {
+			return nil, fmt.Errorf("error adding SSH key %q to the agent: %v", f, err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "kismatic-ssh-agent")
+	if err != nil {
+		return nil, fmt.Errorf("error creating directory for the agent socket: %v", err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("error listening on agent socket %q: %v", sock, err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return &Agent{Socket: sock, dir: dir, listener: l}, nil
+}
+
+// Close stops the agent and removes its socket
+func (a *Agent) Close() error {
+	a.listener.Close()
+	return os.RemoveAll(a.dir)
+}
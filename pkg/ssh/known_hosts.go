@@ -0,0 +1,284 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHosts pins the host keys of the nodes in a known_hosts file. The key
+// of a node is trusted the first time kismatic connects to it, and
+// connections are refused if the key changes afterwards.
+type KnownHosts struct {
+	File string
+	mu   sync.Mutex
+}
+
+// knownHosts is used by the clients to verify the host keys. Host keys are not
+// verified until SetKnownHostsFile is called.
+var knownHosts *KnownHosts
+
+// SetKnownHostsFile pins the host keys of the nodes that all clients connect
+// to in the given known_hosts file
+func SetKnownHostsFile(file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return fmt.Errorf("error getting absolute path of %q: %v", file, err)
+	}
+	knownHosts = &KnownHosts{File: abs}
+	return nil
+}
+
+// KnownHostsFile returns the known_hosts file the host keys are pinned in,
+// or an empty string if host keys are not verified
+func KnownHostsFile() string {
+	if knownHosts == nil {
+		return ""
+	}
+	return knownHosts.File
+}
+
+func hostKeyCallback() ssh.HostKeyCallback {
+	if knownHosts == nil {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	return knownHosts.HostKeyCallback
+}
+
+// HostKeyStatus is the result of comparing a host key with the pinned key
+type HostKeyStatus string
+
+// The possible results of comparing a host key with the pinned key
+const (
+	HostKeyPinned  HostKeyStatus = "pinned"
+	HostKeyUnknown HostKeyStatus = "unknown"
+	HostKeyChanged HostKeyStatus = "changed"
+)
+
+// HostKeyCallback verifies the host key against the pinned key of the host.
+// The key is pinned if the host is not known.
+func (k *KnownHosts) HostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	status, err := k.check(hostname, remote, key)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case HostKeyUnknown:
+		return k.add(hostname, key)
+	case HostKeyChanged:
+		return k.changedKeyError(hostname)
+	}
+	return nil
+}
+
+func (k *KnownHosts) changedKeyError(address string) error {
+	return fmt.Errorf("the host key of %s does not match the key pinned in %q, the connection could be intercepted. "+
+		"If the host key of the node was changed, run 'kismatic ssh-keys trust' for the node", address, k.File)
+}
+
+// Check compares the host key with the pinned key of the address
+func (k *KnownHosts) Check(address string, key ssh.PublicKey) (HostKeyStatus, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.check(address, nil, key)
+}
+
+// Trust pins the host key of the address, replacing the pinned key
+func (k *KnownHosts) Trust(address string, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, err := k.remove(address); err != nil {
+		return err
+	}
+	return k.add(address, key)
+}
+
+// Forget removes the pinned key of the address. Returns false if the
+// address was not known.
+func (k *KnownHosts) Forget(address string) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.remove(address)
+}
+
+func (k *KnownHosts) check(address string, remote net.Addr, key ssh.PublicKey) (HostKeyStatus, error) {
+	if _, err := os.Stat(k.File); os.IsNotExist(err) {
+		return HostKeyUnknown, nil
+	}
+	cb, err := knownhosts.New(k.File)
+	if err != nil {
+		return "", fmt.Errorf("error reading known hosts file %q: %v", k.File, err)
+	}
+	if remote == nil {
+		// the remote address is only used when the host is an IP, and is not
+		// known by name
+		remote = &net.TCPAddr{IP: net.IPv4zero}
+	}
+	err = cb(address, remote, key)
+	if err == nil {
+		return HostKeyPinned, nil
+	}
+	if keyErr, ok := err.(*knownhosts.KeyError); ok {
+		if len(keyErr.Want) == 0 {
+			return HostKeyUnknown, nil
+		}
+		return HostKeyChanged, nil
+	}
+	return "", err
+}
+
+func (k *KnownHosts) add(address string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(k.File), 0700); err != nil {
+		return fmt.Errorf("error creating directory for known hosts file %q: %v", k.File, err)
+	}
+	f, err := os.OpenFile(k.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening known hosts file %q: %v", k.File, err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{address}, key)); err != nil {
+		return fmt.Errorf("error writing to known hosts file %q: %v", k.File, err)
+	}
+	return nil
+}
+
+// remove removes the lines of the address from the file
+func (k *KnownHosts) remove(address string) (bool, error) {
+	b, err := ioutil.ReadFile(k.File)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading known hosts file %q: %v", k.File, err)
+	}
+	host := knownhosts.Normalize(address)
+	removed := false
+	out := &bytes.Buffer{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 0 && !strings.HasPrefix(fields[0], "#") && containsHost(fields[0], host) {
+			removed = true
+			continue
+		}
+		fmt.Fprintln(out, line)
+	}
+	if !removed {
+		return false, nil
+	}
+	if err := ioutil.WriteFile(k.File, out.Bytes(), 0600); err != nil {
+		return false, fmt.Errorf("error writing known hosts file %q: %v", k.File, err)
+	}
+	return true, nil
+}
+
+func containsHost(patterns string, host string) bool {
+	for _, p := range strings.Split(patterns, ",") {
+		if p == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Pin scans the host key of the address, and pins it if the address is not
+// known. The scanned key is returned with the result of the comparison with
+// the key that was pinned before.
+func (k *KnownHosts) Pin(address string, bastion *Bastion) (HostKeyStatus, ssh.PublicKey, error) {
+	key, err := ScanHostKey(address, bastion)
+	if err != nil {
+		return "", nil, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	status, err := k.check(address, nil, key)
+	if err != nil {
+		return "", nil, err
+	}
+	if status == HostKeyUnknown {
+		if err := k.add(address, key); err != nil {
+			return "", nil, err
+		}
+	}
+	return status, key, nil
+}
+
+// PinHostKeys pins the host keys of the addresses that are not known, and
+// returns an error if the key of an address changed. It does nothing if host
+// keys are not verified. The addresses are scanned through the bastion,
+// unless it is nil.
+func PinHostKeys(addresses []string, bastion *Bastion) error {
+	if knownHosts == nil {
+		return nil
+	}
+	errs := make([]error, len(addresses))
+	sem := make(chan struct{}, DefaultParallelism)
+	var wg sync.WaitGroup
+	wg.Add(len(addresses))
+	for i, addr := range addresses {
+		go func(i int, addr string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			status, _, err := knownHosts.Pin(addr, bastion)
+			if err == nil && status == HostKeyChanged {
+				err = knownHosts.changedKeyError(addr)
+			}
+			errs[i] = err
+		}(i, addr)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errHostKeyScanned aborts the connection once the host key was received
+var errHostKeyScanned = errors.New("host key scanned")
+
+// ScanHostKey connects to the address and returns its host key, without
+// authenticating. The connection goes through the bastion, unless it is nil.
+func ScanHostKey(address string, bastion *Bastion) (ssh.PublicKey, error) {
+	var bastionClient *NativeClient
+	if bastion != nil {
+		var err error
+		if bastionClient, err = newNativeClient(bastion.Host, bastion.Port, bastion.User, bastion.Key, nil); err != nil {
+			return nil, fmt.Errorf("bastion: %v", err)
+		}
+	}
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User: "kismatic",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyScanned
+		},
+		Timeout: connectTimeout,
+	}
+	_, err := connect(address, config, bastionClient)
+	if hostKey != nil {
+		return hostKey, nil
+	}
+	return nil, fmt.Errorf("error getting host key of %s: %v", address, err)
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key, as printed by ssh
+func Fingerprint(key ssh.PublicKey) string {
+	return ssh.FingerprintSHA256(key)
+}
@@ -0,0 +1,98 @@
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKnownHosts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-known-hosts")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	_, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+	addr := fmt.Sprintf("127.0.0.1:%d", server.port())
+
+	k := &KnownHosts{File: filepath.Join(dir, "generated", "known_hosts")}
+	status, key, err := k.Pin(addr, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != HostKeyUnknown {
+		t.Errorf("expected the host to be unknown, got %q", status)
+	}
+	b, err := ioutil.ReadFile(k.File)
+	if err != nil {
+		t.Fatalf("error reading known hosts file: %v", err)
+	}
+	if !strings.HasPrefix(string(b), fmt.Sprintf("[127.0.0.1]:%d ssh-rsa ", server.port())) {
+		t.Errorf("unexpected known hosts file:\n%s", b)
+	}
+	if status, _, err = k.Pin(addr, nil); err != nil || status != HostKeyPinned {
+		t.Errorf("expected the host key to be pinned, got %q (%v)", status, err)
+	}
+
+	// the key of the host changes
+	if err := k.Trust(addr, pub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status, err = k.Check(addr, key); err != nil || status != HostKeyChanged {
+		t.Errorf("expected the host key to be changed, got %q (%v)", status, err)
+	}
+	if status, err = k.Check(addr, pub); err != nil || status != HostKeyPinned {
+		t.Errorf("expected the trusted key to be pinned, got %q (%v)", status, err)
+	}
+
+	removed, err := k.Forget(addr)
+	if err != nil || !removed {
+		t.Errorf("expected the host key to be removed, got %v (%v)", removed, err)
+	}
+	if status, err = k.Check(addr, pub); err != nil || status != HostKeyUnknown {
+		t.Errorf("expected the host to be unknown, got %q (%v)", status, err)
+	}
+	if removed, _ = k.Forget(addr); removed {
+		t.Errorf("expected nothing to be removed when the host is not known")
+	}
+}
+
+func TestNativeClientVerifiesHostKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-known-hosts")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+
+	if err := SetKnownHostsFile(filepath.Join(dir, "known_hosts")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { knownHosts = nil }()
+
+	c, err := newNativeClient("127.0.0.1", server.port(), "kismaticuser", key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+	if _, err := c.Output(false, "exit"); err != nil {
+		t.Fatalf("expected the host key to be trusted on first use, got: %v", err)
+	}
+
+	// pin a different key, the connection must be refused
+	if err := knownHosts.Trust(fmt.Sprintf("127.0.0.1:%d", server.port()), pub); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+	_, err = c.Output(false, "exit")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected the connection to be refused when the host key changed, got: %v", err)
+	}
+}
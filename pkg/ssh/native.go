@@ -0,0 +1,326 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// connectTimeout is how long establishing a connection can take
+	connectTimeout = 10 * time.Second
+	// connectionAttempts is the number of times a connection is attempted
+	connectionAttempts = 3
+	// maxSessions is the number of sessions that can be open at the same
+	// time on a connection. OpenSSH allows 10 by default.
+	maxSessions = 8
+	// DefaultCommandTimeout is how long a command can run before it is
+	// aborted, unless the command timeout of the client is changed
+	DefaultCommandTimeout = 10 * time.Minute
+)
+
+// NativeClient runs commands over SSH without depending on an ssh binary.
+// The connections are kept open and reused by all the clients of a host.
+type NativeClient struct {
+	addr   string
+	config *ssh.ClientConfig
+	// poolKey identifies the connection of the client in the pool
+	poolKey string
+	pool    *pool
+	// bastion is the client of the jump host the connection goes through
+	bastion *NativeClient
+	// CommandTimeout is how long a command can run before it is aborted.
+	// Zero means no timeout.
+	CommandTimeout time.Duration
+	// SudoPassword is entered when the commands run with sudo. It is not
+	// needed when the user can sudo without a password.
+	SudoPassword string
+}
+
+func newNativeClient(host string, port int, user string, key string, bastion *Bastion) (*NativeClient, error) {
+	auth, err := authMethods(key)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	c := &NativeClient{
+		addr: addr,
+		config: &ssh.ClientConfig{
+			User:            user,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback(),
+			Timeout:         connectTimeout,
+		},
+		poolKey:        fmt.Sprintf("%s@%s %s", user, addr, key),
+		pool:           connections,
+		CommandTimeout: DefaultCommandTimeout,
+	}
+	if bastion != nil {
+		b, err := newNativeClient(bastion.Host, bastion.Port, bastion.User, bastion.Key, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bastion: %v", err)
+		}
+		c.bastion = b
+		c.poolKey = fmt.Sprintf("%s via %s", c.poolKey, b.poolKey)
+	}
+	return c, nil
+}
+
+// Output runs the command and returns its combined stdout and stderr
+func (c *NativeClient) Output(pty bool, args ...string) (string, error) {
+	session, release, err := c.newSession()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	defer session.Close()
+	cmd := strings.Join(args, " ")
+	// the password is written to stdin, without being echoed in the output
+	sudoPassword := c.SudoPassword != "" && strings.HasPrefix(cmd, "sudo ")
+	// for pseudo-tty and sudo to work correctly Stdin must be set to os.Stdin
+	if pty {
+		if err := requestPty(session, !sudoPassword); err != nil {
+			return "", err
+		}
+		session.Stdin = os.Stdin
+	}
+	if sudoPassword {
+		cmd = sudoWithPassword(cmd)
+		session.Stdin = strings.NewReader(c.SudoPassword + "\n")
+	}
+	var output []byte
+	err = c.withTimeout(session, func() error {
+		var runErr error
+		output, runErr = session.CombinedOutput(cmd)
+		return runErr
+	})
+	return string(output), err
+}
+
+// sudoWithPassword makes sudo read the password from stdin, without printing
+// a prompt
+func sudoWithPassword(cmd string) string {
+	return "sudo -S -p '' " + strings.TrimPrefix(cmd, "sudo ")
+}
+
+// Shell runs the command, binding Stdin, Stdout and Stderr. An interactive
+// shell is started when there is no command.
+func (c *NativeClient) Shell(pty bool, args ...string) error {
+	session, release, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer session.Close()
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+	if (pty || len(args) == 0) && terminal.IsTerminal(fd) {
+		state, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("error setting up the terminal: %v", err)
+		}
+		defer terminal.Restore(fd, state)
+		if err := requestPty(session, true); err != nil {
+			return err
+		}
+	}
+	if len(args) == 0 {
+		if err := session.Shell(); err != nil {
+			return fmt.Errorf("error starting shell: %v", err)
+		}
+		return session.Wait()
+	}
+	return c.withTimeout(session, func() error {
+		return session.Run(strings.Join(args, " "))
+	})
+}
+
+// newSession opens a session on the connection of the host, connecting if
+// there is no connection, or the connection was lost. The session must be
+// closed before it is released.
+func (c *NativeClient) newSession() (*ssh.Session, func(), error) {
+	conn, err := c.pool.get(c.poolKey, c.dial)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.sessions <- struct{}{}
+	release := func() { <-conn.sessions }
+	session, err := conn.NewSession()
+	if err == nil {
+		return session, release, nil
+	}
+	release()
+	if _, ok := err.(*ssh.OpenChannelError); ok {
+		// The server refused the session, but the connection is fine
+		return nil, nil, fmt.Errorf("error opening SSH session to %s: %v", c.addr, err)
+	}
+	// The connection may have been closed by the server, try a new one
+	c.pool.remove(c.poolKey, conn)
+	conn, err = c.pool.get(c.poolKey, c.dial)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.sessions <- struct{}{}
+	release = func() { <-conn.sessions }
+	session, err = conn.NewSession()
+	if err != nil {
+		release()
+		return nil, nil, fmt.Errorf("error opening SSH session to %s: %v", c.addr, err)
+	}
+	return session, release, nil
+}
+
+// dial connects to the host, retrying on errors that are not authentication
+// or host key errors
+func (c *NativeClient) dial() (*ssh.Client, error) {
+	var err error
+	for i := 0; i < connectionAttempts; i++ {
+		var client *ssh.Client
+		client, err = connect(c.addr, c.config, c.bastion)
+		if err == nil {
+			return client, nil
+		}
+		// authentication and host key errors will not go away by retrying
+		if strings.Contains(err.Error(), "unable to authenticate") || strings.Contains(err.Error(), "does not match the key pinned") {
+			break
+		}
+	}
+	return nil, fmt.Errorf("error connecting to %s: %v", c.addr, err)
+}
+
+// connect opens a connection to addr, tunneled through the connection to the
+// bastion if there is one
+func connect(addr string, config *ssh.ClientConfig, bastion *NativeClient) (*ssh.Client, error) {
+	if bastion == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	conn, err := bastion.pool.get(bastion.poolKey, bastion.dial)
+	if err != nil {
+		return nil, err
+	}
+	tunnel, err := conn.Dial("tcp", addr)
+	if err != nil {
+		if _, ok := err.(*ssh.OpenChannelError); !ok {
+			// The connection to the bastion may have been lost
+			bastion.pool.remove(bastion.poolKey, conn)
+		}
+		return nil, fmt.Errorf("error connecting through bastion %s: %v", bastion.addr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(tunnel, addr, config)
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// withTimeout runs f, and closes the session if it runs for longer than the
+// command timeout
+func (c *NativeClient) withTimeout(session *ssh.Session, f func() error) error {
+	if c.CommandTimeout == 0 {
+		return f()
+	}
+	timer := time.AfterFunc(c.CommandTimeout, func() { session.Close() })
+	err := f()
+	if !timer.Stop() {
+		return fmt.Errorf("command timed out after %v", c.CommandTimeout)
+	}
+	return err
+}
+
+func requestPty(session *ssh.Session, echo bool) error {
+	width, height := 80, 40
+	if w, h, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil {
+		width, height = w, h
+	}
+	var echoMode uint32
+	if echo {
+		echoMode = 1
+	}
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          echoMode,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	term := os.Getenv("TERM")
+	if term == "" {
+		term = "xterm"
+	}
+	if err := session.RequestPty(term, height, width, modes); err != nil {
+		return fmt.Errorf("error requesting a pseudo-terminal: %v", err)
+	}
+	return nil
+}
+
+// connections is the pool of connections shared by all the native clients
+var connections = newPool()
+
+// connection to a host, and the sessions that are open on it
+type connection struct {
+	*ssh.Client
+	sessions chan struct{}
+}
+
+// pool keeps a connection open to each host, so that commands run in new
+// sessions of the same connection
+type pool struct {
+	mu    sync.Mutex
+	conns map[string]*connection
+	// dialing makes concurrent clients of a host wait for the same connection
+	dialing map[string]*sync.Mutex
+}
+
+func newPool() *pool {
+	return &pool{
+		conns:   map[string]*connection{},
+		dialing: map[string]*sync.Mutex{},
+	}
+}
+
+func (p *pool) get(key string, dial func() (*ssh.Client, error)) (*connection, error) {
+	p.mu.Lock()
+	if _, ok := p.dialing[key]; !ok {
+		p.dialing[key] = &sync.Mutex{}
+	}
+	dialing := p.dialing[key]
+	p.mu.Unlock()
+
+	dialing.Lock()
+	defer dialing.Unlock()
+	p.mu.Lock()
+	conn, ok := p.conns[key]
+	p.mu.Unlock()
+	if ok {
+		return conn, nil
+	}
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	conn = &connection{Client: client, sessions: make(chan struct{}, maxSessions)}
+	p.mu.Lock()
+	p.conns[key] = conn
+	p.mu.Unlock()
+	return conn, nil
+}
+
+// remove closes the connection and removes it from the pool, unless it was
+// already replaced by a new connection
+func (p *pool) remove(key string, conn *connection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conns[key] == conn {
+		delete(p.conns, key)
+	}
+	conn.Close()
+}
@@ -0,0 +1,409 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an SSH server that echoes the commands it runs. The command
+// "false" exits with status 1, "sleep" takes 50ms to run, and sudo commands
+// that read the password from stdin echo the password.
+type testServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	mu          sync.Mutex
+	connections int
+	// sessions is the number of sessions open at the same time, and
+	// peakSessions the highest it got
+	sessions     int
+	peakSessions int
+}
+
+func newTestServer(t *testing.T, authorizedKey ssh.PublicKey) *testServer {
+	hostKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatalf("error creating host key signer: %v", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	s := &testServer{listener: l, config: config}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// connectionCount returns the number of connections the server accepted
+func (s *testServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+func (s *testServer) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *testServer) handle(c net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(c, s.config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() == "direct-tcpip" {
+			go s.forward(newChannel)
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		s.mu.Lock()
+		s.sessions++
+		if s.sessions > s.peakSessions {
+			s.peakSessions = s.sessions
+		}
+		s.mu.Unlock()
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				s.sessions--
+				s.mu.Unlock()
+			}()
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				// the payload is the length of the command, followed by the command
+				cmd := string(req.Payload[4:])
+				req.Reply(true, nil)
+				if cmd == "sleep" {
+					time.Sleep(50 * time.Millisecond)
+				}
+				fmt.Fprintf(channel, "ran: %s", cmd)
+				if strings.HasPrefix(cmd, "sudo -S ") {
+					password, _ := bufio.NewReader(channel).ReadString('\n')
+					fmt.Fprintf(channel, " with password %s", strings.TrimSuffix(password, "\n"))
+				}
+				status := make([]byte, 4)
+				if cmd == "false" {
+					binary.BigEndian.PutUint32(status, 1)
+				}
+				channel.SendRequest("exit-status", false, status)
+				return
+			}
+		}()
+	}
+}
+
+// forward tunnels the channel to the requested address, as a bastion does
+func (s *testServer) forward(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		io.Copy(conn, channel)
+		conn.Close()
+	}()
+	io.Copy(channel, conn)
+	channel.Close()
+}
+
+func writeTestKey(t *testing.T, dir string) (string, ssh.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	file := filepath.Join(dir, "kismaticuser.key")
+	b := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(file, b, 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	pub, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("error getting public key: %v", err)
+	}
+	return file, pub
+}
+
+func TestNativeClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+
+	c, err := newNativeClient("127.0.0.1", server.port(), "kismaticuser", key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+
+	out, err := c.Output(false, "cat", "/etc/kismatic-version")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "ran: cat /etc/kismatic-version" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if _, err := c.Output(false, "false"); err == nil {
+		t.Errorf("expected an error when the command fails")
+	}
+
+	// Commands run in parallel share the connection
+	commands := []Command{}
+	for i := 0; i < 30; i++ {
+		commands = append(commands, Command{Host: fmt.Sprintf("node%d", i), Client: c, Args: []string{"echo", fmt.Sprint(i)}})
+	}
+	results := RunParallel(commands, 10)
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("unexpected error on %s: %v", r.Host, r.Err)
+		}
+		if r.Host != fmt.Sprintf("node%d", i) || !strings.HasSuffix(r.Output, fmt.Sprintf("echo %d", i)) {
+			t.Errorf("unexpected result %d: %+v", i, r)
+		}
+	}
+	if n := server.connectionCount(); n != 1 {
+		t.Errorf("expected a single connection to the server, got %d", n)
+	}
+}
+
+func TestNativeClientSessionLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+
+	c, err := newNativeClient("127.0.0.1", server.port(), "kismaticuser", key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+
+	commands := []Command{}
+	for i := 0; i < 3*maxSessions; i++ {
+		commands = append(commands, Command{Host: fmt.Sprintf("node%d", i), Client: c, Args: []string{"sleep"}})
+	}
+	for _, r := range RunParallel(commands, DefaultParallelism) {
+		if r.Err != nil {
+			t.Errorf("unexpected error on %s: %v", r.Host, r.Err)
+		}
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.peakSessions > maxSessions {
+		t.Errorf("expected at most %d sessions open at the same time on the connection, got %d", maxSessions, server.peakSessions)
+	}
+	if server.peakSessions < 2 {
+		t.Errorf("expected the commands to share the connection in parallel")
+	}
+	if server.connections != 1 {
+		t.Errorf("expected a single connection to the server, got %d", server.connections)
+	}
+}
+
+func TestNativeClientCommandTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+
+	c, err := newNativeClient("127.0.0.1", server.port(), "kismaticuser", key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+	if c.CommandTimeout != DefaultCommandTimeout {
+		t.Errorf("expected the command timeout to default to %v, got %v", DefaultCommandTimeout, c.CommandTimeout)
+	}
+
+	c.CommandTimeout = 10 * time.Millisecond
+	if _, err := c.Output(false, "sleep"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected the command to time out, got: %v", err)
+	}
+	c.CommandTimeout = time.Second
+	if out, err := c.Output(false, "sleep"); err != nil || out != "ran: sleep" {
+		t.Errorf("unexpected result %q: %v", out, err)
+	}
+}
+
+func TestNativeClientSudoPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+
+	c, err := newNativeClient("127.0.0.1", server.port(), "kismaticuser", key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+	c.SudoPassword = "secret"
+
+	out, err := c.Output(false, "sudo gluster volume info all --xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "ran: sudo -S -p '' gluster volume info all --xml with password secret" {
+		t.Errorf("unexpected output %q", out)
+	}
+	// commands without sudo are not changed
+	out, err = c.Output(false, "cat /etc/kismatic-version")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "ran: cat /etc/kismatic-version" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestNativeClientAuthenticationError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	_, pub := writeTestKey(t, dir)
+	server := newTestServer(t, pub)
+	defer server.listener.Close()
+
+	otherDir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(otherDir)
+	otherKey, _ := writeTestKey(t, otherDir)
+	c, err := newNativeClient("127.0.0.1", server.port(), "kismaticuser", otherKey, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+	if _, err := c.Output(false, "exit"); err == nil {
+		t.Errorf("expected an error when the key is not authorized")
+	}
+	if n := server.connectionCount(); n != 1 {
+		t.Errorf("expected authentication errors not to be retried, got %d connections", n)
+	}
+}
+
+func TestNativeClientThroughBastion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ket-test-ssh")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	key, pub := writeTestKey(t, dir)
+	node := newTestServer(t, pub)
+	defer node.listener.Close()
+	bastion := newTestServer(t, pub)
+	defer bastion.listener.Close()
+
+	c, err := newNativeClient("127.0.0.1", node.port(), "kismaticuser", key, &Bastion{Host: "127.0.0.1", Port: bastion.port(), User: "bastionuser", Key: key})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.pool = newPool()
+	c.bastion.pool = newPool()
+
+	for i := 0; i < 2; i++ {
+		out, err := c.Output(false, "hostname")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out != "ran: hostname" {
+			t.Errorf("unexpected output %q", out)
+		}
+	}
+	if b, n := bastion.connectionCount(), node.connectionCount(); b != 1 || n != 1 {
+		t.Errorf("expected a single connection to the bastion and to the node, got %d and %d", b, n)
+	}
+
+	hostKey, err := ScanHostKey(c.addr, &Bastion{Host: "127.0.0.1", Port: bastion.port(), User: "bastionuser", Key: key})
+	if err != nil {
+		t.Fatalf("unexpected error scanning through the bastion: %v", err)
+	}
+	if hostKey == nil {
+		t.Errorf("expected the host key of the node")
+	}
+}
@@ -0,0 +1,43 @@
+package ssh
+
+import "sync"
+
+// DefaultParallelism is the number of hosts commands run on at the same time
+const DefaultParallelism = 20
+
+// Command to run on a host
+type Command struct {
+	Host   string
+	Client Client
+	Args   []string
+}
+
+// CommandResult is the output of a command that ran on a host
+type CommandResult struct {
+	Host   string
+	Output string
+	Err    error
+}
+
+// RunParallel runs the commands, on at most parallelism hosts at the same
+// time, and returns the results in the order of the commands
+func RunParallel(commands []Command, parallelism int) []CommandResult {
+	if parallelism < 1 {
+		parallelism = DefaultParallelism
+	}
+	results := make([]CommandResult, len(commands))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	wg.Add(len(commands))
+	for i, c := range commands {
+		go func(i int, c Command) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			out, err := c.Client.Output(false, c.Args...)
+			results[i] = CommandResult{Host: c.Host, Output: out, Err: err}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient runs commands that take longer the earlier they are in the
+// commands, and records how many run at the same time
+type fakeClient struct {
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (c *fakeClient) Output(pty bool, args ...string) (string, error) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+	var i int
+	fmt.Sscan(args[0], &i)
+	time.Sleep(time.Duration(10-i%10) * time.Millisecond)
+	if i%3 == 0 {
+		return "", fmt.Errorf("command %d failed", i)
+	}
+	return fmt.Sprintf("output %d", i), nil
+}
+
+func (c *fakeClient) Shell(pty bool, args ...string) error {
+	return nil
+}
+
+func TestRunParallel(t *testing.T) {
+	tests := []struct {
+		parallelism         int
+		expectedParallelism int
+	}{
+		{parallelism: 1, expectedParallelism: 1},
+		{parallelism: 4, expectedParallelism: 4},
+		{parallelism: 0, expectedParallelism: DefaultParallelism},
+	}
+	for _, test := range tests {
+		c := &fakeClient{}
+		commands := []Command{}
+		for i := 0; i < 30; i++ {
+			commands = append(commands, Command{Host: fmt.Sprintf("node%d", i), Client: c, Args: []string{fmt.Sprint(i)}})
+		}
+		results := RunParallel(commands, test.parallelism)
+
+		if len(results) != len(commands) {
+			t.Fatalf("parallelism %d: expected %d results, got %d", test.parallelism, len(commands), len(results))
+		}
+		for i, r := range results {
+			if r.Host != fmt.Sprintf("node%d", i) {
+				t.Errorf("parallelism %d: expected result %d to be of node%d, got %s", test.parallelism, i, i, r.Host)
+			}
+			if i%3 == 0 {
+				if r.Err == nil {
+					t.Errorf("parallelism %d: expected an error on %s", test.parallelism, r.Host)
+				}
+				continue
+			}
+			if r.Err != nil || r.Output != fmt.Sprintf("output %d", i) {
+				t.Errorf("parallelism %d: unexpected result on %s: %+v", test.parallelism, r.Host, r)
+			}
+		}
+		if c.max > test.expectedParallelism {
+			t.Errorf("parallelism %d: expected at most %d commands to run at the same time, got %d", test.parallelism, test.expectedParallelism, c.max)
+		}
+		if test.expectedParallelism > 1 && c.max < 2 {
+			t.Errorf("parallelism %d: expected the commands to run in parallel", test.parallelism)
+		}
+	}
+}
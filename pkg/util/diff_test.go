@@ -0,0 +1,39 @@
+package util
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			a:        "foo\nbar\n",
+			b:        "foo\nbar\n",
+			expected: "",
+		},
+		{
+			a:        "foo\nbar\nbaz\n",
+			b:        "foo\nqux\nbaz\n",
+			expected: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n foo\n-bar\n+qux\n baz\n",
+		},
+		{
+			a:        "",
+			b:        "foo\n",
+			expected: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+foo\n",
+		},
+		{
+			// changes that are far apart result in separate hunks
+			a:        "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:        "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			expected: "--- a\n+++ b\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+	}
+	for i, test := range tests {
+		got := UnifiedDiff("a", "b", []byte(test.a), []byte(test.b))
+		if got != test.expected {
+			t.Errorf("test %d: expected diff\n%s\nbut got\n%s", i, test.expected, got)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package util
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 )
 
 // BackupDirectory checks for existence of the $sourceDir and backs it up to backupDir
@@ -21,3 +23,44 @@ func BackupDirectory(sourceDir string, backupDir string) (bool, error) {
 	// Directory does not already exist, nothing to do
 	return backedup, nil
 }
+
+// CopyDirectory copies the contents of the sourceDir to the destDir,
+// recursively. The destDir is created if it does not exist.
+func CopyDirectory(sourceDir string, destDir string) error {
+	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(destDir, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dest, info.Mode().Perm())
+		}
+		return CopyFile(path, dest)
+	})
+}
+
+// CopyFile copies the sourceFile to the destFile, keeping its permissions
+func CopyFile(sourceFile string, destFile string) error {
+	info, err := os.Stat(sourceFile)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(sourceFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(destFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}